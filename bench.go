@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/angelsolaorbaiceta/binmat/signature"
+)
+
+// runBench scans the corpus, a file or directory, with the signatures several
+// times, and reports the throughput of each run in MB/s, to catch performance
+// regressions in the signatures or in binmat itself.
+func runBench(args []string) {
+	var (
+		flags    = flag.NewFlagSet("bench", flag.ExitOnError)
+		sigPaths pathsFlag
+		count    int
+		profile  bool
+	)
+
+	flags.Var(&sigPaths, "rules", "signature file, directory or compiled bundle to load (can be repeated)")
+	flags.IntVar(&count, "count", 5, "number of times to scan the corpus")
+	flags.BoolVar(&profile, "profile", false, "report the slowest signatures, and the time spent on each of their patterns")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s bench [-count n] [-rules path] <file|directory>\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || count < 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	var (
+		sigs                 = loadSignatures(sigPaths)
+		filePaths, corpusLen = corpusFiles(flags.Arg(0))
+		throughputs          = make([]float64, count)
+		opts                 signature.CheckOptions
+	)
+
+	if profile {
+		opts.Profile = signature.NewProfile()
+	}
+
+	fmt.Printf("Corpus: %d files, %.2f MB. Signatures: %d.\n", len(filePaths), megabytes(corpusLen), len(sigs))
+
+	for run := 0; run < count; run++ {
+		start := time.Now()
+		for _, filePath := range filePaths {
+			if _, err := sigs.CheckWithOptions(filePath, opts); err != nil {
+				fmt.Fprintf(os.Stderr, "Can't check for matches: %s\n", err)
+				os.Exit(1)
+			}
+		}
+		elapsed := time.Since(start)
+
+		throughputs[run] = megabytes(corpusLen) / elapsed.Seconds()
+		fmt.Printf("Run %d: %s, %.2f MB/s\n", run+1, elapsed.Round(time.Millisecond), throughputs[run])
+	}
+
+	slices.Sort(throughputs)
+	fmt.Printf(
+		"Throughput: %.2f MB/s median, %.2f MB/s min, %.2f MB/s max\n",
+		throughputs[len(throughputs)/2], throughputs[0], throughputs[len(throughputs)-1],
+	)
+
+	if opts.Profile != nil {
+		opts.Profile.Write(os.Stdout, profileTop)
+	}
+}
+
+// corpusFiles returns the paths to the file, or to every file in the directory,
+// at the given path, and their total size in bytes.
+func corpusFiles(path string) ([]string, int64) {
+	var (
+		filePaths []string
+		totalLen  int64
+	)
+
+	err := filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		filePaths = append(filePaths, filePath)
+		totalLen += info.Size()
+		return nil
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't read the corpus at '%s': %s\n", path, err)
+		os.Exit(1)
+	}
+
+	return filePaths, totalLen
+}
+
+// megabytes returns the number of bytes in decimal megabytes.
+func megabytes(n int64) float64 {
+	return float64(n) / 1e6
+}
@@ -0,0 +1,85 @@
+package bexpr
+
+import "sort"
+
+// maxAnalyzedVars is the maximum number of variables an expression can have
+// to be analyzed. The analysis evaluates the expression for every combination
+// of values of its variables, so its cost doubles with each variable.
+const maxAnalyzedVars = 16
+
+// An Analysis is the result of statically analyzing an Expression.
+type Analysis struct {
+	// Complete is false if the expression has too many variables to be analyzed,
+	// in which case the rest of the fields are left at their zero value.
+	Complete bool
+	// Satisfiable is true if there is at least one combination of variable values
+	// for which the expression is true. Otherwise, the expression is a contradiction.
+	Satisfiable bool
+	// Tautology is true if the expression is true for every combination of
+	// variable values.
+	Tautology bool
+	// Irrelevant are the sorted names of the variables, and comparisons, whose
+	// value never changes the result of the expression (e.g. "b" in
+	// "a OR (a AND b)").
+	Irrelevant []string
+}
+
+// Analyze evaluates the expression for every combination of values of its
+// variables to find out whether it's a contradiction, a tautology, and which
+// variables don't affect its result. Comparisons are analyzed as variables,
+// independent from each other.
+//
+// Expressions with more than 16 variables and comparisons aren't analyzed.
+func (e *Expression) Analyze() Analysis {
+	var (
+		vars     = e.Vars()
+		analysis Analysis
+	)
+
+	for _, cmp := range e.Comparisons() {
+		vars = append(vars, cmp.String())
+	}
+	sort.Strings(vars)
+
+	if len(vars) > maxAnalyzedVars {
+		return analysis
+	}
+
+	// table[i] is the result of the expression where the value of the variable
+	// vars[j] is the j-th bit of i.
+	var (
+		table  = make([]bool, 1<<len(vars))
+		values = make(map[string]bool, len(vars))
+	)
+
+	analysis.Complete = true
+	analysis.Tautology = true
+
+	for i := range table {
+		for j, name := range vars {
+			values[name] = i&(1<<j) != 0
+		}
+
+		// The map has a value for every variable in the expression: can't fail.
+		table[i], _ = e.Eval(values)
+
+		analysis.Satisfiable = analysis.Satisfiable || table[i]
+		analysis.Tautology = analysis.Tautology && table[i]
+	}
+
+	for j, name := range vars {
+		relevant := false
+		for i := range table {
+			if table[i] != table[i^(1<<j)] {
+				relevant = true
+				break
+			}
+		}
+
+		if !relevant {
+			analysis.Irrelevant = append(analysis.Irrelevant, name)
+		}
+	}
+
+	return analysis
+}
@@ -0,0 +1,57 @@
+package bexpr
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	for _, tCase := range []struct {
+		cond string
+		want Analysis
+	}{
+		{
+			cond: "a AND (b OR c)",
+			want: Analysis{Complete: true, Satisfiable: true},
+		},
+		{
+			cond: "a AND NOT a",
+			want: Analysis{Complete: true, Irrelevant: []string{"a"}},
+		},
+		{
+			cond: "a OR NOT a",
+			want: Analysis{Complete: true, Satisfiable: true, Tautology: true, Irrelevant: []string{"a"}},
+		},
+		{
+			cond: "a OR (a AND b)",
+			want: Analysis{Complete: true, Satisfiable: true, Irrelevant: []string{"b"}},
+		},
+		{
+			cond: "b AND (a AND NOT a)",
+			want: Analysis{Complete: true, Irrelevant: []string{"a", "b"}},
+		},
+	} {
+		t.Run(
+			fmt.Sprintf("analyze '%s'", tCase.cond),
+			func(t *testing.T) {
+				expr, err := Parse(tCase.cond)
+				if err != nil {
+					t.Fatalf("Want no error, got %s", err)
+				}
+
+				assert.Equal(t, tCase.want, expr.Analyze())
+			})
+	}
+
+	t.Run("too many variables aren't analyzed", func(t *testing.T) {
+		cond := "v0"
+		for i := 1; i <= maxAnalyzedVars; i++ {
+			cond = fmt.Sprintf("v%d OR (%s)", i, cond)
+		}
+		expr, _ := Parse(cond)
+
+		assert.False(t, expr.Analyze().Complete)
+	})
+}
@@ -17,22 +17,17 @@ func appendToCondition(baseCond, toAppend conditionExpr) (conditionExpr, *errApp
 		return toAppend, nil
 	}
 
-	switch a := baseCond.(type) {
-	case varConditionExpr:
-		switch b := toAppend.(type) {
-		// Only binary ops can be appended to variables (e.g. "a AND").
-		// The variable is set as the lhs of the binary expression, and the latter
-		// is returned as the parent.
-		// If there was an lhs already, it returns an error.
-		case binaryConditionExpr:
-			if b.hasLhs() {
-				return nil, &errAppendToCond{baseCond, toAppend}
-			}
-
-			b.setLhs(a)
-			return b, nil
+	// Binary ops can be appended to any complete expression (e.g. "a AND",
+	// "NOT a AND", "(a OR b) AND", "a AND b OR").
+	if b, ok := toAppend.(binaryConditionExpr); ok {
+		if b.hasLhs() || !isCondComplete(baseCond) {
+			return nil, &errAppendToCond{baseCond, toAppend}
 		}
 
+		return appendBinary(baseCond, b), nil
+	}
+
+	switch a := baseCond.(type) {
 	case unaryConditionExpr:
 		switch b := toAppend.(type) {
 		// Both variables and unary expressions can be appended to unary expressions
@@ -65,13 +60,51 @@ func appendToCondition(baseCond, toAppend conditionExpr) (conditionExpr, *errApp
 	return nil, &errAppendToCond{baseCond, toAppend}
 }
 
+// appendBinary appends the binary expression, without lhs, to the complete base
+// expression, and returns the top-level expression resulting from the append.
+//
+// The binary expression takes the base as its lhs, unless the base is a binary
+// expression of lower precedence (e.g. "a OR b" followed by "AND"), in which
+// case the binary expression is appended to the base's rhs instead, so that it
+// binds tighter:
+//
+//	"a OR b AND" -> "a OR (b AND ??)"
+//	"a AND b OR" -> "(a AND b) OR ??"
+//
+// Binary operations of the same precedence are left associative.
+func appendBinary(baseCond conditionExpr, toAppend binaryConditionExpr) conditionExpr {
+	if a, ok := baseCond.(binaryConditionExpr); ok && precedence(toAppend) > precedence(a) {
+		a.replaceRhs(appendBinary(a.getRhs(), toAppend))
+		return a
+	}
+
+	toAppend.setLhs(baseCond)
+	return toAppend
+}
+
+// precedence returns the precedence of a binary operation: the higher, the
+// tighter it binds to its operands.
+func precedence(cond binaryConditionExpr) int {
+	switch cond.(type) {
+	case *andCondition:
+		return 2
+	case *orCondition:
+		return 1
+	}
+
+	panic("Forgot to handle a binary condition type?")
+}
+
 // TODO: unify with logic in previous method to avoid duplicating the logic.
 // canAppend determines if b can appended to a (a.append(b)).
 func canAppend(a, b conditionExpr) bool {
 	result := false
 
 	switch a.(type) {
-	// only binary ops can be appended to variables (e.g. "a AND")
+	// only binary ops can be appended to variables (e.g. "a AND").
+	// Binary ops can also be appended to complete unary and binary expressions
+	// (see appendToCondition), but not as their operands, which is what this
+	// function is used to check.
 	case varConditionExpr:
 		switch b.(type) {
 		case binaryConditionExpr:
@@ -0,0 +1,321 @@
+package bexpr
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Comparison operators.
+const (
+	CmpEq = "=="
+	CmpNe = "!="
+	CmpLt = "<"
+	CmpLe = "<="
+	CmpGt = ">"
+	CmpGe = ">="
+	// CmpAt checks that a pattern matches at an offset (e.g. a at entrypoint).
+	CmpAt = "at"
+)
+
+var (
+	identRe = regexp.MustCompile(`^[a-z_][a-z0-9_]*(?:\.[a-z_][a-z0-9_]*)*$`)
+	// Leading zeros are allowed in decimal numbers, which aren't octal.
+	intRe   = regexp.MustCompile(`^(?:[0-9]+|0x[0-9a-fA-F]+)$`)
+	floatRe = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+	// The index of match offsets is optional, and 1 by default.
+	offsetRe = regexp.MustCompile(`^@([a-z0-9_]+)(?:\[([0-9]+)\])?$`)
+)
+
+// An OperandKind is the kind of an operand in a comparison.
+type OperandKind int
+
+const (
+	// OperandIdent is a named value, whose value is only known when the
+	// condition is evaluated (e.g. sha256, elf.machine).
+	OperandIdent OperandKind = iota
+	// OperandCall is a function call, whose value is only known when the
+	// condition is evaluated (e.g. elf.section_size(".text")).
+	OperandCall
+	// OperandString is a string literal, between double quotes (e.g. "a\"b").
+	OperandString
+	// OperandInt is an integer literal, either decimal or hexadecimal (e.g. 0x4d).
+	OperandInt
+	// OperandFloat is a decimal number literal, with a fractional part (e.g.
+	// 7.25).
+	OperandFloat
+	// OperandOffset is the offset of a match of a pattern, whose value is only
+	// known when the condition is evaluated (e.g. @a[2], the second match of a).
+	OperandOffset
+)
+
+// An Operand is one of the sides of a comparison.
+type Operand struct {
+	Kind OperandKind
+	// Ident is the name of the value, for OperandIdent operands, of the
+	// function, for OperandCall operands, or of the pattern, for OperandOffset
+	// operands.
+	Ident string
+	// Args are the arguments of OperandCall operands.
+	Args []Operand
+	// Str is the value of OperandString operands.
+	Str string
+	// Int is the value of OperandInt operands, or the 1-based index of the
+	// match, for OperandOffset operands.
+	Int int64
+	// Float is the value of OperandFloat operands.
+	Float float64
+	// text is the operand as written in the condition.
+	text string
+}
+
+func (o Operand) String() string {
+	switch o.Kind {
+	case OperandIdent:
+		return o.Ident
+	case OperandCall:
+		args := make([]string, len(o.Args))
+		for i, arg := range o.Args {
+			args[i] = arg.String()
+		}
+		return o.Ident + "(" + strings.Join(args, ", ") + ")"
+	case OperandString:
+		return strconv.Quote(o.Str)
+	}
+
+	return o.text
+}
+
+// A Comparison compares two operands (e.g. sha256 == "e3b0..."). Comparisons
+// without an Op test a boolean value, the Lhs, on its own, and have no Rhs
+// (e.g. elf.has_section(".upx")).
+//
+// Comparisons aren't evaluated by the expression, as only the caller knows
+// the values they refer to. Instead, they're evaluated like variables: their
+// result is looked up in the variables map, by the comparison's String.
+type Comparison struct {
+	Op       string
+	Lhs, Rhs Operand
+}
+
+// String returns the comparison in canonical form, which is the name of the
+// variable holding its result when the expression is evaluated.
+func (c Comparison) String() string {
+	if c.Op == "" {
+		return c.Lhs.String()
+	}
+
+	return c.Lhs.String() + " " + c.Op + " " + c.Rhs.String()
+}
+
+// A cmpCondition is a comparison. Like a variable, it doesn't have operands in
+// the boolean expression, and its result is looked up in the variables map, so
+// it's also a varConditionExpr, although it isn't listed in the Vars.
+type cmpCondition struct {
+	cmp Comparison
+}
+
+// apply returns the result of the comparison, as defined in the passed in map.
+func (c *cmpCondition) apply(vars map[string]bool) (bool, *ErrMissingVarValue) {
+	value, ok := vars[c.cmp.String()]
+	if !ok {
+		return false, &ErrMissingVarValue{OffendingName: c.cmp.String()}
+	}
+
+	return value, nil
+}
+
+func (c *cmpCondition) getName() string {
+	return c.cmp.String()
+}
+
+func (c *cmpCondition) String() string {
+	return c.cmp.String()
+}
+
+// Refs returns the names the comparison refers to, other than those of values
+// and functions: the names passed as arguments (e.g. a in f(a) == 1), at any
+// depth, those of match offsets (e.g. a in @a[1] == 0) and the name checked by
+// CmpAt comparisons (e.g. a in a at 0). Each name appears only once.
+func (c Comparison) Refs() []string {
+	var names []string
+
+	add := func(name string) {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	var walk func(o Operand, isArg bool)
+	walk = func(o Operand, isArg bool) {
+		switch {
+		case o.Kind == OperandOffset, o.Kind == OperandIdent && isArg:
+			add(o.Ident)
+		case o.Kind == OperandCall:
+			for _, arg := range o.Args {
+				walk(arg, true)
+			}
+		}
+	}
+
+	walk(c.Lhs, c.Op == CmpAt)
+	walk(c.Rhs, false)
+
+	return names
+}
+
+// isCmpOp returns true if the token is a comparison operator.
+func isCmpOp(token string) bool {
+	switch token {
+	case CmpEq, CmpNe, CmpLt, CmpLe, CmpGt, CmpGe, CmpAt:
+		return true
+	}
+
+	return false
+}
+
+// startsComparison returns true if the token, followed by the next token, is
+// the start of a comparison: a literal, a match offset, a value followed by a
+// comparison operator, a dotted name or a function call. Names and numbers
+// alone are variable names (e.g. "1 AND sha256" has two variables).
+func startsComparison(token, next string) bool {
+	return strings.HasPrefix(token, `"`) ||
+		strings.HasPrefix(token, "@") ||
+		isCmpOp(next) ||
+		strings.Contains(token, ".") ||
+		next == tokenGroupStart && identRe.MatchString(token)
+}
+
+// parseComparison parses the comparison starting with the lhs token, whose
+// operator and rhs, if any, are the next tokens in the iterator.
+func parseComparison(lhsToken string, iter *tokenIter) (*cmpCondition, *ErrConditionParse) {
+	cmpErr := func(err error) *ErrConditionParse {
+		return &ErrConditionParse{
+			OffendingCond: iter.condition,
+			Reason:        ParseErrInvalidComparison,
+			Details:       err.Error(),
+		}
+	}
+
+	lhs, err := parseOperand(lhsToken, iter)
+	if err != nil {
+		return nil, cmpErr(err)
+	}
+
+	op := iter.peek()
+	if !isCmpOp(op) {
+		// Dotted names and function calls can be boolean values on their own.
+		if lhs.Kind == OperandCall || lhs.Kind == OperandIdent && strings.Contains(lhs.Ident, ".") {
+			return &cmpCondition{cmp: Comparison{Lhs: lhs}}, nil
+		}
+
+		return nil, cmpErr(fmt.Errorf("%s must be compared to a value, as in '%s == 1'", lhs, lhs))
+	}
+	iter.next()
+
+	if !iter.hasNext() {
+		return nil, cmpErr(fmt.Errorf("missing value after '%s %s'", lhs, op))
+	}
+
+	rhs, err := parseOperand(iter.next(), iter)
+	if err != nil {
+		return nil, cmpErr(err)
+	}
+
+	return &cmpCondition{cmp: Comparison{Op: op, Lhs: lhs, Rhs: rhs}}, nil
+}
+
+// parseOperand parses the token as a comparison operand. The arguments of
+// function calls are read from the iterator.
+func parseOperand(token string, iter *tokenIter) (Operand, error) {
+	if strings.HasPrefix(token, `"`) {
+		str, err := strconv.Unquote(token)
+		if err != nil {
+			return Operand{}, fmt.Errorf("invalid string %s", token)
+		}
+		return Operand{Kind: OperandString, Str: str, text: token}, nil
+	}
+
+	if intRe.MatchString(token) {
+		var (
+			value int64
+			text  string
+			err   error
+		)
+		if hexDigits, isHex := strings.CutPrefix(token, "0x"); isHex {
+			value, err = strconv.ParseInt(hexDigits, 16, 64)
+			text = strings.ToLower(token)
+		} else {
+			value, err = strconv.ParseInt(token, 10, 64)
+			text = strconv.FormatInt(value, 10)
+		}
+		if err != nil {
+			return Operand{}, fmt.Errorf("'%s' is out of range", token)
+		}
+
+		return Operand{Kind: OperandInt, Int: value, text: text}, nil
+	}
+
+	if floatRe.MatchString(token) {
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return Operand{}, fmt.Errorf("'%s' is out of range", token)
+		}
+
+		return Operand{Kind: OperandFloat, Float: value, text: strconv.FormatFloat(value, 'f', -1, 64)}, nil
+	}
+
+	if match := offsetRe.FindStringSubmatch(token); match != nil {
+		index := int64(1)
+		if match[2] != "" {
+			var err error
+			if index, err = strconv.ParseInt(match[2], 10, 64); err != nil || index < 1 {
+				return Operand{}, fmt.Errorf("'%s' has to index a match from 1", token)
+			}
+		}
+
+		return Operand{
+			Kind:  OperandOffset,
+			Ident: match[1],
+			Int:   index,
+			text:  fmt.Sprintf("@%s[%d]", match[1], index),
+		}, nil
+	}
+
+	if !identRe.MatchString(token) {
+		return Operand{}, fmt.Errorf("'%s' isn't a value", token)
+	}
+
+	if iter.peek() != tokenGroupStart {
+		return Operand{Kind: OperandIdent, Ident: token, text: token}, nil
+	}
+	iter.next()
+
+	call := Operand{Kind: OperandCall, Ident: token, Args: []Operand{}}
+	if iter.peek() == tokenGroupEnd {
+		iter.next()
+		return call, nil
+	}
+
+	for iter.hasNext() {
+		arg, err := parseOperand(iter.next(), iter)
+		if err != nil {
+			return Operand{}, err
+		}
+		call.Args = append(call.Args, arg)
+
+		switch iter.peek() {
+		case tokenArgSep:
+			iter.next()
+		case tokenGroupEnd:
+			iter.next()
+			return call, nil
+		default:
+			return Operand{}, fmt.Errorf("missing ')' after the arguments of '%s'", token)
+		}
+	}
+
+	return Operand{}, fmt.Errorf("missing ')' after the arguments of '%s'", token)
+}
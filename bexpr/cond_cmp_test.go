@@ -0,0 +1,188 @@
+package bexpr
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseComparison(t *testing.T) {
+	for _, tCase := range []struct {
+		cond string
+		want []Comparison
+	}{
+		{
+			cond: `sha256 == "AB"`,
+			want: []Comparison{{
+				Op:  CmpEq,
+				Lhs: Operand{Kind: OperandIdent, Ident: "sha256", text: "sha256"},
+				Rhs: Operand{Kind: OperandString, Str: "AB", text: `"AB"`},
+			}},
+		},
+		{
+			cond: `a AND NOT (size>=0x1F OR 010 != size) AND size >= 0x1f`,
+			want: []Comparison{
+				{
+					Op:  CmpGe,
+					Lhs: Operand{Kind: OperandIdent, Ident: "size", text: "size"},
+					Rhs: Operand{Kind: OperandInt, Int: 31, text: "0x1f"},
+				},
+				{
+					Op:  CmpNe,
+					Lhs: Operand{Kind: OperandInt, Int: 10, text: "10"},
+					Rhs: Operand{Kind: OperandIdent, Ident: "size", text: "size"},
+				},
+			},
+		},
+		{cond: "a AND 1 OR 2", want: nil},
+		{
+			cond: `elf.has_section(".upx") AND elf.section_size(".text", 0x1) > 10 AND f()`,
+			want: []Comparison{
+				{
+					Lhs: Operand{Kind: OperandCall, Ident: "elf.has_section", Args: []Operand{
+						{Kind: OperandString, Str: ".upx", text: `".upx"`},
+					}},
+				},
+				{
+					Op: CmpGt,
+					Lhs: Operand{Kind: OperandCall, Ident: "elf.section_size", Args: []Operand{
+						{Kind: OperandString, Str: ".text", text: `".text"`},
+						{Kind: OperandInt, Int: 1, text: "0x1"},
+					}},
+					Rhs: Operand{Kind: OperandInt, Int: 10, text: "10"},
+				},
+				{Lhs: Operand{Kind: OperandCall, Ident: "f", Args: []Operand{}}},
+			},
+		},
+		{
+			cond: "NOT elf.is_pie",
+			want: []Comparison{{Lhs: Operand{Kind: OperandIdent, Ident: "elf.is_pie", text: "elf.is_pie"}}},
+		},
+		{
+			cond: "a at entrypoint AND NOT b at 0x200",
+			want: []Comparison{
+				{
+					Op:  CmpAt,
+					Lhs: Operand{Kind: OperandIdent, Ident: "a", text: "a"},
+					Rhs: Operand{Kind: OperandIdent, Ident: "entrypoint", text: "entrypoint"},
+				},
+				{
+					Op:  CmpAt,
+					Lhs: Operand{Kind: OperandIdent, Ident: "b", text: "b"},
+					Rhs: Operand{Kind: OperandInt, Int: 0x200, text: "0x200"},
+				},
+			},
+		},
+		{cond: "at OR NOT at", want: nil},
+		{
+			cond: "uint16(@a[2]) == 0x5a4d AND @b < 0x100",
+			want: []Comparison{
+				{
+					Op: CmpEq,
+					Lhs: Operand{Kind: OperandCall, Ident: "uint16", Args: []Operand{
+						{Kind: OperandOffset, Ident: "a", Int: 2, text: "@a[2]"},
+					}},
+					Rhs: Operand{Kind: OperandInt, Int: 0x5a4d, text: "0x5a4d"},
+				},
+				{
+					Op:  CmpLt,
+					Lhs: Operand{Kind: OperandOffset, Ident: "b", Int: 1, text: "@b[1]"},
+					Rhs: Operand{Kind: OperandInt, Int: 0x100, text: "0x100"},
+				},
+			},
+		},
+		{
+			cond: "math.entropy(0, 10) > 7.20 AND 0.5 <= x",
+			want: []Comparison{
+				{
+					Op: CmpGt,
+					Lhs: Operand{Kind: OperandCall, Ident: "math.entropy", Args: []Operand{
+						{Kind: OperandInt, Int: 0, text: "0"},
+						{Kind: OperandInt, Int: 10, text: "10"},
+					}},
+					Rhs: Operand{Kind: OperandFloat, Float: 7.2, text: "7.2"},
+				},
+				{
+					Op:  CmpLe,
+					Lhs: Operand{Kind: OperandFloat, Float: 0.5, text: "0.5"},
+					Rhs: Operand{Kind: OperandIdent, Ident: "x", text: "x"},
+				},
+			},
+		},
+	} {
+		t.Run(
+			fmt.Sprintf("parse the comparisons in '%s'", tCase.cond),
+			func(t *testing.T) {
+				expr, err := Parse(tCase.cond)
+				if err != nil {
+					t.Fatalf("Want no error, got %s", err)
+				}
+
+				assert.Equal(t, tCase.want, expr.Comparisons())
+			})
+	}
+
+	for _, cond := range []string{
+		`"AB"`,
+		"sha256 ==",
+		"== 1",
+		"a == b == c",
+		"size > 99999999999999999999",
+		"size > AND",
+		"f(1",
+		"f(1 2)",
+		"f(1,)",
+		"f(AND)",
+		"a at",
+		"at 0",
+		"@a",
+		"@a[0] == 1",
+	} {
+		t.Run(
+			fmt.Sprintf("invalid comparison '%s' yields a parsing error", cond),
+			func(t *testing.T) {
+				_, err := Parse(cond)
+
+				assert.NotNil(t, err)
+			})
+	}
+
+	t.Run("comparisons are evaluated by their canonical form", func(t *testing.T) {
+		expr, _ := Parse(`a AND (size>0x10 OR name=="x")`)
+
+		assert.Equal(t, []string{"a"}, expr.Vars())
+
+		_, missing := expr.Eval(map[string]bool{"a": true})
+		assert.Equal(t, "size > 0x10", missing.OffendingName)
+
+		got, err := expr.Eval(map[string]bool{"a": true, "size > 0x10": false, `name == "x"`: true})
+		assert.Nil(t, err)
+		assert.True(t, got)
+	})
+
+	t.Run("comparisons are analyzed as variables", func(t *testing.T) {
+		expr, _ := Parse(`a OR (a AND size > 1)`)
+
+		assert.Equal(t, []string{"size > 1"}, expr.Analyze().Irrelevant)
+	})
+
+	t.Run("comparisons refer to the names in their arguments and match offsets", func(t *testing.T) {
+		expr, _ := Parse(`f(a, g(b, "c"), 1) == @d AND e at x AND sha256 == "x"`)
+		comparisons := expr.Comparisons()
+
+		assert.Equal(t, []string{"a", "b", "d"}, comparisons[0].Refs())
+		assert.Equal(t, []string{"e"}, comparisons[1].Refs())
+		assert.Empty(t, comparisons[2].Refs())
+	})
+
+	t.Run("comparisons are printed in canonical form, or by the printer", func(t *testing.T) {
+		expr, _ := Parse(`NOT (sha1=="a\"b") AND size<=1 OR f(1,"x")`)
+
+		assert.Equal(t, `NOT sha1 == "a\"b" AND size <= 1 OR f(1, "x")`, DefaultPrinter.Print(expr))
+
+		printer := DefaultPrinter
+		printer.Cmp = func(cmp Comparison) string { return "cmp(" + cmp.Lhs.Ident + ")" }
+		assert.Equal(t, "NOT cmp(sha1) AND cmp(size) OR cmp(f)", printer.Print(expr))
+	})
+}
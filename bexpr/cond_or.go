@@ -39,6 +39,12 @@ func (c *orCondition) setRhs(expr conditionExpr) *errAppendToCond {
 	return err
 }
 
+// replaceRhs sets the rhs, replacing the existing one, if any.
+// Unlike setRhs, any expression can be set, including binary expressions.
+func (c *orCondition) replaceRhs(expr conditionExpr) {
+	c.rhs = expr
+}
+
 func (c *orCondition) getRhs() conditionExpr {
 	return c.rhs
 }
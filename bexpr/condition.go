@@ -48,24 +48,27 @@ type Condition func(map[string]bool) (bool, *ErrMissingVarValue)
 //   - "a AND (b OR c)"
 //   - "a AND NOT b"
 //   - "a AND NOT (b OR c)"
+//   - "a OR b AND c", which is the same as "a OR (b AND c)"
+//
+// NOT binds tighter than AND, and AND tighter than OR. Operations with the same
+// precedence are evaluated from left to right.
+//
+// Conditions can also compare values, which can be named values, strings
+// between double quotes, or decimal and hexadecimal integers, with the ==, !=,
+// <, <=, > and >= operators (e.g. "a AND size > 0x400"), and check where a
+// pattern matches with the at operator (e.g. "a at entrypoint"). Values can also
+// be the offsets of the matches of a pattern (e.g. "@a[1] < 0x400"). The results of
+// the comparisons are passed to the condition in the variables map, by their
+// canonical form (see Comparison).
 //
 // If the expression can't be parsed, an ErrConditionParse error is returned.
 func ParseCondition(condition string) (Condition, *ErrConditionParse) {
-	iter := makeTokenIter(condition)
-	expr, err := parse(iter)
+	expr, err := Parse(condition)
 	if err != nil {
 		return nil, err
 	}
 
-	cond := func(vars map[string]bool) (bool, *ErrMissingVarValue) {
-		if expr == nil {
-			return false, nil
-		}
-
-		return expr.apply(vars)
-	}
-
-	return cond, nil
+	return expr.Eval, nil
 }
 
 func parse(iter *tokenIter) (conditionExpr, *ErrConditionParse) {
@@ -116,6 +119,29 @@ outerLoop:
 			}
 
 		default:
+			// Comparisons are read as a whole, as they're leaves of the expression.
+			if startsComparison(token, iter.peek()) {
+				cmp, parseErr := parseComparison(token, iter)
+				if parseErr != nil {
+					return nil, parseErr
+				}
+
+				expr, err = appendToCondition(expr, cmp)
+				if err != nil {
+					return nil, err.toParseErr(iter.condition)
+				}
+				continue
+			}
+
+			// "at" is also a valid variable name, when it isn't an operator.
+			if isCmpOp(token) && token != CmpAt {
+				return nil, &ErrConditionParse{
+					OffendingCond: iter.condition,
+					Reason:        ParseErrInvalidComparison,
+					Details:       fmt.Sprintf("missing value before '%s'", token),
+				}
+			}
+
 			// Check if token is a valid variable name
 			// Invalid variable names directly trigger an error, as they are unrecoverable
 			if IsValidVarName(token) {
@@ -195,4 +195,59 @@ func TestParseCondition(t *testing.T) {
 			runConditionTestCase("a AND (b AND NOT c)", tCase)
 		})
 	}
+
+	for _, tCase := range []struct {
+		cond string
+		same string
+	}{
+		{cond: "a AND b AND c", same: "(a AND b) AND c"},
+		{cond: "a OR b AND c", same: "a OR (b AND c)"},
+		{cond: "a AND b OR c", same: "(a AND b) OR c"},
+		{cond: "NOT a AND b", same: "(NOT a) AND b"},
+		{cond: "a OR NOT b AND c OR d", same: "(a OR ((NOT b) AND c)) OR d"},
+		{cond: "(a OR b) AND c", same: "c AND (a OR b)"},
+		// Binary operations used to be chained only with parentheses around
+		// their rhs, which is how these conditions, which parsed before, still
+		// parse.
+		{cond: "a AND (b OR c)", same: "(b OR c) AND a"},
+		{cond: "a OR (b AND c)", same: "(b AND c) OR a"},
+		{cond: "a AND NOT (b OR c)", same: "a AND NOT b AND NOT c"},
+	} {
+		t.Run(
+			fmt.Sprintf("Condition '%s' is the same as '%s'", tCase.cond, tCase.same),
+			func(t *testing.T) {
+				cond, err := ParseCondition(tCase.cond)
+				if err != nil {
+					t.Fatalf("Want no error, got %s", err)
+				}
+				same, _ := ParseCondition(tCase.same)
+
+				for i := 0; i < 16; i++ {
+					input := map[string]bool{
+						"a": i&1 != 0, "b": i&2 != 0, "c": i&4 != 0, "d": i&8 != 0,
+					}
+					got, _ := cond(input)
+					want, _ := same(input)
+
+					assert.Equal(t, want, got, input)
+				}
+			})
+	}
+
+	// These conditions chain binary operations without parentheses, and used to
+	// be rejected.
+	for _, cond := range []string{"a AND b AND c", "a OR b AND c", "(a OR b) AND c", "NOT a AND b"} {
+		t.Run(fmt.Sprintf("Condition '%s' parses", cond), func(t *testing.T) {
+			_, err := ParseCondition(cond)
+
+			assert.Nil(t, err)
+		})
+	}
+
+	t.Run("A binary operation after an incomplete one yields a parsing error", func(t *testing.T) {
+		_, err := ParseCondition("a AND NOT OR b")
+		if err == nil {
+			t.Fatal("Expected parsing error, got none")
+		}
+	})
 }
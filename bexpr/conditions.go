@@ -22,7 +22,7 @@ type conditionExpr interface {
 }
 
 // A varConditionExpr is a boolean variable that can be evaluated as being either
-// true or false.
+// true or false. Comparisons are evaluated the same way, by their name.
 type varConditionExpr interface {
 	conditionExpr
 
@@ -36,6 +36,7 @@ type binaryConditionExpr interface {
 
 	hasRhs() bool
 	setRhs(expr conditionExpr) *errAppendToCond
+	replaceRhs(expr conditionExpr)
 	getRhs() conditionExpr
 
 	hasLhs() bool
@@ -53,8 +54,8 @@ type unaryConditionExpr interface {
 	getOp() conditionExpr
 }
 
-// isCondComplete returns whether the passed in condition has its operands
-// defined (if should have them).
+// isCondComplete returns whether the passed in condition, and recursively its
+// operands, have their operands defined (if should have them).
 func isCondComplete(cond conditionExpr) bool {
 	// A nil condition is considered "complete" (there isn't anything missing)
 	if cond == nil {
@@ -67,12 +68,13 @@ func isCondComplete(cond conditionExpr) bool {
 		return true
 
 	case unaryConditionExpr:
-		// A unary condition is complete if it has an operand
-		return typedCond.hasOp()
+		// A unary condition is complete if it has a complete operand
+		return typedCond.hasOp() && isCondComplete(typedCond.getOp())
 
 	case binaryConditionExpr:
-		// A binary condition if it has lhs and rhs
-		return typedCond.hasLhs() && typedCond.hasRhs()
+		// A binary condition if it has complete lhs and rhs
+		return typedCond.hasLhs() && typedCond.hasRhs() &&
+			isCondComplete(typedCond.getLhs()) && isCondComplete(typedCond.getRhs())
 	}
 
 	panic("Forgot to handle a condition type?")
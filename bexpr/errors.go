@@ -16,9 +16,10 @@ func (e ErrMissingVarValue) Error() string {
 type ParseErrorReason string
 
 const (
-	ParseErrInvalidAppend  ParseErrorReason = "invalid append attempt"
-	ParseErrInvalidVarName ParseErrorReason = "invalid variable name"
-	ParseErrIncompleteExpr ParseErrorReason = "incomplete binary operation"
+	ParseErrInvalidAppend     ParseErrorReason = "invalid append attempt"
+	ParseErrInvalidVarName    ParseErrorReason = "invalid variable name"
+	ParseErrIncompleteExpr    ParseErrorReason = "incomplete binary operation"
+	ParseErrInvalidComparison ParseErrorReason = "invalid comparison"
 )
 
 // ErrConditionParse is returned when a condition expression can't be parsed due
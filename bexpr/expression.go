@@ -0,0 +1,119 @@
+package bexpr
+
+import "sort"
+
+// An Expression is a parsed condition.
+// Unlike a Condition, which can only be evaluated, an Expression can also be
+// inspected, for example, to find out which variables it uses.
+type Expression struct {
+	condition string
+	root      conditionExpr
+}
+
+// Parse parses a condition string and returns its Expression.
+// The syntax is the same as that accepted by ParseCondition.
+//
+// If the expression can't be parsed, an ErrConditionParse error is returned.
+func Parse(condition string) (*Expression, *ErrConditionParse) {
+	iter := makeTokenIter(condition)
+	root, err := parse(iter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expression{condition: condition, root: root}, nil
+}
+
+// Eval evaluates the expression given the variable values in the map.
+// An empty expression always evaluates to false.
+func (e *Expression) Eval(vars map[string]bool) (bool, *ErrMissingVarValue) {
+	if e.root == nil {
+		return false, nil
+	}
+
+	return e.root.apply(vars)
+}
+
+// Vars returns the sorted names of the variables used in the expression.
+// Each name appears only once, regardless of how many times it's used.
+func (e *Expression) Vars() []string {
+	var (
+		seen  = make(map[string]bool)
+		names []string
+	)
+
+	walkVars(e.root, func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	})
+
+	sort.Strings(names)
+	return names
+}
+
+// Comparisons returns the comparisons in the expression, in the order they
+// appear. Each comparison appears only once, regardless of how many times it's
+// used.
+//
+// Their results have to be passed to Eval, as the value of the variables named
+// after their String.
+func (e *Expression) Comparisons() []Comparison {
+	var (
+		seen        = make(map[string]bool)
+		comparisons []Comparison
+	)
+
+	walkComparisons(e.root, func(cmp Comparison) {
+		if !seen[cmp.String()] {
+			seen[cmp.String()] = true
+			comparisons = append(comparisons, cmp)
+		}
+	})
+
+	return comparisons
+}
+
+func (e *Expression) String() string {
+	if e.root == nil {
+		return ""
+	}
+
+	return e.root.String()
+}
+
+// walkVars calls fn with the name of every variable found in the expression,
+// in the order they appear.
+func walkVars(expr conditionExpr, fn func(name string)) {
+	switch typedExpr := expr.(type) {
+	case *cmpCondition:
+		// Comparisons aren't variables, although they're evaluated like them.
+
+	case varConditionExpr:
+		fn(typedExpr.getName())
+
+	case unaryConditionExpr:
+		walkVars(typedExpr.getOp(), fn)
+
+	case binaryConditionExpr:
+		walkVars(typedExpr.getLhs(), fn)
+		walkVars(typedExpr.getRhs(), fn)
+	}
+}
+
+// walkComparisons calls fn with every comparison found in the expression, in
+// the order they appear.
+func walkComparisons(expr conditionExpr, fn func(cmp Comparison)) {
+	switch typedExpr := expr.(type) {
+	case *cmpCondition:
+		fn(typedExpr.cmp)
+
+	case unaryConditionExpr:
+		walkComparisons(typedExpr.getOp(), fn)
+
+	case binaryConditionExpr:
+		walkComparisons(typedExpr.getLhs(), fn)
+		walkComparisons(typedExpr.getRhs(), fn)
+	}
+}
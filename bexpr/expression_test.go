@@ -0,0 +1,48 @@
+package bexpr
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpression(t *testing.T) {
+	for _, tCase := range []struct {
+		cond string
+		want []string
+	}{
+		{cond: "", want: nil},
+		{cond: "a", want: []string{"a"}},
+		{cond: "b AND NOT a", want: []string{"a", "b"}},
+		{cond: "a AND (b OR NOT (c OR a))", want: []string{"a", "b", "c"}},
+	} {
+		t.Run(
+			fmt.Sprintf("variables of '%s'", tCase.cond),
+			func(t *testing.T) {
+				expr, err := Parse(tCase.cond)
+				if err != nil {
+					t.Fatalf("Want no error, got %s", err)
+				}
+
+				assert.Equal(t, tCase.want, expr.Vars())
+			})
+	}
+
+	t.Run("Eval", func(t *testing.T) {
+		expr, _ := Parse("a AND NOT b")
+
+		got, err := expr.Eval(map[string]bool{"a": true, "b": false})
+		assert.Nil(t, err)
+		assert.True(t, got)
+
+		_, err = expr.Eval(map[string]bool{"a": true})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("String", func(t *testing.T) {
+		expr, _ := Parse("  a AND (b  OR c)")
+
+		assert.Equal(t, "a AND (b OR c)", expr.String())
+	})
+}
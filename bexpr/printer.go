@@ -0,0 +1,97 @@
+package bexpr
+
+// A Printer prints expressions with the given keywords for the operations and
+// the minimum parentheses required by their precedence, regardless of the
+// parentheses in the original condition.
+//
+// Printers can be used to translate expressions into other languages with the
+// same boolean operations, like the conditions of YARA rules:
+//
+//	yaraPrinter := Printer{
+//		And: "and",
+//		Or:  "or",
+//		Not: "not",
+//		Var: func(name string) string { return "$" + name },
+//	}
+//	yaraPrinter.Print(expr) // "$a and ($b or not $c)"
+type Printer struct {
+	And, Or, Not string
+	// Var returns how a variable is printed. If nil, variables are printed by
+	// their name.
+	Var func(name string) string
+	// Cmp returns how a comparison is printed. If nil, comparisons are printed
+	// in canonical form (see Comparison.String).
+	Cmp func(cmp Comparison) string
+}
+
+// DefaultPrinter prints expressions as conditions that Parse reads back.
+var DefaultPrinter = Printer{And: tokenAnd, Or: tokenOr, Not: tokenNot}
+
+// Print returns the expression as a string. Empty expressions yield an empty
+// string.
+func (p Printer) Print(e *Expression) string {
+	if e.root == nil {
+		return ""
+	}
+
+	return p.print(e.root)
+}
+
+func (p Printer) print(expr conditionExpr) string {
+	switch typedExpr := ungroup(expr).(type) {
+	case *cmpCondition:
+		if p.Cmp == nil {
+			return typedExpr.cmp.String()
+		}
+		return p.Cmp(typedExpr.cmp)
+
+	case varConditionExpr:
+		if p.Var == nil {
+			return typedExpr.getName()
+		}
+		return p.Var(typedExpr.getName())
+
+	case unaryConditionExpr:
+		op := ungroup(typedExpr.getOp())
+		if _, isBinary := op.(binaryConditionExpr); isBinary {
+			return p.Not + " (" + p.print(op) + ")"
+		}
+		return p.Not + " " + p.print(op)
+
+	case binaryConditionExpr:
+		keyword := p.Or
+		if _, isAnd := typedExpr.(*andCondition); isAnd {
+			keyword = p.And
+		}
+
+		return p.printOperand(typedExpr, typedExpr.getLhs()) +
+			" " + keyword + " " +
+			p.printOperand(typedExpr, typedExpr.getRhs())
+	}
+
+	panic("Forgot to handle a condition type?")
+}
+
+// printOperand prints the operand of the binary expression, in parentheses if
+// it's a binary expression that binds looser. Binary operations of the same
+// precedence are associative, so they never need parentheses.
+func (p Printer) printOperand(parent binaryConditionExpr, operand conditionExpr) string {
+	operand = ungroup(operand)
+	if binOperand, isBinary := operand.(binaryConditionExpr); isBinary && precedence(binOperand) < precedence(parent) {
+		return "(" + p.print(operand) + ")"
+	}
+
+	return p.print(operand)
+}
+
+// ungroup returns the expression inside the parentheses, if the expression is
+// a group.
+func ungroup(expr conditionExpr) conditionExpr {
+	for {
+		group, isGroup := expr.(*groupCondition)
+		if !isGroup {
+			return expr
+		}
+		expr = group.getOp()
+	}
+}
@@ -0,0 +1,56 @@
+package bexpr
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrinter(t *testing.T) {
+	for _, tCase := range []struct {
+		cond string
+		want string
+	}{
+		{cond: "a", want: "a"},
+		{cond: "((a))", want: "a"},
+		{cond: "a AND (b OR c)", want: "a AND (b OR c)"},
+		{cond: "(a AND b) OR c", want: "a AND b OR c"},
+		{cond: "a OR (b AND c)", want: "a OR b AND c"},
+		{cond: "a AND (b AND c)", want: "a AND b AND c"},
+		{cond: "NOT (a)", want: "NOT a"},
+		{cond: "NOT (a OR b) AND NOT NOT c", want: "NOT (a OR b) AND NOT NOT c"},
+		{cond: "(a OR b) AND (c OR (d AND e))", want: "(a OR b) AND (c OR d AND e)"},
+	} {
+		t.Run(
+			fmt.Sprintf("print '%s' as '%s'", tCase.cond, tCase.want),
+			func(t *testing.T) {
+				expr, err := Parse(tCase.cond)
+				if err != nil {
+					t.Fatalf("Want no error, got %s", err)
+				}
+
+				assert.Equal(t, tCase.want, DefaultPrinter.Print(expr))
+			})
+	}
+
+	t.Run("custom keywords and variables", func(t *testing.T) {
+		var (
+			expr, _ = Parse("a AND (b OR NOT c)")
+			printer = Printer{
+				And: "and",
+				Or:  "or",
+				Not: "not",
+				Var: func(name string) string { return "$" + name },
+			}
+		)
+
+		assert.Equal(t, "$a and ($b or not $c)", printer.Print(expr))
+	})
+
+	t.Run("empty expressions print empty", func(t *testing.T) {
+		expr, _ := Parse("")
+
+		assert.Equal(t, "", DefaultPrinter.Print(expr))
+	})
+}
@@ -11,16 +11,21 @@ const (
 	tokenNot        = "NOT"
 	tokenGroupStart = "("
 	tokenGroupEnd   = ")"
+	tokenArgSep     = ","
 )
 
 var (
+	// String literals, match offsets, comparison operators, hexadecimal and
+	// decimal numbers and dotted names come first, so that they're tokenized as
+	// a whole.
 	tokensStr = fmt.Sprintf(
-		`[a-z0-9_]+|%s|%s|%s|%s|%s`,
+		`"(?:[^"\\]|\\.)*"|@[a-z0-9_]+(?:\[[0-9]+\])?|==|!=|<=|>=|<|>|0x[0-9a-fA-F]+|[0-9]+\.[0-9]+|[a-z_][a-z0-9_]*(?:\.[a-z_][a-z0-9_]*)+|[a-z0-9_]+|%s|%s|%s|%s|%s|%s`,
 		tokenAnd,
 		tokenOr,
 		tokenNot,
 		regexp.QuoteMeta(tokenGroupStart),
 		regexp.QuoteMeta(tokenGroupEnd),
+		tokenArgSep,
 	)
 
 	tokensRe = regexp.MustCompile(tokensStr)
@@ -72,6 +77,16 @@ func (iter *tokenIter) next() string {
 	return next
 }
 
+// peek returns the next token without advancing the iterator, or an empty
+// string if the iteration finished.
+func (iter *tokenIter) peek() string {
+	if !iter.hasNext() {
+		return ""
+	}
+
+	return iter.tokens[iter.nextIdx]
+}
+
 func (iter *tokenIter) getAll() []string {
 	tokens := make([]string, 0, len(iter.tokens))
 
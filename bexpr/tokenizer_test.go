@@ -15,6 +15,16 @@ func TestTokenize(t *testing.T) {
 		{cond: "a AND (b OR c)", want: []string{"a", "AND", "(", "b", "OR", "c", ")"}},
 		{cond: "  a   AND (  b OR c )  ", want: []string{"a", "AND", "(", "b", "OR", "c", ")"}},
 		{cond: "foo78 OR NOT bar23", want: []string{"foo78", "OR", "NOT", "bar23"}},
+		{
+			cond: `a AND sha256=="AB \"c\" d" OR size >= 0x1F`,
+			want: []string{"a", "AND", "sha256", "==", `"AB \"c\" d"`, "OR", "size", ">=", "0x1F"},
+		},
+		{
+			cond: `elf.has_section(".upx", 1) OR elf.type=="exec"`,
+			want: []string{"elf.has_section", "(", `".upx"`, ",", "1", ")", "OR", "elf.type", "==", `"exec"`},
+		},
+		{cond: "x!=1 AND y<2 OR z>3", want: []string{"x", "!=", "1", "AND", "y", "<", "2", "OR", "z", ">", "3"}},
+		{cond: "math.entropy(0, 10)>7.25", want: []string{"math.entropy", "(", "0", ",", "10", ")", ">", "7.25"}},
 	} {
 
 		t.Run(
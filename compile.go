@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	sigio "github.com/angelsolaorbaiceta/binmat/signature/io"
+)
+
+// runCompile loads and validates the signatures in the paths passed as
+// arguments, or in the default signatures directory if none is given, and
+// writes them into a bundle file that loads faster.
+func runCompile(args []string) {
+	var (
+		flags   = flag.NewFlagSet("compile", flag.ExitOnError)
+		outPath string
+	)
+
+	flags.StringVar(&outPath, "o", "signatures.bmat", "path to the bundle file to write")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s compile [-o bundle] [file|directory]...\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	sigs := loadSignatures(flags.Args())
+
+	file, err := os.Create(outPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't create the bundle file: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := sigio.WriteBundle(file, sigs); err != nil {
+		file.Close()
+		fmt.Fprintf(os.Stderr, "Can't write the bundle file: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := file.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't write the bundle file: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Compiled %d signatures into '%s'.\n", len(sigs), outPath)
+}
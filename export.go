@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/angelsolaorbaiceta/binmat/signature/yara"
+)
+
+// runExport loads the signatures in the paths passed as arguments, or in the
+// default signatures directory if none is given, and writes them in another
+// format. YARA is the only format signatures can be exported to.
+func runExport(args []string) {
+	var (
+		flags   = flag.NewFlagSet("export", flag.ExitOnError)
+		toYara  bool
+		outPath string
+	)
+
+	flags.BoolVar(&toYara, "yara", false, "export the signatures as YARA rules")
+	flags.StringVar(&outPath, "o", "", "path to the file to write (defaults to stdout)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export -yara [-o file] [file|directory]...\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if !toYara {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	sigs := loadSignatures(flags.Args())
+
+	var out io.Writer = os.Stdout
+	if outPath != "" {
+		file, err := os.Create(outPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't create the export file: %s\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := yara.Export(out, sigs); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't export the signatures: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	sigio "github.com/angelsolaorbaiceta/binmat/signature/io"
+)
+
+// runFmt rewrites the yaml signature files in the paths passed as arguments, or
+// in the default signatures directory if none is given, in canonical form.
+// In check mode, files aren't rewritten, and it exits with a non zero status if
+// any file isn't formatted.
+func runFmt(args []string) {
+	var (
+		flags = flag.NewFlagSet("fmt", flag.ExitOnError)
+		check bool
+	)
+
+	flags.BoolVar(&check, "check", false, "list the files that aren't formatted, without rewriting them")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s fmt [-check] [file|directory]...\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	filePaths, err := sigio.FindSignatureFiles(sigPathsOrDefault(flags.Args())...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding the signature files: %s\n", err)
+		os.Exit(1)
+	}
+
+	var unformatted, failed int
+	for _, filePath := range filePaths {
+		// Only yaml files are formatted.
+		if strings.EqualFold(filepath.Ext(filePath), ".json") {
+			continue
+		}
+
+		src, err := os.ReadFile(filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't read '%s': %s\n", filePath, err)
+			failed++
+			continue
+		}
+
+		formatted, err := sigio.FormatYaml(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't format '%s': %s\n", filePath, err)
+			failed++
+			continue
+		}
+
+		if bytes.Equal(src, formatted) {
+			continue
+		}
+
+		unformatted++
+		fmt.Println(filePath)
+
+		if !check {
+			if err := os.WriteFile(filePath, formatted, 0o644); err != nil {
+				fmt.Fprintf(os.Stderr, "Can't write '%s': %s\n", filePath, err)
+				failed++
+			}
+		}
+	}
+
+	if failed > 0 || check && unformatted > 0 {
+		os.Exit(1)
+	}
+}
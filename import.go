@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	sigio "github.com/angelsolaorbaiceta/binmat/signature/io"
+	"github.com/angelsolaorbaiceta/binmat/signature/yara"
+)
+
+// runImport translates the YARA rules in the files passed as arguments into
+// signatures, and writes them as a yaml signatures file.
+// The rules and constructs that can't be imported are reported to stderr.
+func runImport(args []string) {
+	var (
+		flags   = flag.NewFlagSet("import", flag.ExitOnError)
+		outPath string
+	)
+
+	flags.StringVar(&outPath, "o", "", "path to the signatures file to write (defaults to stdout)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s import [-o file] <yara file>...\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	var (
+		importer  = yara.NewImporter()
+		sigs      []sigio.Signature
+		skipCount int
+	)
+
+	for _, yaraPath := range flags.Args() {
+		file, err := os.Open(yaraPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't read the YARA file: %s\n", err)
+			os.Exit(1)
+		}
+
+		fileSigs, skipped, err := importer.Import(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't import '%s': %s\n", yaraPath, err)
+			os.Exit(1)
+		}
+
+		for _, skip := range skipped {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", yaraPath, skip.Line, skip)
+			if skip.RuleSkipped {
+				skipCount++
+			}
+		}
+		sigs = append(sigs, fileSigs...)
+	}
+
+	var out io.Writer = os.Stdout
+	if outPath != "" {
+		file, err := os.Create(outPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't create the signatures file: %s\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := sigio.WriteAllToYaml(out, sigs); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't write the signatures: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %d rules, skipped %d.\n", len(sigs), skipCount)
+}
@@ -1,50 +1,247 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/angelsolaorbaiceta/binmat/signature"
 	sigio "github.com/angelsolaorbaiceta/binmat/signature/io"
 )
 
+// profileTop is the number of signatures reported by the -profile flag.
+const profileTop = 10
+
+// stdinName is the file path of the matches of the standard input, which is
+// scanned when the path is "-".
+const stdinName = "<stdin>"
+
+// A pathsFlag is a command line flag that can be repeated to pass several paths.
+type pathsFlag []string
+
+func (p *pathsFlag) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathsFlag) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <file|directory>\n", os.Args[0])
-		os.Exit(1)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			runValidate(os.Args[2:])
+			return
+		case "compile":
+			runCompile(os.Args[2:])
+			return
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "fmt":
+			runFmt(os.Args[2:])
+			return
+		case "test":
+			runTest(os.Args[2:])
+			return
+		case "bench":
+			runBench(os.Args[2:])
+			return
+		case "scan":
+			runScan(os.Args[2:])
+			return
+		}
 	}
 
-	homePath, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting the user's home path: %s\n", err)
-		os.Exit(1)
+	runScan(os.Args[1:])
+}
+
+// runScan checks the file, every file in the directory or, with -pid, the
+// memory of the process, against the signatures, and reports the matches.
+// It's also the default command, when none is given.
+func runScan(args []string) {
+	var (
+		flags    = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		sigPaths pathsFlag
+		tags     string
+		severity string
+		profile  bool
+		hashes   bool
+		entropy  bool
+		jsonOut  bool
+		archives bool
+		depth    int
+		maxSize  int64
+		password string
+		pid      int
+	)
+
+	flags.Var(&sigPaths, "rules", "signature file, directory or compiled bundle to load (can be repeated)")
+	flags.StringVar(&tags, "tags", "", "comma separated tags of the signatures to run, prefix with '-' to exclude (e.g. packer,-test)")
+	flags.StringVar(&severity, "severity", "", "minimum severity of the signatures to run: info, low, medium, high or critical")
+	flags.BoolVar(&profile, "profile", false, "report the slowest signatures, and the time spent on each of their patterns")
+	flags.BoolVar(&hashes, "hashes", false, "report the MD5, SHA-1 and SHA-256 of the matched files")
+	flags.BoolVar(&entropy, "entropy", false, "report the entropy of the matched files")
+	flags.BoolVar(&jsonOut, "json", false, "write the matches of every file as JSON")
+	flags.BoolVar(&archives, "archives", false, "unpack zip, tar and gzip files and check each member")
+	flags.IntVar(&depth, "archive-depth", signature.DefaultArchiveDepth, "how many archives deep to unpack members")
+	flags.Int64Var(&maxSize, "archive-size", signature.DefaultArchiveMaxSize, "maximum number of bytes to unpack from each file")
+	flags.StringVar(&password, "archive-password", "", "password of encrypted zip files (e.g. infected)")
+	flags.IntVar(&pid, "pid", 0, "scan the memory of the process with this pid instead of a file (Linux only)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [scan] [flags] <file|directory|->\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s scan -pid n [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s validate [file|directory]...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s compile [-o bundle] [file|directory]...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s import [-o file] <yara file>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s export -yara [-o file] [file|directory]...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s fmt [-check] [file|directory]...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s test [-v] [-run regexp] [file|directory]...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s bench [-count n] [-rules path] <file|directory>\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
 	}
+	flags.Parse(args)
 
-	sigsPath := filepath.Join(homePath, ".config/binmat")
-	sigs, err := sigio.LoadSignatures(sigsPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading the .yaml signatures from '%s': %s\n", sigsPath, err)
+	if pid == 0 && flags.NArg() != 1 || pid != 0 && flags.NArg() != 0 {
+		flags.Usage()
 		os.Exit(1)
 	}
 
-	matches := searchMatches(sigs)
+	filter := signature.ParseTagsFilter(tags)
+	if severity != "" {
+		minSeverity, err := signature.ParseSeverity(severity)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -severity flag: %s\n", err)
+			os.Exit(1)
+		}
+		filter.MinSeverity = minSeverity
+	}
+
+	sigs := loadSignatures(sigPaths).Filter(filter)
+
+	opts := signature.CheckOptions{Hashes: hashes, Entropy: entropy}
+	if profile {
+		opts.Profile = signature.NewProfile()
+	}
+	if archives {
+		opts.Archives = &signature.ArchiveOptions{Depth: depth, MaxSize: maxSize, Password: password}
+	}
+
+	var matches []signature.SigMatch
+	if pid != 0 {
+		matches = searchProcessMatches(sigs, pid, opts)
+	} else {
+		matches = searchMatches(sigs, flags.Arg(0), opts)
+	}
+
+	if jsonOut {
+		if err := signature.WriteJSON(os.Stdout, matches); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing the matches: %s\n", err)
+			os.Exit(1)
+		}
+		if opts.Profile != nil {
+			opts.Profile.Write(os.Stderr, profileTop)
+		}
+		return
+	}
+
 	fmt.Printf("Scanned %d files.\n", len(matches))
 	for _, match := range matches {
 		if match.IsMatch {
 			match.Write(os.Stdout)
 		}
 	}
+
+	if opts.Profile != nil {
+		opts.Profile.Write(os.Stdout, profileTop)
+	}
+}
+
+// loadSignatures loads the signatures from the given paths, or from the default
+// signatures directory, "$HOME/.config/binmat", if none is given.
+// Paths to compiled bundles are loaded as such, and the rest as signature files.
+func loadSignatures(sigPaths []string) signature.Signatures {
+	var (
+		filePaths []string
+		sigs      []signature.Signature
+	)
+
+	for _, sigPath := range sigPathsOrDefault(sigPaths) {
+		if !sigio.IsBundleFile(sigPath) {
+			filePaths = append(filePaths, sigPath)
+			continue
+		}
+
+		bundleSigs, err := sigio.LoadBundle(sigPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading the signatures bundle '%s': %s\n", sigPath, err)
+			os.Exit(1)
+		}
+		sigs = append(sigs, bundleSigs...)
+	}
+
+	if len(filePaths) > 0 {
+		fileSigs, err := sigio.LoadSignatures(filePaths...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading the signatures from '%s': %s\n", strings.Join(filePaths, ", "), err)
+			os.Exit(1)
+		}
+		sigs = append(sigs, fileSigs...)
+	}
+
+	// Each bundle and the signature files are linked on their own, but they have
+	// to be linked together to be checked in the right order.
+	linked, err := signature.Link(sigs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error linking the signatures: %s\n", err)
+		os.Exit(1)
+	}
+
+	return linked
+}
+
+// sigPathsOrDefault returns the given paths, or the path to the default
+// signatures directory, "$HOME/.config/binmat", if there are none.
+func sigPathsOrDefault(sigPaths []string) []string {
+	if len(sigPaths) > 0 {
+		return sigPaths
+	}
+
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting the user's home path: %s\n", err)
+		os.Exit(1)
+	}
+
+	return []string{filepath.Join(homePath, ".config/binmat")}
 }
 
-func searchMatches(sigs signature.Signatures) []signature.SigMatch {
+// searchMatches checks the file, or every file in the directory, at the given
+// path against the signatures, with the given options. The path "-" is the
+// standard input.
+func searchMatches(sigs signature.Signatures, path string, opts signature.CheckOptions) []signature.SigMatch {
 	var (
-		path    = os.Args[1]
 		isDir   bool
 		matches []signature.SigMatch
 		err     error
 	)
 
+	if path == "-" {
+		matches, err = sigs.CheckReader(stdinName, os.Stdin, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't read the standard input: %s\n", err)
+			os.Exit(1)
+		}
+		return matches
+	}
+
 	if stat, err := os.Stat(path); err != nil {
 		fmt.Fprintf(os.Stderr, "Can't get '%s' file info: %s\n", path, err)
 		os.Exit(1)
@@ -53,9 +250,9 @@ func searchMatches(sigs signature.Signatures) []signature.SigMatch {
 	}
 
 	if isDir {
-		matches, err = sigs.CheckDir(path)
+		matches, err = sigs.CheckDirWithOptions(path, opts)
 	} else {
-		matches, err = sigs.Check(path)
+		matches, err = sigs.CheckWithOptions(path, opts)
 	}
 
 	if err != nil {
@@ -65,3 +262,15 @@ func searchMatches(sigs signature.Signatures) []signature.SigMatch {
 
 	return matches
 }
+
+// searchProcessMatches checks the memory of the process with the given pid
+// against the signatures, with the given options.
+func searchProcessMatches(sigs signature.Signatures, pid int, opts signature.CheckOptions) []signature.SigMatch {
+	matches, err := sigs.CheckProcess(pid, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't check the memory of process %d for matches: %s\n", pid, err)
+		os.Exit(1)
+	}
+
+	return matches
+}
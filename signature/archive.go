@@ -0,0 +1,294 @@
+package signature
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"hash/crc32"
+	"io"
+	"path"
+	"strings"
+)
+
+// The limits of the archive options when they're zero.
+const (
+	DefaultArchiveDepth   = 3
+	DefaultArchiveMaxSize = 256 << 20
+)
+
+// ArchiveSeparator separates the path of an archive from the path of a member
+// inside it, in the meta of the member's matches (e.g. bundle.zip!inner/bin).
+const ArchiveSeparator = "!"
+
+// zipEncrypted is the general purpose flag of encrypted zip members.
+const zipEncrypted = 0x1
+
+// zipDataDescriptor is the general purpose flag of zip members whose CRC-32
+// and sizes come after their data.
+const zipDataDescriptor = 0x8
+
+// ArchiveOptions set how the members of zip, tar and gzip archives are
+// unpacked to be checked as files of their own. The limits defend against
+// archives that unpack to much more data than they take, like zip bombs.
+type ArchiveOptions struct {
+	// Depth is how many archives deep members are unpacked, 1 being the members
+	// of the checked file. If zero, it's DefaultArchiveDepth.
+	Depth int
+	// MaxSize is the number of bytes unpacked, at most, from each checked file,
+	// across all its members and nested archives. Members that don't fit are
+	// skipped. If zero, it's DefaultArchiveMaxSize.
+	MaxSize int64
+	// Password decrypts the members of zip files encrypted with the traditional
+	// PKWARE encryption, as in "infected".
+	Password string
+}
+
+// An archiveUnpacker unpacks the members of the archives inside a checked
+// file, keeping track of the data left to unpack.
+type archiveUnpacker struct {
+	opts ArchiveOptions
+	left int64
+}
+
+func newArchiveUnpacker(opts ArchiveOptions) *archiveUnpacker {
+	if opts.Depth == 0 {
+		opts.Depth = DefaultArchiveDepth
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = DefaultArchiveMaxSize
+	}
+
+	return &archiveUnpacker{opts: opts, left: opts.MaxSize}
+}
+
+// unpack calls the function with the name and data of each regular file in the
+// data, if it's a zip, tar or gzip archive, in order. Members that can't be
+// read, like encrypted members without the right password, or that don't fit
+// in the data left to unpack, are skipped.
+//
+// The data of a gzip file is its only member, named after the file, without
+// the extension, unless it's a tar archive, whose members are unpacked
+// instead.
+func (u *archiveUnpacker) unpack(name string, data []byte, member func(name string, data []byte)) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		u.unpackZip(data, member)
+	case isTar(data):
+		u.unpackTar(bytes.NewReader(data), member)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		unpacked, err := u.read(reader)
+		if err != nil {
+			return
+		}
+		if isTar(unpacked) {
+			u.unpackTar(bytes.NewReader(unpacked), member)
+			return
+		}
+		member(gzipMemberName(name, reader.Header.Name), unpacked)
+	}
+}
+
+// isTar returns true if the data starts with the header of a POSIX tar file.
+func isTar(data []byte) bool {
+	return len(data) >= 512 && bytes.HasPrefix(data[257:], []byte("ustar"))
+}
+
+// gzipMemberName returns the name of the only member of the named gzip file:
+// the original name kept in its header or, if there's none, the file's name
+// without its extension.
+func gzipMemberName(name, headerName string) string {
+	if headerName != "" {
+		return path.Base(headerName)
+	}
+
+	base := path.Base(strings.ReplaceAll(name, "\\", "/"))
+	switch {
+	case strings.HasSuffix(base, ".tgz"):
+		return strings.TrimSuffix(base, ".tgz") + ".tar"
+	case strings.HasSuffix(base, ".gz"):
+		return strings.TrimSuffix(base, ".gz")
+	}
+
+	return base
+}
+
+func (u *archiveUnpacker) unpackZip(data []byte, member func(name string, data []byte)) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return
+	}
+
+	for _, file := range reader.File {
+		if file.Mode().IsDir() {
+			continue
+		}
+
+		var unpacked []byte
+		if file.Flags&zipEncrypted != 0 {
+			unpacked, err = u.readEncryptedZip(file)
+		} else {
+			unpacked, err = u.readZip(file)
+		}
+		if err != nil {
+			continue
+		}
+
+		member(file.Name, unpacked)
+	}
+}
+
+func (u *archiveUnpacker) readZip(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return u.read(reader)
+}
+
+// readEncryptedZip decrypts the member, encrypted with the traditional PKWARE
+// encryption, with the password, and decompresses it.
+func (u *archiveUnpacker) readEncryptedZip(file *zip.File) ([]byte, error) {
+	raw, err := file.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		keys   = newZipKeys(u.opts.Password)
+		header = make([]byte, 12)
+	)
+
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, err
+	}
+	keys.decrypt(header)
+
+	// The last byte of the header checks the password. It's the high byte of
+	// the CRC-32 or, if it comes after the data, of the modification time.
+	check := byte(file.CRC32 >> 24)
+	if file.Flags&zipDataDescriptor != 0 {
+		check = byte(file.ModifiedTime >> 8)
+	}
+	if header[11] != check {
+		return nil, errors.New("wrong password")
+	}
+
+	var decompressed io.Reader = &zipDecrypter{keys: keys, reader: raw}
+	switch file.Method {
+	case zip.Store:
+	case zip.Deflate:
+		inflater := flate.NewReader(decompressed)
+		defer inflater.Close()
+		decompressed = inflater
+	default:
+		return nil, zip.ErrAlgorithm
+	}
+
+	unpacked, err := u.read(decompressed)
+	if err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(unpacked) != file.CRC32 {
+		return nil, zip.ErrChecksum
+	}
+
+	return unpacked, nil
+}
+
+func (u *archiveUnpacker) unpackTar(r io.Reader, member func(name string, data []byte)) {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err != nil {
+			return
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		unpacked, err := u.read(reader)
+		if err != nil {
+			continue
+		}
+
+		member(header.Name, unpacked)
+	}
+}
+
+// errArchiveTooBig is returned when a member doesn't fit in the data left to
+// unpack.
+var errArchiveTooBig = errors.New("the archive unpacks to more than the maximum size")
+
+// read reads the data of a member, as long as it fits in the data left to
+// unpack, and takes it from it.
+func (u *archiveUnpacker) read(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, u.left+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > u.left {
+		return nil, errArchiveTooBig
+	}
+
+	u.left -= int64(len(data))
+	return data, nil
+}
+
+// zipKeys are the keys of the traditional PKWARE encryption of zip files,
+// which are updated with each byte of plain text.
+type zipKeys [3]uint32
+
+func newZipKeys(password string) *zipKeys {
+	keys := &zipKeys{0x12345678, 0x23456789, 0x34567890}
+	for i := 0; i < len(password); i++ {
+		keys.update(password[i])
+	}
+
+	return keys
+}
+
+func (k *zipKeys) update(b byte) {
+	k[0] = crc32Update(k[0], b)
+	k[1] = (k[1]+k[0]&0xff)*134775813 + 1
+	k[2] = crc32Update(k[2], byte(k[1]>>24))
+}
+
+// stream returns the byte of the key stream the next byte is encrypted with.
+func (k *zipKeys) stream() byte {
+	temp := k[2] | 2
+	return byte((temp * (temp ^ 1)) >> 8)
+}
+
+// decrypt decrypts the data in place.
+func (k *zipKeys) decrypt(data []byte) {
+	for i := range data {
+		data[i] ^= k.stream()
+		k.update(data[i])
+	}
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ crc>>8
+}
+
+// A zipDecrypter decrypts the data of an encrypted zip member as it's read.
+type zipDecrypter struct {
+	keys   *zipKeys
+	reader io.Reader
+}
+
+func (d *zipDecrypter) Read(p []byte) (int, error) {
+	n, err := d.reader.Read(p)
+	d.keys.decrypt(p[:n])
+
+	return n, err
+}
@@ -0,0 +1,185 @@
+package signature
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A testMember is a file inside a test archive.
+type testMember struct {
+	name string
+	data []byte
+}
+
+// makeTestZip returns a zip file with the members, stored without compression,
+// whose data is encrypted with the password, if it isn't empty.
+func makeTestZip(password string, members ...testMember) []byte {
+	var (
+		buf    bytes.Buffer
+		writer = zip.NewWriter(&buf)
+	)
+
+	for _, member := range members {
+		if password == "" {
+			w, _ := writer.CreateHeader(&zip.FileHeader{Name: member.name, Method: zip.Store})
+			w.Write(member.data)
+			continue
+		}
+
+		var (
+			crc    = crc32.ChecksumIEEE(member.data)
+			keys   = newZipKeys(password)
+			header = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, byte(crc >> 24)}
+			plain  = append(header, member.data...)
+			cipher = make([]byte, len(plain))
+		)
+
+		for i, b := range plain {
+			cipher[i] = b ^ keys.stream()
+			keys.update(b)
+		}
+
+		w, _ := writer.CreateRaw(&zip.FileHeader{
+			Name:               member.name,
+			Method:             zip.Store,
+			Flags:              zipEncrypted,
+			CRC32:              crc,
+			CompressedSize64:   uint64(len(cipher)),
+			UncompressedSize64: uint64(len(member.data)),
+		})
+		w.Write(cipher)
+	}
+
+	writer.Close()
+	return buf.Bytes()
+}
+
+// makeTestTarGz returns a gzip compressed tar file with the members.
+func makeTestTarGz(members ...testMember) []byte {
+	var (
+		buf       bytes.Buffer
+		gzWriter  = gzip.NewWriter(&buf)
+		tarWriter = tar.NewWriter(gzWriter)
+	)
+
+	tarWriter.WriteHeader(&tar.Header{Name: "inner/", Typeflag: tar.TypeDir, Mode: 0o755})
+	for _, member := range members {
+		tarWriter.WriteHeader(&tar.Header{Name: member.name, Mode: 0o644, Size: int64(len(member.data))})
+		tarWriter.Write(member.data)
+	}
+
+	tarWriter.Close()
+	gzWriter.Close()
+	return buf.Bytes()
+}
+
+func makeTestGzip(data []byte) []byte {
+	var (
+		buf    bytes.Buffer
+		writer = gzip.NewWriter(&buf)
+	)
+
+	writer.Write(data)
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestCheckArchives(t *testing.T) {
+	var (
+		upx      = MakePattern([]byte("UPX!"))
+		sig, _   = Make("upx", "", map[string]*SignaturePattern{"upx": upx}, "upx")
+		packed   = testMember{name: "inner/bin.exe", data: []byte("MZ..UPX!....")}
+		clean    = testMember{name: "readme.txt", data: []byte("nothing to see here")}
+		innerZip = makeTestZip("", packed)
+	)
+
+	for _, tCase := range []struct {
+		name string
+		data []byte
+		opts *ArchiveOptions
+		// want are the paths of the matched members, relative to the archive,
+		// which matches too when its members are stored.
+		want []string
+	}{
+		{name: "zip", data: makeTestZip("", clean, packed), opts: &ArchiveOptions{}, want: []string{"!inner/bin.exe"}},
+		{name: "zip without unpacking", data: makeTestZip("", clean, packed)},
+		{
+			name: "nested zip",
+			data: makeTestZip("", testMember{name: "inner.zip", data: innerZip}),
+			opts: &ArchiveOptions{},
+			want: []string{"!inner.zip", "!inner.zip!inner/bin.exe"},
+		},
+		{
+			name: "nested zip past the depth",
+			data: makeTestZip("", testMember{name: "inner.zip", data: innerZip}),
+			opts: &ArchiveOptions{Depth: 1},
+			want: []string{"!inner.zip"},
+		},
+		{name: "tar.gz", data: makeTestTarGz(clean, packed), opts: &ArchiveOptions{}, want: []string{"!inner/bin.exe"}},
+		{name: "gzip", data: makeTestGzip(packed.data), opts: &ArchiveOptions{}, want: []string{"!bin"}},
+		{
+			name: "encrypted zip",
+			data: makeTestZip("infected", packed),
+			opts: &ArchiveOptions{Password: "infected"},
+			want: []string{"!inner/bin.exe"},
+		},
+		{name: "encrypted zip with the wrong password", data: makeTestZip("infected", packed), opts: &ArchiveOptions{Password: "other"}},
+		{name: "encrypted zip without password", data: makeTestZip("infected", packed), opts: &ArchiveOptions{}},
+		{
+			name: "zip bigger than the maximum size",
+			data: makeTestZip("", clean, packed),
+			opts: &ArchiveOptions{MaxSize: int64(len(clean.data) + len(packed.data) - 1)},
+		},
+		{name: "other files", data: packed.data, opts: &ArchiveOptions{}},
+	} {
+		t.Run(fmt.Sprintf("check the members of %s", tCase.name), func(t *testing.T) {
+			binPath := filepath.Join(t.TempDir(), "bin")
+			if err := os.WriteFile(binPath, tCase.data, 0o644); err != nil {
+				t.Fatalf("Can't write test file: %s", err)
+			}
+
+			matches, err := Signatures{sig}.CheckWithOptions(binPath, CheckOptions{Archives: tCase.opts})
+
+			var got []string
+			for _, match := range matches {
+				if match.IsMatch && match.Meta.FilePath != binPath {
+					got = append(got, match.Meta.FilePath[len(binPath):])
+				}
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tCase.want, got)
+		})
+	}
+
+	t.Run("the archive and every member are checked", func(t *testing.T) {
+		binPath := filepath.Join(t.TempDir(), "bundle.tgz")
+		if err := os.WriteFile(binPath, makeTestTarGz(clean, packed), 0o644); err != nil {
+			t.Fatalf("Can't write test file: %s", err)
+		}
+
+		matches, _ := Signatures{sig}.CheckWithOptions(binPath, CheckOptions{Archives: &ArchiveOptions{}})
+
+		if assert.Len(t, matches, 3) {
+			assert.Equal(t, binPath, matches[0].Meta.FilePath)
+			assert.Equal(t, binPath+"!readme.txt", matches[1].Meta.FilePath)
+			assert.Equal(t, binPath+"!inner/bin.exe", matches[2].Meta.FilePath)
+			assert.Equal(t, matchOffsets{4}, matches[2].Offsets["upx"])
+		}
+	})
+
+	t.Run("gzip members are named after the file", func(t *testing.T) {
+		assert.Equal(t, "bin.exe", gzipMemberName("dir/bin.exe.gz", ""))
+		assert.Equal(t, "bundle.tar", gzipMemberName("bundle.tgz", ""))
+		assert.Equal(t, "original", gzipMemberName("bundle.gz", "dir/original"))
+	})
+}
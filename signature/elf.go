@@ -0,0 +1,98 @@
+package signature
+
+import (
+	"bytes"
+	"debug/elf"
+	"slices"
+	"strings"
+)
+
+// An elfFile is the ELF file parsed from the scanned data, with the symbols
+// and libraries it imports.
+type elfFile struct {
+	file      *elf.File
+	imports   []string
+	libraries []string
+}
+
+// parseELF parses the data as an ELF file, returning nil if it isn't one.
+func parseELF(data []byte) (parsed any) {
+	// Malformed files shouldn't stop the scan, they're just not ELF files.
+	defer func() {
+		if recover() != nil {
+			parsed = (*elfFile)(nil)
+		}
+	}()
+
+	file, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return (*elfFile)(nil)
+	}
+
+	parsedFile := &elfFile{file: file}
+
+	// Static binaries have no dynamic section, so they import nothing.
+	symbols, _ := file.ImportedSymbols()
+	for _, symbol := range symbols {
+		parsedFile.imports = append(parsedFile.imports, symbol.Name)
+	}
+	parsedFile.libraries, _ = file.ImportedLibraries()
+
+	return parsedFile
+}
+
+// elfValue returns the file value of an ELF file, undefined for files that
+// aren't ELF files.
+func elfValue(kind valueKind, args []valueKind, get func(f *elfFile, args []valueArg) (any, bool)) fileValue {
+	return fileValue{
+		kind: kind,
+		args: args,
+		get: func(f *scannedFile, args []valueArg) (any, bool) {
+			parsed := f.module("elf", parseELF).(*elfFile)
+			if parsed == nil {
+				return nil, false
+			}
+			return get(parsed, args)
+		},
+	}
+}
+
+// elfName returns the name of an ELF constant without its prefix, in lowercase
+// (e.g. "x86_64" for EM_X86_64).
+func elfName(name, prefix string) string {
+	return strings.ToLower(strings.TrimPrefix(name, prefix))
+}
+
+func elfValues() map[string]fileValue {
+	return map[string]fileValue{
+		"elf.type": elfValue(kindString, nil, func(f *elfFile, _ []valueArg) (any, bool) {
+			return elfName(f.file.Type.String(), "ET_"), true
+		}),
+		"elf.machine": elfValue(kindString, nil, func(f *elfFile, _ []valueArg) (any, bool) {
+			return elfName(f.file.Machine.String(), "EM_"), true
+		}),
+		"elf.entry_point": elfValue(kindInt, nil, func(f *elfFile, _ []valueArg) (any, bool) {
+			return int64(f.file.Entry), true
+		}),
+		"elf.number_of_sections": elfValue(kindInt, nil, func(f *elfFile, _ []valueArg) (any, bool) {
+			return int64(len(f.file.Sections)), true
+		}),
+		"elf.has_section": elfValue(kindBool, []valueKind{kindString}, func(f *elfFile, args []valueArg) (any, bool) {
+			return f.file.Section(args[0].Str) != nil, true
+		}),
+		// The size of a section the file doesn't have is undefined.
+		"elf.section_size": elfValue(kindInt, []valueKind{kindString}, func(f *elfFile, args []valueArg) (any, bool) {
+			section := f.file.Section(args[0].Str)
+			if section == nil {
+				return nil, false
+			}
+			return int64(section.Size), true
+		}),
+		"elf.imports": elfValue(kindBool, []valueKind{kindString}, func(f *elfFile, args []valueArg) (any, bool) {
+			return slices.Contains(f.imports, args[0].Str), true
+		}),
+		"elf.needs": elfValue(kindBool, []valueKind{kindString}, func(f *elfFile, args []valueArg) (any, bool) {
+			return slices.Contains(f.libraries, args[0].Str), true
+		}),
+	}
+}
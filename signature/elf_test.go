@@ -0,0 +1,75 @@
+package signature
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// makeTestELF returns a little endian, 64 bit x86 executable, with the entry
+// point at 0x401000 and an 8 byte .upx section, holding "UPX!", followed by the
+// section headers and a program header, which loads .upx at the entry point.
+func makeTestELF() []byte {
+	var (
+		buf      bytes.Buffer
+		shstrtab = "\x00.shstrtab\x00.upx\x00"
+		write    = func(data any) { binary.Write(&buf, binary.LittleEndian, data) }
+	)
+
+	write(elf.Header64{
+		Ident:     [elf.EI_NIDENT]byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT)},
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(elf.EM_X86_64),
+		Version:   uint32(elf.EV_CURRENT),
+		Entry:     0x401000,
+		Phoff:     288,
+		Shoff:     96,
+		Ehsize:    64,
+		Phentsize: 56,
+		Phnum:     1,
+		Shentsize: 64,
+		Shnum:     3,
+		Shstrndx:  1,
+	})
+	write([]byte(shstrtab))
+	write(make([]byte, 88-buf.Len()))
+	write([]byte("UPX!...."))
+
+	write(elf.Section64{})
+	write(elf.Section64{Name: 1, Type: uint32(elf.SHT_STRTAB), Off: 64, Size: uint64(len(shstrtab))})
+	write(elf.Section64{Name: 11, Type: uint32(elf.SHT_PROGBITS), Off: 88, Size: 8})
+	write(elf.Prog64{
+		Type:   uint32(elf.PT_LOAD),
+		Flags:  uint32(elf.PF_R | elf.PF_X),
+		Off:    88,
+		Vaddr:  0x401000,
+		Filesz: 8,
+		Memsz:  8,
+	})
+
+	return buf.Bytes()
+}
+
+func TestELFValues(t *testing.T) {
+	testValues(t, makeTestELF(), []valueTestCase{
+		{condition: `upx AND elf.machine == "x86_64" AND elf.has_section(".upx")`, want: true},
+		{condition: `elf.type == "exec"`, want: true},
+		{condition: `elf.entry_point == 0x401000`, want: true},
+		{condition: `elf.number_of_sections > 2`, want: true},
+		{condition: `elf.section_size(".upx") == 8`, want: true},
+		{condition: `elf.section_size(".text") >= 0`, want: false},
+		{condition: `elf.has_section(".text")`, want: false},
+		{condition: `elf.imports("printf") OR elf.needs("libc.so.6")`, want: false},
+		{condition: `elf.machine != "aarch64"`, want: true},
+		{condition: `upx AND NOT elf.has_section(".upx")`, want: false, wantOther: true},
+	})
+
+	t.Run("malformed ELF files aren't ELF files", func(t *testing.T) {
+		data := makeTestELF()[:80]
+
+		assert.Nil(t, parseELF(data).(*elfFile))
+	})
+}
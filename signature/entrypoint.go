@@ -0,0 +1,136 @@
+package signature
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"encoding/binary"
+)
+
+// Thread state flavors of LC_UNIXTHREAD load commands whose instruction pointer
+// can be read.
+const (
+	machoThreadStateX86   = 1
+	machoThreadStateX8664 = 4
+	machoThreadStateArm64 = 6
+)
+
+func entryPointValues() map[string]fileValue {
+	return map[string]fileValue{
+		// The entry point of files that aren't executables is undefined.
+		"entrypoint": {
+			kind: kindInt,
+			get: func(f *scannedFile, _ []valueArg) (any, bool) {
+				offset, ok := entryPointOffset(f)
+				return offset, ok
+			},
+		},
+	}
+}
+
+// entryPointOffset returns the offset, in the file, of the entry point of the
+// ELF, PE or Mach-O executable. It returns false if the file isn't one, or its
+// entry point isn't in the file's data.
+func entryPointOffset(f *scannedFile) (int64, bool) {
+	if parsed := f.module("pe", parsePE).(*peFile); parsed != nil {
+		// Object files have no entry point, and DLLs may have none.
+		if !parsed.optional || parsed.entryPoint == 0 {
+			return 0, false
+		}
+		for _, section := range parsed.sections {
+			if rva := parsed.entryPoint; rva >= section.VirtualAddress && rva-section.VirtualAddress < uint32(len(section.data)) {
+				return int64(section.Offset) + int64(rva-section.VirtualAddress), true
+			}
+		}
+		return 0, false
+	}
+
+	if parsed := f.module("elf", parseELF).(*elfFile); parsed != nil {
+		file := parsed.file
+		if file.Type != elf.ET_EXEC && file.Type != elf.ET_DYN || file.Entry == 0 {
+			return 0, false
+		}
+		return elfVirtualOffset(file, file.Entry)
+	}
+
+	if parsed := f.module("macho", parseMachO).(*machoFile); parsed != nil && parsed.file != nil {
+		return parsed.entryPointOffset()
+	}
+
+	return 0, false
+}
+
+// elfVirtualOffset returns the offset, in the file, of the virtual address,
+// which has to be in the file data of a loadable segment.
+func elfVirtualOffset(file *elf.File, addr uint64) (int64, bool) {
+	for _, prog := range file.Progs {
+		if prog.Type == elf.PT_LOAD && addr >= prog.Vaddr && addr-prog.Vaddr < prog.Filesz {
+			return int64(prog.Off + addr - prog.Vaddr), true
+		}
+	}
+
+	return 0, false
+}
+
+// entryPointOffset returns the offset, in the file, of the entry point set by
+// the LC_MAIN load command or, in older executables, the instruction pointer
+// of the LC_UNIXTHREAD load command.
+func (m *machoFile) entryPointOffset() (int64, bool) {
+	for _, load := range m.file.Loads {
+		raw := load.Raw()
+		if len(raw) < 16 {
+			continue
+		}
+
+		switch m.file.ByteOrder.Uint32(raw) {
+		case machoLoadCommands["main"]:
+			// The entry point is relative to the start of the __TEXT segment.
+			offset := m.file.ByteOrder.Uint64(raw[8:])
+			if text := m.file.Segment("__TEXT"); text != nil {
+				offset += text.Offset
+			}
+			return int64(offset), true
+
+		case machoLoadCommands["unixthread"]:
+			if addr, ok := machoThreadPC(raw, m.file.ByteOrder); ok {
+				return m.virtualOffset(addr)
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// machoThreadPC returns the instruction pointer of the thread state in the raw
+// LC_UNIXTHREAD load command, if its flavor is known.
+func machoThreadPC(raw []byte, order binary.ByteOrder) (uint64, bool) {
+	var (
+		flavor = order.Uint32(raw[8:])
+		state  = raw[16:]
+	)
+
+	switch {
+	case flavor == machoThreadStateX86 && len(state) >= 11*4:
+		// eax, ebx, ecx, edx, edi, esi, ebp, esp, ss, eflags and eip.
+		return uint64(order.Uint32(state[10*4:])), true
+	case flavor == machoThreadStateX8664 && len(state) >= 17*8:
+		// rax to r15, and rip.
+		return order.Uint64(state[16*8:]), true
+	case flavor == machoThreadStateArm64 && len(state) >= 33*8:
+		// x0 to x28, fp, lr, sp and pc.
+		return order.Uint64(state[32*8:]), true
+	}
+
+	return 0, false
+}
+
+// virtualOffset returns the offset, in the file, of the virtual address, which
+// has to be in the file data of a segment.
+func (m *machoFile) virtualOffset(addr uint64) (int64, bool) {
+	for _, load := range m.file.Loads {
+		if segment, ok := load.(*macho.Segment); ok && addr >= segment.Addr && addr-segment.Addr < segment.Filesz {
+			return int64(segment.Offset + addr - segment.Addr), true
+		}
+	}
+
+	return 0, false
+}
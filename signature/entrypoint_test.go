@@ -0,0 +1,83 @@
+package signature
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryPoint(t *testing.T) {
+	var (
+		patterns = map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}
+
+		// The last load command, LC_UUID, is replaced by an LC_MAIN of the same
+		// size, whose entry point is the __text section.
+		machoMain = makeTestMachO(macho.CpuAmd64)
+
+		elfObject = makeTestELF()
+		data      = []byte("....UPX!....")
+	)
+
+	binary.LittleEndian.PutUint32(machoMain[240:], machoLoadCommands["main"])
+	binary.LittleEndian.PutUint64(machoMain[248:], 0x200)
+	binary.LittleEndian.PutUint16(elfObject[16:], uint16(elf.ET_REL))
+
+	for _, tCase := range []struct {
+		name      string
+		data      []byte
+		condition string
+		want      bool
+	}{
+		{name: "PE", data: makeTestPE(), condition: "upx at entrypoint AND entrypoint == 0x200", want: true},
+		{name: "ELF", data: makeTestELF(), condition: "upx at entrypoint AND entrypoint == 88", want: true},
+		{name: "ELF object", data: elfObject, condition: "entrypoint >= 0", want: false},
+		{name: "Mach-O", data: machoMain, condition: "upx at entrypoint AND entrypoint == 0x200", want: true},
+		{name: "Mach-O without entry point", data: makeTestMachO(macho.CpuAmd64), condition: "upx at entrypoint", want: false},
+		{name: "other files", data: data, condition: "upx at entrypoint", want: false},
+		{name: "other files", data: data, condition: "entrypoint >= 0 OR entrypoint < 0", want: false},
+		{name: "other files", data: data, condition: "upx at 4", want: true},
+		{name: "other files", data: data, condition: "upx at 0x5", want: false},
+		{name: "PE", data: makeTestPE(), condition: "upx at pe.section_size(\".text\")", want: true},
+	} {
+		t.Run(
+			fmt.Sprintf("'%s' evaluates to %t for %s", tCase.condition, tCase.want, tCase.name),
+			func(t *testing.T) {
+				sig, err := Make("entry", "", patterns, tCase.condition)
+				if err != nil {
+					t.Fatalf("Want no error, got %s", err)
+				}
+
+				assert.Equal(t, tCase.want, sig.CheckMatch(tCase.data).IsMatch)
+			})
+	}
+
+	for _, condition := range []string{
+		"other at entrypoint",
+		"entrypoint at 0",
+		`upx at "main"`,
+		"upx at pe.machine",
+		"upx at upx",
+		"upx at entrypoint()",
+	} {
+		t.Run(
+			fmt.Sprintf("'%s' is an invalid comparison", condition),
+			func(t *testing.T) {
+				_, err := Make("entry", "", patterns, condition)
+
+				if assert.NotNil(t, err) {
+					assert.Equal(t, ErrSigInvalidComparison, err.(ErrSignature).reason)
+				}
+			})
+	}
+
+	t.Run("patterns checked at an offset are used", func(t *testing.T) {
+		sig, err := Make("entry", "", patterns, "upx at entrypoint")
+
+		assert.Nil(t, err)
+		assert.Empty(t, sig.Warnings())
+	})
+}
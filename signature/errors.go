@@ -5,10 +5,14 @@ import "fmt"
 type ErrSignatureReason string
 
 const (
-	ErrSigEmptyName      ErrSignatureReason = "the name can't be empty"
-	ErrSigEmptyPatterns  ErrSignatureReason = "the patterns map can't be empty"
-	ErrSigWrongCondition ErrSignatureReason = "the condition is either empty or invalid"
-	ErrSigMissingPattern ErrSignatureReason = "missing pattern for condition"
+	ErrSigEmptyName         ErrSignatureReason = "the name can't be empty"
+	ErrSigEmptyPatterns     ErrSignatureReason = "the patterns map can't be empty"
+	ErrSigWrongCondition    ErrSignatureReason = "the condition is either empty or invalid"
+	ErrSigMissingPattern    ErrSignatureReason = "missing pattern for condition"
+	ErrSigInvalidComparison ErrSignatureReason = "invalid comparison in the condition"
+	ErrSigUnknownRef        ErrSignatureReason = "the referenced signature doesn't exist"
+	ErrSigAmbiguousRef      ErrSignatureReason = "more than one signature has the referenced name"
+	ErrSigRefCycle          ErrSignatureReason = "signatures reference each other in a cycle"
 )
 
 // An ErrSignature is an error originating from an ill-formed signature.
@@ -24,3 +28,8 @@ func (e ErrSignature) Error() string {
 func (e ErrSignature) Unwrap() error {
 	return e.cause
 }
+
+// Reason returns why the signature is ill-formed.
+func (e ErrSignature) Reason() ErrSignatureReason {
+	return e.reason
+}
@@ -0,0 +1,127 @@
+package signature
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// A Severity is how serious a signature match is considered, as set in the
+// "severity" key of the signature's meta.
+type Severity int
+
+const (
+	SeverityNone Severity = iota
+	SeverityInfo
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = map[string]Severity{
+	"info":     SeverityInfo,
+	"low":      SeverityLow,
+	"medium":   SeverityMedium,
+	"high":     SeverityHigh,
+	"critical": SeverityCritical,
+}
+
+// ParseSeverity returns the Severity with the given (case insensitive) name,
+// or an error if there's none.
+func ParseSeverity(name string) (Severity, error) {
+	severity, ok := severityNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return SeverityNone, fmt.Errorf(
+			"unknown severity '%s', should be one of: info, low, medium, high, critical", name,
+		)
+	}
+
+	return severity, nil
+}
+
+// A Filter selects signatures by their tags and severity.
+// The zero value selects every signature.
+type Filter struct {
+	// IncludeTags are the tags a signature must have at least one of.
+	// When empty, signatures aren't selected by their tags.
+	IncludeTags []string
+	// ExcludeTags are the tags a signature must have none of.
+	ExcludeTags []string
+	// MinSeverity is the minimum severity of the selected signatures.
+	// When set, signatures without a known severity aren't selected.
+	MinSeverity Severity
+}
+
+// ParseTagsFilter parses a comma separated list of tags, where tags prefixed
+// with a "-" are excluded (e.g. "packer,-test"), into a Filter.
+func ParseTagsFilter(tags string) Filter {
+	var filter Filter
+
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if excluded, ok := strings.CutPrefix(tag, "-"); ok {
+			if excluded != "" {
+				filter.ExcludeTags = append(filter.ExcludeTags, excluded)
+			}
+		} else if tag != "" {
+			filter.IncludeTags = append(filter.IncludeTags, tag)
+		}
+	}
+
+	return filter
+}
+
+// Selects returns true if the signature passes the filter.
+func (f Filter) Selects(sig *Signature) bool {
+	for _, tag := range f.ExcludeTags {
+		if sig.HasTag(tag) {
+			return false
+		}
+	}
+
+	if len(f.IncludeTags) > 0 && !slices.ContainsFunc(f.IncludeTags, sig.HasTag) {
+		return false
+	}
+
+	return f.MinSeverity == SeverityNone || sig.Severity() >= f.MinSeverity
+}
+
+// Filter returns the signatures that pass the filter, in the same order.
+//
+// The signatures referenced by the selected ones are kept too, so they can be
+// evaluated, but they're made private unless selected themselves, so their
+// matches aren't reported.
+func (s Signatures) Filter(filter Filter) Signatures {
+	var (
+		selected = make([]bool, len(s))
+		needed   = make(map[string]bool)
+		filtered Signatures
+	)
+
+	for i := range s {
+		selected[i] = filter.Selects(&s[i])
+	}
+
+	// Signatures only reference signatures that come before them (see Link), so
+	// traversing them backwards finds all the transitive references.
+	for i := len(s) - 1; i >= 0; i-- {
+		if selected[i] || needed[s[i].Name] {
+			for _, ref := range s[i].Refs {
+				needed[ref] = true
+			}
+		}
+	}
+
+	for i, sig := range s {
+		if selected[i] {
+			filtered = append(filtered, sig)
+		} else if needed[sig.Name] {
+			sig.Private = true
+			filtered = append(filtered, sig)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,56 @@
+package signature
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	sigs := Signatures{
+		{Name: "upx", Tags: []string{"packer"}, Meta: map[string]string{"severity": "medium"}},
+		{Name: "upx_test", Tags: []string{"packer", "test"}, Meta: map[string]string{"severity": "high"}},
+		{Name: "dropper", Tags: []string{"dropper"}, Meta: map[string]string{"severity": "Critical"}},
+		{Name: "untagged"},
+	}
+
+	names := func(sigs Signatures) []string {
+		var names []string
+		for _, sig := range sigs {
+			names = append(names, sig.Name)
+		}
+		return names
+	}
+
+	t.Run("zero filter selects every signature", func(t *testing.T) {
+		assert.Equal(t, sigs, sigs.Filter(Filter{}))
+	})
+
+	t.Run("include and exclude tags", func(t *testing.T) {
+		filter := ParseTagsFilter("packer, -test")
+
+		assert.Equal(t, []string{"packer"}, filter.IncludeTags)
+		assert.Equal(t, []string{"test"}, filter.ExcludeTags)
+		assert.Equal(t, []string{"upx"}, names(sigs.Filter(filter)))
+	})
+
+	t.Run("only exclude tags", func(t *testing.T) {
+		filter := ParseTagsFilter("-test")
+
+		assert.Equal(t, []string{"upx", "dropper", "untagged"}, names(sigs.Filter(filter)))
+	})
+
+	t.Run("minimum severity", func(t *testing.T) {
+		severity, err := ParseSeverity("high")
+		assert.Nil(t, err)
+
+		filter := Filter{MinSeverity: severity}
+		assert.Equal(t, []string{"upx_test", "dropper"}, names(sigs.Filter(filter)))
+	})
+
+	t.Run("unknown severity", func(t *testing.T) {
+		_, err := ParseSeverity("urgent")
+
+		assert.NotNil(t, err)
+	})
+}
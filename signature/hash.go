@@ -0,0 +1,35 @@
+package signature
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Hashes are the digests of a file, as lowercase hexadecimal strings.
+// They're empty if they weren't computed.
+type Hashes struct {
+	MD5    string
+	SHA1   string
+	SHA256 string
+}
+
+// IsZero returns true if the hashes weren't computed.
+func (h Hashes) IsZero() bool {
+	return h == Hashes{}
+}
+
+func hashData(data []byte) Hashes {
+	var (
+		md5Sum    = md5.Sum(data)
+		sha1Sum   = sha1.Sum(data)
+		sha256Sum = sha256.Sum256(data)
+	)
+
+	return Hashes{
+		MD5:    hex.EncodeToString(md5Sum[:]),
+		SHA1:   hex.EncodeToString(sha1Sum[:]),
+		SHA256: hex.EncodeToString(sha256Sum[:]),
+	}
+}
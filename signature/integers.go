@@ -0,0 +1,50 @@
+package signature
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// integerValues are the unsigned integers read from the file data at an
+// offset, in little endian or, with the "be" suffix, big endian byte order
+// (e.g. uint16(0) == 0x5a4d, uint32be(@a[1]) > 0x100).
+//
+// Integers of 64 bits are read as signed integers, as are the literals they're
+// compared with, so those with the highest bit set are negative.
+func integerValues() map[string]fileValue {
+	values := make(map[string]fileValue)
+	for _, size := range []int{1, 2, 4, 8} {
+		name := fmt.Sprintf("uint%d", size*8)
+		values[name] = integerValue(size, binary.LittleEndian)
+		values[name+"be"] = integerValue(size, binary.BigEndian)
+	}
+
+	return values
+}
+
+// integerValue returns the file value of the unsigned integer of the given
+// size, in bytes, at the offset. Integers that don't fit in the data, before or
+// after it, are undefined.
+func integerValue(size int, order binary.ByteOrder) fileValue {
+	return fileValue{
+		kind: kindInt,
+		args: []valueKind{kindOffset},
+		get: func(f *scannedFile, args []valueArg) (any, bool) {
+			offset := args[0].Int
+			if offset < 0 || offset > int64(len(f.data)-size) {
+				return nil, false
+			}
+
+			data := f.data[offset:]
+			switch size {
+			case 1:
+				return int64(data[0]), true
+			case 2:
+				return int64(order.Uint16(data)), true
+			case 4:
+				return int64(order.Uint32(data)), true
+			}
+			return int64(order.Uint64(data)), true
+		},
+	}
+}
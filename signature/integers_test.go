@@ -0,0 +1,74 @@
+package signature
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegerValues(t *testing.T) {
+	var (
+		patterns = map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}
+		pe       = makeTestPE()
+	)
+
+	for _, tCase := range []struct {
+		condition string
+		want      bool
+	}{
+		{condition: "uint16(0) == 0x5a4d AND uint16be(0) == 0x4d5a", want: true},
+		{condition: "uint8(0x3c) == 0x40 AND uint8be(0x3c) == 0x40", want: true},
+		{condition: "uint32(uint32(0x3c)) == 0x4550", want: true},
+		{condition: "uint32(@upx[1]) == 0x21585055 AND uint32be(@upx) == 0x55505821", want: true},
+		{condition: "uint64(0x40) == 0x2866400004550", want: true},
+		{condition: "uint64be(0x3c) == 0x4000000050450000", want: true},
+		{condition: "@upx[1] == 0x200", want: true},
+		{condition: "upx at @upx[1]", want: true},
+		{condition: "uint32(entrypoint) == 0x21585055", want: true},
+		{condition: "uint8(@upx[2]) >= 0", want: false},
+		{condition: fmt.Sprintf("uint32(%d) >= 0", len(pe)-2), want: false},
+		{condition: fmt.Sprintf("uint16(%d) >= 0", len(pe)-2), want: true},
+	} {
+		t.Run(
+			fmt.Sprintf("'%s' evaluates to %t", tCase.condition, tCase.want),
+			func(t *testing.T) {
+				sig, err := Make("ints", "", patterns, tCase.condition)
+				if err != nil {
+					t.Fatalf("Want no error, got %s", err)
+				}
+
+				assert.Equal(t, tCase.want, sig.CheckMatch(pe).IsMatch)
+			})
+	}
+
+	for _, condition := range []string{
+		`uint16("0") == 1`,
+		"uint16(pe.machine) == 1",
+		"uint16(@other[1]) == 1",
+		"uint16(upx) == 1",
+		"uint16() == 1",
+		"uint16 == 1",
+		"uint16(0) == \"MZ\"",
+		`@upx[1] == "0"`,
+		"@other == 0",
+		"uint128(0) == 1",
+	} {
+		t.Run(
+			fmt.Sprintf("'%s' is an invalid comparison", condition),
+			func(t *testing.T) {
+				_, err := Make("ints", "", patterns, condition)
+
+				if assert.NotNil(t, err) {
+					assert.Equal(t, ErrSigInvalidComparison, err.(ErrSignature).reason)
+				}
+			})
+	}
+
+	t.Run("patterns used by their offset are used", func(t *testing.T) {
+		sig, err := Make("ints", "", patterns, "uint32(@upx[1]) == 0x21585055")
+
+		assert.Nil(t, err)
+		assert.Empty(t, sig.Warnings())
+	})
+}
@@ -0,0 +1,269 @@
+package io
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/angelsolaorbaiceta/binmat/signature"
+)
+
+// BundleVersion is the version of the bundle format written by WriteBundle.
+// It has to be increased every time the format changes, so that bundles
+// written by older versions of binmat are rejected instead of misread.
+const BundleVersion uint16 = 2
+
+// bundleMagic are the bytes every bundle starts with.
+var bundleMagic = []byte("BINMAT\x00B")
+
+// A bundle file is laid out as follows:
+//
+//	magic     [8]byte   "BINMAT\x00B"
+//	version   uint16    big endian, BundleVersion
+//	length    uint64    big endian, length of the payload in bytes
+//	checksum  [32]byte  SHA-256 of the payload
+//	payload   []byte    gob encoded bundleContents
+const bundleHeaderLen = 8 + 2 + 8 + sha256.Size
+
+// bundleContents is the payload of a bundle.
+type bundleContents struct {
+	Signatures []bundleSignature
+}
+
+// A bundleSignature is the serialized form of a validated domain signature.
+// Patterns are stored already parsed, as their bytes and mask.
+type bundleSignature struct {
+	Name        string
+	Description string
+	Patterns    []bundlePattern
+	Condition   string
+	Tags        []string
+	Meta        []bundleMeta
+	Source      string
+	Refs        []string
+	Private     bool
+}
+
+type bundlePattern struct {
+	Name    string
+	Bytes   []byte
+	Mask    []byte
+	Section string
+	Start   int
+	End     int
+	Overlay bool
+}
+
+// Meta is stored as a sorted list, as gob encodes maps in random order.
+type bundleMeta struct {
+	Key   string
+	Value string
+}
+
+// WriteBundle serializes the signatures into a versioned binary bundle that
+// ReadBundle can load without parsing the original signature files.
+func WriteBundle(w io.Writer, sigs signature.Signatures) error {
+	var (
+		contents = bundleContents{Signatures: make([]bundleSignature, len(sigs))}
+		payload  bytes.Buffer
+	)
+
+	for i, sig := range sigs {
+		contents.Signatures[i] = signatureToBundle(sig)
+	}
+
+	if err := gob.NewEncoder(&payload).Encode(contents); err != nil {
+		return err
+	}
+
+	var (
+		header   = make([]byte, 0, bundleHeaderLen)
+		checksum = sha256.Sum256(payload.Bytes())
+	)
+
+	header = append(header, bundleMagic...)
+	header = binary.BigEndian.AppendUint16(header, BundleVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(payload.Len()))
+	header = append(header, checksum[:]...)
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	_, err := w.Write(payload.Bytes())
+	return err
+}
+
+// ReadBundle loads the signatures from a bundle written by WriteBundle.
+//
+// Patterns are loaded already parsed, and conditions are parsed again, which
+// is fast, but they aren't analyzed for warnings, which only Signature.Warnings
+// does, when validating.
+//
+// The returned error is an ErrBundle if the data isn't a bundle, was written
+// with a different format version, or its checksum doesn't match.
+func ReadBundle(r io.Reader) (signature.Signatures, error) {
+	header := make([]byte, bundleHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrBundle{reason: ErrBundleNotABundle, cause: err}
+	}
+
+	if !bytes.Equal(header[:len(bundleMagic)], bundleMagic) {
+		return nil, ErrBundle{reason: ErrBundleNotABundle}
+	}
+
+	var (
+		rest     = header[len(bundleMagic):]
+		version  = binary.BigEndian.Uint16(rest[0:2])
+		length   = binary.BigEndian.Uint64(rest[2:10])
+		checksum = rest[10:]
+	)
+
+	if version != BundleVersion {
+		return nil, ErrBundle{reason: ErrBundleVersion, version: version}
+	}
+
+	var payload bytes.Buffer
+	if n, err := io.CopyN(&payload, r, int64(length)); err != nil || uint64(n) != length {
+		return nil, ErrBundle{reason: ErrBundleCorrupted, cause: err}
+	}
+
+	if sum := sha256.Sum256(payload.Bytes()); !bytes.Equal(sum[:], checksum) {
+		return nil, ErrBundle{reason: ErrBundleChecksum}
+	}
+
+	var contents bundleContents
+	if err := gob.NewDecoder(&payload).Decode(&contents); err != nil {
+		return nil, ErrBundle{reason: ErrBundleCorrupted, cause: err}
+	}
+
+	sigs := make([]signature.Signature, len(contents.Signatures))
+	for i, bundleSig := range contents.Signatures {
+		sig, err := bundleToSignature(bundleSig)
+		if err != nil {
+			return nil, ErrBundle{reason: ErrBundleCorrupted, cause: err}
+		}
+
+		sigs[i] = sig
+	}
+
+	return signature.Link(sigs)
+}
+
+// LoadBundle loads the signatures from the bundle file at the given path.
+func LoadBundle(path string) (signature.Signatures, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadBundle(file)
+}
+
+// IsBundleFile returns true if the file at the given path starts like a bundle.
+// Any error reading the file yields false.
+func IsBundleFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(bundleMagic))
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return false
+	}
+
+	return bytes.Equal(magic, bundleMagic)
+}
+
+func signatureToBundle(sig signature.Signature) bundleSignature {
+	names := make([]string, 0, len(sig.Patterns))
+	for name := range sig.Patterns {
+		names = append(names, name)
+	}
+	// Sorted, so that the same signatures always yield the same bundle.
+	sort.Strings(names)
+
+	patterns := make([]bundlePattern, len(names))
+	for i, name := range names {
+		var (
+			pattern = sig.Patterns[name]
+			scope   = pattern.Scope()
+		)
+		patterns[i] = bundlePattern{
+			Name:    name,
+			Bytes:   pattern.Bytes(),
+			Mask:    pattern.Mask(),
+			Section: scope.Section,
+			Start:   scope.Start,
+			End:     scope.End,
+			Overlay: scope.Overlay,
+		}
+	}
+
+	var meta []bundleMeta
+	for key, value := range sig.Meta {
+		meta = append(meta, bundleMeta{Key: key, Value: value})
+	}
+	sort.Slice(meta, func(i, j int) bool { return meta[i].Key < meta[j].Key })
+
+	return bundleSignature{
+		Name:        sig.Name,
+		Description: sig.Description,
+		Patterns:    patterns,
+		Condition:   sig.Condition,
+		Tags:        sig.Tags,
+		Meta:        meta,
+		Source:      sig.Source,
+		Refs:        sig.Refs,
+		Private:     sig.Private,
+	}
+}
+
+func bundleToSignature(bundleSig bundleSignature) (signature.Signature, error) {
+	patterns := make(map[string]*signature.SignaturePattern, len(bundleSig.Patterns))
+	for _, pattern := range bundleSig.Patterns {
+		if len(pattern.Bytes) == 0 || len(pattern.Bytes) != len(pattern.Mask) {
+			return signature.Signature{}, fmt.Errorf(
+				"pattern '%s' in '%s' has a wrong length", pattern.Name, bundleSig.Name,
+			)
+		}
+
+		patterns[pattern.Name] = signature.MakePatternWithMask(pattern.Bytes, pattern.Mask)
+		scope := signature.Scope{
+			Section: pattern.Section,
+			Start:   pattern.Start,
+			End:     pattern.End,
+			Overlay: pattern.Overlay,
+		}
+		if !scope.IsZero() {
+			patterns[pattern.Name] = patterns[pattern.Name].WithScope(scope)
+		}
+	}
+
+	sig, err := signature.MakeWithRefs(
+		bundleSig.Name,
+		bundleSig.Description,
+		patterns,
+		bundleSig.Condition,
+		bundleSig.Refs,
+	)
+	sig.Tags = bundleSig.Tags
+	if len(bundleSig.Meta) > 0 {
+		sig.Meta = make(map[string]string, len(bundleSig.Meta))
+		for _, meta := range bundleSig.Meta {
+			sig.Meta[meta.Key] = meta.Value
+		}
+	}
+	sig.Source = bundleSig.Source
+	sig.Private = bundleSig.Private
+
+	return sig, err
+}
@@ -0,0 +1,137 @@
+package io
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/angelsolaorbaiceta/binmat/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundle(t *testing.T) {
+	dir := writeSigFiles(t, map[string]string{
+		"a.yaml": `name: dropper
+tags: [dropper]
+meta:
+  author: jdoe
+  severity: high
+  reference: https://example.com
+  date: 2024-01-31
+patterns:
+  a: '{ 01 ?? 03 }'
+  b: a string
+  c:
+    pattern: MZ
+    scope: {range: [0, 64]}
+condition: upx_packed AND (a OR b OR c)
+`,
+		"b.yaml": `name: upx_packed
+private: true
+patterns:
+  upx: UPX!
+condition: upx
+`,
+	})
+	sigs, err := LoadSignatures(dir)
+	if err != nil {
+		t.Fatalf("Can't load signatures: %s", err)
+	}
+
+	writeBundle := func() []byte {
+		var buf bytes.Buffer
+		if err := WriteBundle(&buf, sigs); err != nil {
+			t.Fatalf("Can't write bundle: %s", err)
+		}
+		return buf.Bytes()
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		got, err := ReadBundle(bytes.NewReader(writeBundle()))
+
+		assert.Nil(t, err)
+		if assert.Len(t, got, 2) {
+			for i := range sigs {
+				assert.Equal(t, sigs[i].Name, got[i].Name)
+				assert.Equal(t, sigs[i].Condition, got[i].Condition)
+				assert.Equal(t, sigs[i].Patterns, got[i].Patterns)
+				assert.Equal(t, sigs[i].Tags, got[i].Tags)
+				assert.Equal(t, sigs[i].Meta, got[i].Meta)
+				assert.Equal(t, sigs[i].Source, got[i].Source)
+				assert.Equal(t, sigs[i].Refs, got[i].Refs)
+				assert.Equal(t, sigs[i].Private, got[i].Private)
+			}
+		}
+	})
+
+	t.Run("the same signatures always yield the same bundle", func(t *testing.T) {
+		assert.Equal(t, writeBundle(), writeBundle())
+	})
+
+	t.Run("load from file", func(t *testing.T) {
+		bundlePath := filepath.Join(t.TempDir(), "rules.bmat")
+		os.WriteFile(bundlePath, writeBundle(), 0o644)
+
+		assert.True(t, IsBundleFile(bundlePath))
+		assert.False(t, IsBundleFile(filepath.Join(dir, "a.yaml")))
+
+		got, err := LoadBundle(bundlePath)
+		assert.Nil(t, err)
+		assert.Len(t, got, 2)
+	})
+
+	for _, tCase := range []struct {
+		name   string
+		modify func([]byte) []byte
+		want   ErrBundleReason
+	}{
+		{
+			name:   "not a bundle",
+			modify: func(b []byte) []byte { return []byte("name: not a bundle") },
+			want:   ErrBundleNotABundle,
+		},
+		{
+			name:   "stale version",
+			modify: func(b []byte) []byte { b[9] = byte(BundleVersion + 1); return b },
+			want:   ErrBundleVersion,
+		},
+		{
+			name:   "corrupted payload",
+			modify: func(b []byte) []byte { b[len(b)-1] ^= 0xff; return b },
+			want:   ErrBundleChecksum,
+		},
+		{
+			name:   "truncated payload",
+			modify: func(b []byte) []byte { return b[:len(b)-10] },
+			want:   ErrBundleCorrupted,
+		},
+	} {
+		t.Run(tCase.name, func(t *testing.T) {
+			_, err := ReadBundle(bytes.NewReader(tCase.modify(writeBundle())))
+
+			var bundleErr ErrBundle
+			if assert.ErrorAs(t, err, &bundleErr) {
+				assert.Equal(t, tCase.want, bundleErr.Reason())
+			}
+		})
+	}
+
+	t.Run("checked matches are the same", func(t *testing.T) {
+		got, _ := ReadBundle(bytes.NewReader(writeBundle()))
+
+		binPath := filepath.Join(t.TempDir(), "bin")
+		os.WriteFile(binPath, []byte("xxUPX!xx\x01\x02\x03xx"), 0o644)
+
+		var results []signature.SigMatch
+		for _, s := range []signature.Signatures{sigs, got} {
+			matches, err := s.Check(binPath)
+			assert.Nil(t, err)
+			assert.Len(t, matches, 1)
+			results = append(results, matches...)
+		}
+
+		assert.True(t, results[0].IsMatch)
+		assert.True(t, results[1].IsMatch)
+	})
+}
@@ -0,0 +1,123 @@
+package io
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An ErrDocument is an error decoding one of the documents in a yaml file.
+type ErrDocument struct {
+	// Document is the 1-based index of the document in the file.
+	Document int
+	// Item is the 1-based index of the signature in the document, when the
+	// document is a list of signatures. Otherwise, it's zero.
+	Item  int
+	cause error
+}
+
+func (e ErrDocument) Error() string {
+	if e.Item > 0 {
+		return fmt.Sprintf("document %d, item %d: %s", e.Document, e.Item, e.cause)
+	}
+
+	return fmt.Sprintf("document %d: %s", e.Document, e.cause)
+}
+
+func (e ErrDocument) Unwrap() error {
+	return e.cause
+}
+
+// An ErrPatternValue is returned when the value of a pattern isn't a string.
+// YAML parses some unquoted values as other types, like "{ 74 fc }", which is a
+// mapping unless quoted.
+type ErrPatternValue struct {
+	Pattern string
+	// Kind is the kind of value found instead of a string (e.g. "map", "number").
+	Kind string
+	// Line and Column are the position of the value, if known. Otherwise, zero.
+	Line, Column int
+}
+
+func (e ErrPatternValue) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.message())
+	}
+
+	return e.message()
+}
+
+// message returns the error message without the position.
+func (e ErrPatternValue) message() string {
+	msg := fmt.Sprintf("pattern '%s' must be a string, got a %s", e.Pattern, e.Kind)
+	if e.Kind == "map" {
+		msg += " (quote byte patterns, as in '{ 74 fc }')"
+	}
+
+	return msg
+}
+
+// An ErrInclude is an error resolving one of the files included by a signature.
+type ErrInclude struct {
+	// File is the path to the file with the include.
+	File string
+	// Include is the included path, as written in the file.
+	Include string
+	cause   error
+}
+
+func (e ErrInclude) Error() string {
+	return fmt.Sprintf("%s: can't include '%s': %s", e.File, e.Include, e.cause)
+}
+
+func (e ErrInclude) Unwrap() error {
+	return e.cause
+}
+
+// An ErrIncludeCycle is returned when files include each other in a cycle.
+type ErrIncludeCycle struct {
+	// Files are the paths to the files in the cycle, in include order.
+	// The first and last files are the same.
+	Files []string
+}
+
+func (e ErrIncludeCycle) Error() string {
+	return fmt.Sprintf("include cycle: %s", strings.Join(e.Files, " -> "))
+}
+
+type ErrBundleReason string
+
+const (
+	ErrBundleNotABundle ErrBundleReason = "the data isn't a signature bundle"
+	ErrBundleVersion    ErrBundleReason = "the bundle was written with an unsupported format version"
+	ErrBundleChecksum   ErrBundleReason = "the bundle checksum doesn't match its contents"
+	ErrBundleCorrupted  ErrBundleReason = "the bundle contents are corrupted"
+)
+
+// An ErrBundle is an error reading a signature bundle.
+type ErrBundle struct {
+	reason  ErrBundleReason
+	version uint16
+	cause   error
+}
+
+func (e ErrBundle) Error() string {
+	msg := fmt.Sprintf("invalid bundle (%s)", e.reason)
+
+	if e.reason == ErrBundleVersion {
+		msg += fmt.Sprintf(". Got version %d, want %d: compile the bundle again", e.version, BundleVersion)
+	}
+	if e.cause != nil {
+		msg += fmt.Sprintf(". Cause: %s", e.cause)
+	}
+
+	return msg
+}
+
+func (e ErrBundle) Unwrap() error {
+	return e.cause
+}
+
+// Reason returns why the bundle can't be read.
+func (e ErrBundle) Reason() ErrBundleReason {
+	return e.reason
+}
@@ -0,0 +1,151 @@
+package io
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the file, at the root of a signatures directory,
+// that lists the files and directories that shouldn't be loaded.
+const IgnoreFileName = ".binmatignore"
+
+// sigFileExts are the extensions of the files holding signatures.
+var sigFileExts = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+}
+
+// findSigFiles returns the paths to all the signature files found in the given
+// roots. Roots can be files or directories:
+//   - Files are always included, regardless of their extension.
+//   - Directories are recursively explored, and the files with a signature file
+//     extension in them are included, unless ignored by the IgnoreFileName in
+//     the root directory.
+//
+// Each file is only included once, even if found from different roots.
+func findSigFiles(roots []string) ([]string, error) {
+	var (
+		files []string
+		seen  = make(map[string]bool)
+	)
+
+	addFile := func(filePath string) {
+		if !seen[filePath] {
+			seen[filePath] = true
+			files = append(files, filePath)
+		}
+	}
+
+	for _, root := range roots {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			addFile(root)
+			continue
+		}
+
+		ignore, err := readIgnoreFile(filepath.Join(root, IgnoreFileName))
+		if err != nil {
+			return nil, err
+		}
+
+		err = filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if filePath == root {
+				return nil
+			}
+
+			relPath, err := filepath.Rel(root, filePath)
+			if err != nil {
+				return err
+			}
+
+			if ignore.matches(filepath.ToSlash(relPath), entry.IsDir()) {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !entry.IsDir() && sigFileExts[filepath.Ext(entry.Name())] {
+				addFile(filePath)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// An ignoreList is a list of glob patterns, as read from an ignore file.
+type ignoreList []string
+
+// readIgnoreFile reads the glob patterns in the ignore file at the given path.
+// Empty lines and lines starting with "#" are skipped.
+// A missing ignore file yields an empty list.
+func readIgnoreFile(filePath string) (ignoreList, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var (
+		patterns ignoreList
+		scanner  = bufio.NewScanner(file)
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		patterns = append(patterns, line)
+	}
+
+	return patterns, scanner.Err()
+}
+
+// matches returns true if the slash separated path, relative to the root
+// directory, is ignored.
+//
+// Patterns are matched against the full relative path and against the base
+// name, so "*.draft.yaml" ignores drafts at any depth, whereas "family/*.yaml"
+// only those in "family". Patterns ending in "/" only match directories.
+func (l ignoreList) matches(relPath string, isDir bool) bool {
+	for _, pattern := range l {
+		if strings.HasSuffix(pattern, "/") {
+			if !isDir {
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+
+		if ok, _ := path.Match(pattern, relPath); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(relPath)); ok {
+			return true
+		}
+	}
+
+	return false
+}
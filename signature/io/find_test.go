@@ -0,0 +1,92 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSigFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"top.yaml",
+		"notes.txt",
+		"family/one.yml",
+		"family/two.draft.yaml",
+		"family/nested/three.yaml",
+		"archive/old.yaml",
+		"single/file.rule",
+	} {
+		filePath := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(filePath), 0o755)
+		if err := os.WriteFile(filePath, nil, 0o644); err != nil {
+			t.Fatalf("Can't write test file: %s", err)
+		}
+	}
+
+	ignore := "# drafts and old rules\n*.draft.yaml\n\narchive/\n"
+	if err := os.WriteFile(filepath.Join(dir, IgnoreFileName), []byte(ignore), 0o644); err != nil {
+		t.Fatalf("Can't write ignore file: %s", err)
+	}
+
+	t.Run("recursively finds yaml and yml files, honouring the ignore file", func(t *testing.T) {
+		files, err := findSigFiles([]string{dir})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "family/nested/three.yaml"),
+			filepath.Join(dir, "family/one.yml"),
+			filepath.Join(dir, "top.yaml"),
+		}, files)
+	})
+
+	t.Run("files are included regardless of their extension and only once", func(t *testing.T) {
+		var (
+			single     = filepath.Join(dir, "single/file.rule")
+			family     = filepath.Join(dir, "family")
+			files, err = findSigFiles([]string{single, family, filepath.Join(family, "one.yml")})
+		)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			single,
+			filepath.Join(dir, "family/nested/three.yaml"),
+			filepath.Join(dir, "family/one.yml"),
+			filepath.Join(dir, "family/two.draft.yaml"),
+		}, files)
+	})
+
+	t.Run("fails if a path doesn't exist", func(t *testing.T) {
+		_, err := findSigFiles([]string{filepath.Join(dir, "missing")})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestLoadSignatures(t *testing.T) {
+	dir := writeSigFiles(t, map[string]string{
+		"a.yaml": "name: a\npatterns:\n  a: '{ 01 02 03 }'\ncondition: a\n",
+		"b.yml":  "name: b\npatterns:\n  a: '{ 04 05 06 }'\ncondition: a\n",
+	})
+
+	t.Run("signatures record their source file", func(t *testing.T) {
+		sigs, err := LoadSignatures(dir)
+
+		assert.Nil(t, err)
+		if assert.Len(t, sigs, 2) {
+			assert.Equal(t, filepath.Join(dir, "a.yaml"), sigs[0].Source)
+			assert.Equal(t, filepath.Join(dir, "b.yml"), sigs[1].Source)
+		}
+	})
+
+	t.Run("errors mention the offending file", func(t *testing.T) {
+		bad := filepath.Join(t.TempDir(), "bad.yaml")
+		os.WriteFile(bad, []byte("name: bad\npatterns:\n  a: '{ 0 }'\ncondition: a\n"), 0o644)
+
+		_, err := LoadSignatures(dir, bad)
+
+		assert.ErrorContains(t, err, bad)
+	})
+}
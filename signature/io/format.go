@@ -0,0 +1,146 @@
+package io
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/angelsolaorbaiceta/binmat/bexpr"
+	"gopkg.in/yaml.v3"
+)
+
+// FindSignatureFiles returns the paths to the signature files found at the given
+// paths, the same way LoadSignatures finds them.
+func FindSignatureFiles(paths ...string) ([]string, error) {
+	return findSigFiles(paths)
+}
+
+// FormatYaml rewrites the signatures in a yaml file in canonical form:
+//   - Indentation of two spaces.
+//   - Byte patterns with lowercase hex bytes separated by a single space.
+//   - All pattern values single quoted, and the rest of the strings only quoted
+//     when required.
+//   - Conditions printed with single spaces between operations, and only the
+//     parentheses required by the precedence of the operations.
+//
+// Comments and the order of the fields are kept. Patterns and conditions that
+// can't be parsed are kept as they are, so that they're reported when loaded.
+func FormatYaml(src []byte) ([]byte, error) {
+	var (
+		decoder   = yaml.NewDecoder(bytes.NewReader(src))
+		formatted bytes.Buffer
+		encoder   = yaml.NewEncoder(&formatted)
+	)
+	encoder.SetIndent(2)
+
+	for docIdx := 1; ; docIdx++ {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrDocument{Document: docIdx, cause: err}
+		}
+
+		// Empty documents, like the one after a trailing "---", are dropped.
+		if isEmptyDocument(&doc) && !hasComments(&doc) {
+			continue
+		}
+
+		normalizeStrings(&doc)
+		if !isEmptyDocument(&doc) {
+			for _, root := range documentSigNodes(&doc) {
+				formatSignature(root)
+			}
+		}
+
+		if err := encoder.Encode(&doc); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return formatted.Bytes(), nil
+}
+
+// formatSignature formats the patterns and condition of the signature node.
+func formatSignature(root *yaml.Node) {
+	if patterns := valueNode(root, "patterns"); patterns != nil && patterns.Kind == yaml.MappingNode {
+		for i := 1; i < len(patterns.Content); i += 2 {
+			value := patterns.Content[i]
+			// Scoped patterns are a mapping with the pattern and its scope.
+			if value.Kind == yaml.MappingNode {
+				value = valueNode(value, "pattern")
+			}
+			if value == nil || value.Kind != yaml.ScalarNode || value.ShortTag() != "!!str" {
+				continue
+			}
+
+			value.Value = formatPattern(value.Value)
+			value.Style = yaml.SingleQuotedStyle
+		}
+	}
+
+	if condition := valueNode(root, "condition"); condition != nil && condition.Kind == yaml.ScalarNode {
+		if expr, err := bexpr.Parse(condition.Value); err == nil {
+			condition.Value = bexpr.DefaultPrinter.Print(expr)
+		}
+	}
+}
+
+// formatPattern returns the byte pattern with lowercase hex bytes separated by
+// a single space. Strings and malformed byte patterns are returned as they are.
+func formatPattern(pattern string) string {
+	if !bytePatternRe.MatchString(pattern) {
+		return pattern
+	}
+
+	fields := strings.Fields(strings.Trim(pattern, "{ }"))
+	for i, field := range fields {
+		if field == "??" {
+			continue
+		}
+		if _, err := strconv.ParseUint(field, 16, 8); err != nil || len(field) != 2 {
+			return pattern
+		}
+		fields[i] = strings.ToLower(field)
+	}
+
+	return "{ " + strings.Join(fields, " ") + " }"
+}
+
+// normalizeStrings removes the quotes of all the strings in the node, so that
+// they're only quoted when required. Multi-line strings keep their literal or
+// folded style.
+func normalizeStrings(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		if node.Style == yaml.SingleQuotedStyle || node.Style == yaml.DoubleQuotedStyle {
+			node.Style = 0
+		}
+		return
+	}
+
+	for _, child := range node.Content {
+		normalizeStrings(child)
+	}
+}
+
+// hasComments returns true if the node, or any of its children, has comments.
+func hasComments(node *yaml.Node) bool {
+	if node.HeadComment != "" || node.LineComment != "" || node.FootComment != "" {
+		return true
+	}
+
+	for _, child := range node.Content {
+		if hasComments(child) {
+			return true
+		}
+	}
+
+	return false
+}
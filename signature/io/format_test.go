@@ -0,0 +1,97 @@
+package io
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatYaml(t *testing.T) {
+	src := `# A packer signature
+name: "packed"
+description: 'UPX packed'
+tags: [packer, "123"]
+meta:
+    severity: "high"
+    version: "2"
+patterns:
+    a: "{ 74 FC  ff ?? }"   # the stub
+    b: some text
+    c: '{74fc}'
+condition: (a AND (b)) OR   c
+---
+- name: second
+  patterns:
+    a: '{ 01 02 03 }'
+  condition: a AND NOT
+---
+`
+	want := `# A packer signature
+name: packed
+description: UPX packed
+tags: [packer, "123"]
+meta:
+  severity: high
+  version: "2"
+patterns:
+  a: '{ 74 fc ff ?? }' # the stub
+  b: 'some text'
+  c: '{74fc}'
+condition: a AND b OR c
+---
+- name: second
+  patterns:
+    a: '{ 01 02 03 }'
+  condition: a AND NOT
+`
+
+	got, err := FormatYaml([]byte(src))
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, string(got))
+
+	t.Run("formatting is idempotent", func(t *testing.T) {
+		again, err := FormatYaml(got)
+
+		assert.Nil(t, err)
+		assert.Equal(t, string(got), string(again))
+	})
+
+	t.Run("scoped patterns are formatted", func(t *testing.T) {
+		src := "name: scoped\npatterns:\n    a:\n        pattern: \"{ 74 FC }\"\n        scope: {section: .text}\ncondition: a\n"
+		want := "name: scoped\npatterns:\n  a:\n    pattern: '{ 74 fc }'\n    scope: {section: .text}\ncondition: a\n"
+
+		got, err := FormatYaml([]byte(src))
+
+		assert.Nil(t, err)
+		assert.Equal(t, want, string(got))
+	})
+
+	t.Run("syntax errors report the failing document", func(t *testing.T) {
+		_, err := FormatYaml([]byte("name: first\n---\nname: [second\n"))
+
+		var docErr ErrDocument
+		if assert.ErrorAs(t, err, &docErr) {
+			assert.Equal(t, 2, docErr.Document)
+		}
+	})
+}
+
+func TestFormatPattern(t *testing.T) {
+	for _, tCase := range []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "{ 74 FC ff ?? }", want: "{ 74 fc ff ?? }"},
+		{pattern: "  {74   fc}  ", want: "{ 74 fc }"},
+		{pattern: "{ 7 4 }", want: "{ 7 4 }"},
+		{pattern: "some text", want: "some text"},
+	} {
+		t.Run(
+			fmt.Sprintf("format '%s' as '%s'", tCase.pattern, tCase.want),
+			func(t *testing.T) {
+				assert.Equal(t, tCase.want, formatPattern(tCase.pattern))
+			})
+	}
+}
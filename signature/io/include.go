@@ -0,0 +1,255 @@
+package io
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/angelsolaorbaiceta/binmat/bexpr"
+)
+
+// An includeResolver reads the files included by signatures and resolves the
+// patterns they share. Each file is read only once.
+//
+// A file shares the patterns of its library documents, together with the
+// patterns those libraries include themselves.
+type includeResolver struct {
+	// files are the signatures read from each file, by absolute path.
+	files map[string][]Signature
+	// shared are the patterns each file shares, by absolute path.
+	shared map[string]map[string]sharedPattern
+	// resolving are the files whose shared patterns are being resolved, in
+	// include order. Finding a file twice means there's an include cycle.
+	resolving []string
+}
+
+// A sharedPattern is a pattern definition and the file it comes from.
+type sharedPattern struct {
+	value string
+	// scope is the scope of the pattern, or nil if it doesn't have one.
+	scope  *PatternScope
+	source string
+}
+
+func newIncludeResolver() *includeResolver {
+	return &includeResolver{
+		files:  make(map[string][]Signature),
+		shared: make(map[string]map[string]sharedPattern),
+	}
+}
+
+// readFile reads the signatures in the file at the given path, or returns them
+// from the cache if the file was already read.
+func (r *includeResolver) readFile(filePath string) ([]Signature, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if sigs, ok := r.files[absPath]; ok {
+		return sigs, nil
+	}
+
+	sigs, err := readSigFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	r.files[absPath] = sigs
+	return sigs, nil
+}
+
+// resolve adds to the signature the patterns its condition uses that aren't
+// defined in the signature itself, but in the files it includes.
+// Patterns defined in the signature take precedence over the included ones.
+//
+// The signature is returned unchanged if its condition can't be parsed: the
+// error will be reported when mapping the signature to the domain.
+func (r *includeResolver) resolve(sig Signature) (Signature, error) {
+	if len(sig.Include) == 0 {
+		return sig, nil
+	}
+
+	shared, err := r.includedPatterns(sig.Source, sig.Include)
+	if err != nil {
+		return sig, err
+	}
+
+	expr, parseErr := bexpr.Parse(sig.Condition)
+	if parseErr != nil {
+		return sig, nil
+	}
+
+	patterns := make(map[string]string, len(sig.Patterns))
+	for name, pattern := range sig.Patterns {
+		patterns[name] = pattern
+	}
+	scopes := make(map[string]PatternScope, len(sig.Scopes))
+	for name, scope := range sig.Scopes {
+		scopes[name] = scope
+	}
+
+	for _, name := range patternNames(expr) {
+		if _, ok := patterns[name]; ok {
+			continue
+		}
+		if pattern, ok := shared[name]; ok {
+			patterns[name] = pattern.value
+			if pattern.scope != nil {
+				scopes[name] = *pattern.scope
+			}
+		}
+	}
+
+	sig.Patterns = patterns
+	if len(scopes) > 0 {
+		sig.Scopes = scopes
+	}
+	return sig, nil
+}
+
+// includedPatterns returns all the patterns shared by the included files.
+// Include paths are relative to the directory of the including file.
+func (r *includeResolver) includedPatterns(
+	fromFile string,
+	includes []string,
+) (map[string]sharedPattern, error) {
+	patterns := make(map[string]sharedPattern)
+
+	for _, include := range includes {
+		shared, err := r.sharedPatterns(includePath(fromFile, include))
+		if err != nil {
+			// Errors in nested includes already point at the failing include.
+			if errors.As(err, &ErrInclude{}) || errors.As(err, &ErrIncludeCycle{}) {
+				return nil, err
+			}
+			return nil, ErrInclude{File: fromFile, Include: include, cause: err}
+		}
+
+		if err := mergeSharedPatterns(patterns, shared); err != nil {
+			return nil, ErrInclude{File: fromFile, Include: include, cause: err}
+		}
+	}
+
+	return patterns, nil
+}
+
+// sharedPatterns returns the patterns shared by the file at the given path.
+func (r *includeResolver) sharedPatterns(filePath string) (map[string]sharedPattern, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if shared, ok := r.shared[absPath]; ok {
+		return shared, nil
+	}
+
+	for i, resolving := range r.resolving {
+		if resolving == absPath {
+			cycle := append(append([]string{}, r.resolving[i:]...), absPath)
+			return nil, ErrIncludeCycle{Files: cycle}
+		}
+	}
+
+	r.resolving = append(r.resolving, absPath)
+	defer func() {
+		r.resolving = r.resolving[:len(r.resolving)-1]
+	}()
+
+	sigs, err := r.readFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := make(map[string]sharedPattern)
+	for _, sig := range sigs {
+		if !sig.Library {
+			continue
+		}
+
+		included, err := r.includedPatterns(filePath, sig.Include)
+		if err != nil {
+			return nil, err
+		}
+
+		own := make(map[string]sharedPattern, len(sig.Patterns))
+		for name, value := range sig.Patterns {
+			own[name] = sharedPattern{value: value, source: filePath}
+			if scope, ok := sig.Scopes[name]; ok {
+				own[name] = sharedPattern{value: value, scope: &scope, source: filePath}
+			}
+		}
+
+		if err := mergeSharedPatterns(shared, included); err != nil {
+			return nil, err
+		}
+		if err := mergeSharedPatterns(shared, own); err != nil {
+			return nil, err
+		}
+	}
+
+	r.shared[absPath] = shared
+	return shared, nil
+}
+
+// includePath returns the path to the included file, which is relative to the
+// directory of the including file, unless absolute.
+func includePath(fromFile, include string) string {
+	if filepath.IsAbs(include) {
+		return include
+	}
+
+	return filepath.Join(filepath.Dir(fromFile), include)
+}
+
+// mergeSharedPatterns adds the patterns in src to dst.
+// It fails if a pattern with the same name but a different value, or scope, is
+// in both.
+func mergeSharedPatterns(dst, src map[string]sharedPattern) error {
+	names := make([]string, 0, len(src))
+	for name := range src {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pattern := src[name]
+		if existing, ok := dst[name]; ok && !existing.equal(pattern) {
+			return fmt.Errorf(
+				"pattern '%s' is defined differently in '%s' and '%s'",
+				name, existing.source, pattern.source,
+			)
+		}
+
+		dst[name] = pattern
+	}
+
+	return nil
+}
+
+// patternNames returns the names in the expression that can refer to patterns:
+// its variables, and those its comparisons refer to (e.g. pe.in_section(a, ".text"),
+// a at entrypoint or @a[1]).
+func patternNames(expr *bexpr.Expression) []string {
+	names := expr.Vars()
+	for _, cmp := range expr.Comparisons() {
+		names = append(names, cmp.Refs()...)
+	}
+
+	return names
+}
+
+// equal returns true if both patterns have the same value and scope.
+func (p sharedPattern) equal(other sharedPattern) bool {
+	if p.value != other.value || (p.scope == nil) != (other.scope == nil) {
+		return false
+	}
+
+	return p.scope == nil ||
+		p.scope.Section == other.scope.Section &&
+			p.scope.Overlay == other.scope.Overlay &&
+			slices.Equal(p.scope.Range, other.scope.Range)
+}
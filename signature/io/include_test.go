@@ -0,0 +1,184 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/angelsolaorbaiceta/binmat/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncludes(t *testing.T) {
+	writeFiles := func(t *testing.T, files map[string]string) string {
+		dir := t.TempDir()
+		for name, content := range files {
+			filePath := filepath.Join(dir, name)
+			os.MkdirAll(filepath.Dir(filePath), 0o755)
+			if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+				t.Fatalf("Can't write test file: %s", err)
+			}
+		}
+
+		return dir
+	}
+
+	t.Run("signatures use the patterns in included libraries", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"lib/pe.yaml": `library: true
+include: [stubs.yaml]
+patterns:
+  mz: '{ 4d 5a 90 00 }'
+  pe: '{ 50 45 00 00 }'
+`,
+			"lib/stubs.yaml": `library: true
+patterns:
+  upx: UPX!
+`,
+			"rules/packed.yaml": `name: packed pe
+include: [../lib/pe.yaml]
+patterns:
+  pe: '{ 50 45 00 00 4c 01 }'
+condition: mz AND (pe AND upx)
+`,
+		})
+
+		sigs, err := LoadSignatures(filepath.Join(dir, "rules"))
+
+		assert.Nil(t, err)
+		if assert.Len(t, sigs, 1) {
+			assert.Equal(t, "packed pe", sigs[0].Name)
+			assert.Len(t, sigs[0].Patterns, 3)
+			// The signature's own pattern takes precedence over the included one.
+			assert.Equal(t, 6, sigs[0].Patterns["pe"].Length())
+			assert.Equal(t, []byte("UPX!"), sigs[0].Patterns["upx"].Bytes())
+		}
+	})
+
+	t.Run("signatures in included files are loaded", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"common.yaml": `library: true
+patterns:
+  mz: '{ 4d 5a 90 00 }'
+---
+name: common rule
+patterns:
+  a: '{ 01 02 03 }'
+condition: a
+`,
+			"rules/rule.yaml": `name: rule
+include: [../common.yaml]
+condition: mz
+`,
+		})
+
+		sigs, err := LoadSignatures(filepath.Join(dir, "rules"))
+
+		assert.Nil(t, err)
+		if assert.Len(t, sigs, 2) {
+			assert.Equal(t, "rule", sigs[0].Name)
+			assert.Equal(t, "common rule", sigs[1].Name)
+		}
+	})
+
+	t.Run("include cycles are detected", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"a.yaml":    "library: true\ninclude: [b.yaml]\npatterns:\n  a: '{ 01 02 03 }'\n",
+			"b.yaml":    "library: true\ninclude: [a.yaml]\npatterns:\n  b: '{ 04 05 06 }'\n",
+			"rule.yaml": "name: rule\ninclude: [a.yaml]\ncondition: a AND b\n",
+		})
+
+		_, err := LoadSignatures(filepath.Join(dir, "rule.yaml"))
+
+		var cycleErr ErrIncludeCycle
+		if assert.ErrorAs(t, err, &cycleErr) {
+			assert.Equal(t, []string{
+				filepath.Join(dir, "a.yaml"),
+				filepath.Join(dir, "b.yaml"),
+				filepath.Join(dir, "a.yaml"),
+			}, cycleErr.Files)
+		}
+	})
+
+	t.Run("missing included files", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"rule.yaml": "name: rule\ninclude: [missing.yaml]\ncondition: a\n",
+		})
+
+		_, err := LoadSignatures(dir)
+
+		var includeErr ErrInclude
+		if assert.ErrorAs(t, err, &includeErr) {
+			assert.Equal(t, "missing.yaml", includeErr.Include)
+		}
+	})
+
+	t.Run("conflicting pattern definitions", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"a.yaml":    "library: true\npatterns:\n  mz: '{ 4d 5a }'\n",
+			"b.yaml":    "library: true\npatterns:\n  mz: '{ 4d 5a 90 }'\n",
+			"rule.yaml": "name: rule\ninclude: [a.yaml, b.yaml]\ncondition: mz\n",
+		})
+
+		_, err := LoadSignatures(filepath.Join(dir, "rule.yaml"))
+
+		assert.ErrorContains(t, err, "pattern 'mz' is defined differently")
+	})
+
+	t.Run("included patterns keep their scope", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"lib.yaml":  "library: true\npatterns:\n  mz:\n    pattern: '{ 4d 5a }'\n    scope: {range: [0, 2]}\n",
+			"rule.yaml": "name: rule\ninclude: [lib.yaml]\ncondition: mz\n",
+		})
+
+		sigs, err := LoadSignatures(filepath.Join(dir, "rule.yaml"))
+
+		assert.Nil(t, err)
+		if assert.Len(t, sigs, 1) {
+			assert.Equal(t, signature.Scope{Start: 0, End: 2}, sigs[0].Patterns["mz"].Scope())
+		}
+	})
+
+	t.Run("included patterns can be checked where they match", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"lib.yaml":  "library: true\npatterns:\n  mz: '{ 4d 5a }'\n  upx: UPX!\n",
+			"rule.yaml": "name: rule\ninclude: [lib.yaml]\ncondition: mz at 0 AND pe.in_section(upx, \"UPX0\")\n",
+		})
+
+		sigs, err := LoadSignatures(filepath.Join(dir, "rule.yaml"))
+
+		assert.Nil(t, err)
+		if assert.Len(t, sigs, 1) {
+			assert.Len(t, sigs[0].Patterns, 2)
+		}
+	})
+
+	t.Run("conflicting pattern scopes", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"a.yaml":    "library: true\npatterns:\n  mz: '{ 4d 5a }'\n",
+			"b.yaml":    "library: true\npatterns:\n  mz:\n    pattern: '{ 4d 5a }'\n    scope: overlay\n",
+			"rule.yaml": "name: rule\ninclude: [a.yaml, b.yaml]\ncondition: mz\n",
+		})
+
+		_, err := LoadSignatures(filepath.Join(dir, "rule.yaml"))
+
+		assert.ErrorContains(t, err, "pattern 'mz' is defined differently")
+	})
+
+	t.Run("validation resolves includes", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"lib.yaml": "library: true\npatterns:\n  mz: '{ 4d 5a 90 }'\n",
+			"ok.yaml":  "name: ok\ninclude: [lib.yaml]\ncondition: mz\n",
+			"bad.yaml": "name: bad\ninclude: [missing.yaml]\ncondition: mz\n",
+		})
+
+		issues, err := Validate(dir)
+
+		assert.Nil(t, err)
+		if assert.Len(t, issues, 1) {
+			assert.Equal(t, filepath.Join(dir, "bad.yaml"), issues[0].File)
+			assert.Equal(t, 2, issues[0].Line)
+			assert.Equal(t, SeverityError, issues[0].Severity)
+		}
+	})
+}
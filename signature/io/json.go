@@ -0,0 +1,135 @@
+package io
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ReadAllFromJson attempts to decode all the signatures in a json file.
+// The file can contain either a single signature or a list of signatures,
+// whose Document is their 1-based index in the list.
+//
+// If a signature has a pattern whose value isn't a string, an ErrPatternValue
+// is returned, inside an ErrDocument with the item of the list. Syntax and type
+// errors point at the failing line.
+func ReadAllFromJson(r io.Reader) ([]Signature, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	// Decoding the file as generic values first finds the patterns that aren't
+	// strings, which would otherwise fail with a less helpful type error.
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, jsonError(data, err)
+	}
+
+	items, isList := value.([]any)
+	if !isList {
+		items = []any{value}
+	}
+	for i, item := range items {
+		if err := checkJsonPatterns(item); err != nil {
+			docErr := ErrDocument{Document: 1, cause: err}
+			if isList {
+				docErr.Item = i + 1
+			}
+
+			return nil, docErr
+		}
+	}
+
+	var signatures []Signature
+	if isList {
+		err = json.Unmarshal(data, &signatures)
+	} else {
+		signatures = make([]Signature, 1)
+		err = json.Unmarshal(data, &signatures[0])
+	}
+	if err != nil {
+		return nil, jsonError(data, err)
+	}
+
+	for i := range signatures {
+		signatures[i].Document = i + 1
+	}
+
+	return signatures, nil
+}
+
+// checkJsonPatterns returns an ErrPatternValue if any of the patterns in the
+// decoded signature isn't a string, or an object with a string pattern.
+func checkJsonPatterns(item any) error {
+	sig, _ := item.(map[string]any)
+	patterns, _ := sig["patterns"].(map[string]any)
+
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := patterns[name]
+		// Scoped patterns are objects with the pattern and its scope.
+		if object, ok := value.(map[string]any); ok {
+			if pattern, ok := object["pattern"]; ok {
+				value = pattern
+			}
+		}
+
+		if kind := jsonValueKind(value); kind != "" {
+			return ErrPatternValue{Pattern: name, Kind: kind}
+		}
+	}
+
+	return nil
+}
+
+// jsonValueKind returns the kind of the decoded json value, if it isn't a
+// string. Otherwise, it returns an empty string.
+func jsonValueKind(value any) string {
+	switch value.(type) {
+	case string:
+		return ""
+	case map[string]any:
+		return "map"
+	case []any:
+		return "list"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+
+	return "number"
+}
+
+// jsonError adds the line where the error happened to syntax and type errors.
+func jsonError(data []byte, err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		offset    int64
+	)
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+
+	line := bytes.Count(data[:min(offset, int64(len(data)))], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %w", line, err)
+}
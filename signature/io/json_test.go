@@ -0,0 +1,132 @@
+package io
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadAllFromJson(t *testing.T) {
+	t.Run("a single signature", func(t *testing.T) {
+		json := `{
+  "name": "first",
+  "patterns": {"a": "{ 01 02 03 }"},
+  "condition": "a",
+  "tags": ["packer"]
+}`
+		sigs, err := ReadAllFromJson(strings.NewReader(json))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []Signature{{
+			Name:      "first",
+			Patterns:  map[string]string{"a": "{ 01 02 03 }"},
+			Condition: "a",
+			Tags:      []string{"packer"},
+			Document:  1,
+		}}, sigs)
+	})
+
+	t.Run("a list of signatures", func(t *testing.T) {
+		json := `[
+  {"name": "first", "patterns": {"a": "{ 01 02 03 }"}, "condition": "a"},
+  {"name": "second", "patterns": {"a": "text"}, "condition": "a", "private": true}
+]`
+		sigs, err := ReadAllFromJson(strings.NewReader(json))
+
+		assert.Nil(t, err)
+		if assert.Len(t, sigs, 2) {
+			assert.Equal(t, "first", sigs[0].Name)
+			assert.Equal(t, "second", sigs[1].Name)
+			assert.True(t, sigs[1].Private)
+			assert.Equal(t, 1, sigs[0].Document)
+			assert.Equal(t, 2, sigs[1].Document)
+		}
+	})
+
+	t.Run("patterns that aren't strings report their item", func(t *testing.T) {
+		json := `[
+  {"name": "first", "patterns": {"a": "{ 01 02 03 }"}, "condition": "a"},
+  {
+    "name": "second",
+    "patterns": {
+      "a": "text",
+      "b": 1234
+    },
+    "condition": "a"
+  }
+]`
+		_, err := ReadAllFromJson(strings.NewReader(json))
+
+		var (
+			docErr     ErrDocument
+			patternErr ErrPatternValue
+		)
+		if assert.ErrorAs(t, err, &docErr) {
+			assert.Equal(t, 2, docErr.Item)
+		}
+		if assert.ErrorAs(t, err, &patternErr) {
+			assert.Equal(t, ErrPatternValue{Pattern: "b", Kind: "number"}, patternErr)
+		}
+	})
+
+	t.Run("syntax errors report their line", func(t *testing.T) {
+		json := "[\n  {\"name\": \"first\"},\n  {\"name\": second}\n]"
+		_, err := ReadAllFromJson(strings.NewReader(json))
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "line 3:")
+		}
+	})
+
+	t.Run("type errors report their line", func(t *testing.T) {
+		json := "{\n  \"name\": \"first\",\n  \"tags\": \"packer\"\n}"
+		_, err := ReadAllFromJson(strings.NewReader(json))
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "line 3:")
+		}
+	})
+}
+
+func TestLoadJsonSignatures(t *testing.T) {
+	dir := writeSigFiles(t, map[string]string{
+		"one.json":  `{"name": "one", "patterns": {"a": "{ 01 02 03 }"}, "condition": "a"}`,
+		"two.yaml":  "name: two\npatterns:\n  a: '{ 04 05 06 }'\ncondition: a AND one\n",
+		"skip.txt":  "not a signature",
+		"bad.jsonx": "not a signature either",
+	})
+
+	sigs, err := LoadSignatures(dir)
+
+	assert.Nil(t, err)
+	if assert.Len(t, sigs, 2) {
+		assert.Equal(t, "one", sigs[0].Name)
+		assert.Equal(t, "two", sigs[1].Name)
+	}
+
+	t.Run("validates json files", func(t *testing.T) {
+		dir := writeSigFiles(t, map[string]string{
+			"bad.json":    "{\n  \"name\": \"bad\",\n  \"patterns\": {\"a\": [1, 2]},\n  \"condition\": \"a\"\n}",
+			"broken.json": "{\n  \"name\": \"broken\",\n  \"patterns\": {\n}",
+			"unused.json": "{\n  \"name\": \"unused\",\n  \"patterns\": {\"a\": \"abcd\", \"b\": \"efgh\"},\n  \"condition\": \"a\"\n}",
+		})
+
+		issues, err := Validate(dir)
+
+		assert.Nil(t, err)
+		if assert.Len(t, issues, 3) {
+			assert.Equal(t, Issue{
+				File:     issues[0].File,
+				Line:     3,
+				Column:   21,
+				Severity: SeverityError,
+				Message:  "pattern 'a' must be a string, got a list",
+			}, issues[0])
+			assert.Equal(t, SeverityError, issues[1].Severity, issues[1].String())
+			assert.Equal(t, 4, issues[1].Line, issues[1].String())
+			assert.Equal(t, SeverityWarning, issues[2].Severity, issues[2].String())
+			assert.Equal(t, 3, issues[2].Line, issues[2].String())
+		}
+	})
+}
@@ -1,16 +1,21 @@
 package io
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/angelsolaorbaiceta/binmat/signature"
 )
 
-// LoadSignatures loads the signatures from the .yaml files found at the given
-// directory path, typically "$HOME/.config/binmat".
-func LoadSignatures(path string) (signature.Signatures, error) {
-	signatures, err := loadIOSignatures(path)
+// LoadSignatures loads the signatures from the .yaml, .yml and .json files found
+// at the given paths, typically "$HOME/.config/binmat".
+//
+// Paths can be files or directories. Directories are recursively explored,
+// skipping the files and directories listed in their IgnoreFileName.
+func LoadSignatures(paths ...string) (signature.Signatures, error) {
+	signatures, err := loadIOSignatures(paths)
 	if err != nil {
 		return nil, err
 	}
@@ -23,47 +28,89 @@ func LoadSignatures(path string) (signature.Signatures, error) {
 	return domainSigs, nil
 }
 
-func loadIOSignatures(path string) ([]Signature, error) {
-	yamlFilePaths, err := findYamlFiles(path)
+// loadIOSignatures reads the signatures in the files found at the given paths,
+// and resolves their includes. The signatures in the included files are loaded
+// too, after those in the files found at the paths.
+func loadIOSignatures(paths []string) ([]Signature, error) {
+	sigFilePaths, err := findSigFiles(paths)
 	if err != nil {
 		return nil, err
 	}
 
-	signatures := make([]Signature, len(yamlFilePaths))
+	var (
+		signatures []Signature
+		resolver   = newIncludeResolver()
+		// loaded are the absolute paths to the files whose signatures were loaded.
+		loaded = make(map[string]bool)
+	)
 
-	for i, filePath := range yamlFilePaths {
-		r, err := os.Open(filePath)
+	loadFile := func(filePath string) error {
+		absPath, err := filepath.Abs(filePath)
 		if err != nil {
-			return signatures, err
+			return err
+		}
+		if loaded[absPath] {
+			return nil
 		}
 
-		sig, err := ReadFromYaml(r)
+		sigs, err := resolver.readFile(filePath)
 		if err != nil {
+			return fmt.Errorf("%s: %w", filePath, err)
+		}
+
+		loaded[absPath] = true
+		signatures = append(signatures, sigs...)
+		return nil
+	}
+
+	for _, filePath := range sigFilePaths {
+		if err := loadFile(filePath); err != nil {
 			return signatures, err
 		}
+	}
 
+	// Resolving the includes can load new files, whose signatures are appended
+	// and resolved in turn.
+	for i := 0; i < len(signatures); i++ {
+		sig, err := resolver.resolve(signatures[i])
+		if err != nil {
+			return signatures, fmt.Errorf("%s: document %d: %w", sig.Source, sig.Document, err)
+		}
 		signatures[i] = sig
+
+		for _, include := range sig.Include {
+			if err := loadFile(includePath(sig.Source, include)); err != nil {
+				return signatures, err
+			}
+		}
 	}
 
 	return signatures, nil
 }
 
-// findYamlFiles returns a slice of full paths to all .yaml files found in the
-// passed in directory. Directories aren't recursively explored, just the top
-// level is searched.
-func findYamlFiles(path string) ([]string, error) {
-	var yamlFiles []string
-
-	entries, err := os.ReadDir(path)
+// readSigFile reads all the signatures in the file at the given path, recording
+// the path as the signatures' source.
+// Files with the .json extension are read as json, and the rest as yaml.
+func readSigFile(filePath string) ([]Signature, error) {
+	r, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
+	defer r.Close()
 
-	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".yaml" {
-			yamlFiles = append(yamlFiles, filepath.Join(path, entry.Name()))
-		}
+	readAll := ReadAllFromYaml
+	if isJsonFile(filePath) {
+		readAll = ReadAllFromJson
 	}
 
-	return yamlFiles, nil
+	sigs, err := readAll(r)
+	for i := range sigs {
+		sigs[i].Source = filePath
+	}
+
+	return sigs, err
+}
+
+func isJsonFile(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), ".json")
 }
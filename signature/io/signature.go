@@ -1,12 +1,16 @@
 package io
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/angelsolaorbaiceta/binmat/bexpr"
 	"github.com/angelsolaorbaiceta/binmat/signature"
 	"gopkg.in/yaml.v3"
 )
@@ -14,14 +18,279 @@ import (
 var bytePatternRe = regexp.MustCompile(`^\s*\{[0-9a-fA-F ?]*\}\s*$`)
 
 // A Signature is the serialization read/write entity for a domain signature.
+// Signatures can be read from yaml and json files.
 type Signature struct {
-	Name        string            `yaml:"name"`
-	Description string            `yaml:"description"`
-	Patterns    map[string]string `yaml:"patterns"`
-	Condition   string            `yaml:"condition"`
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description" json:"description"`
+	Patterns    map[string]string `yaml:"patterns" json:"patterns"`
+	// Scopes are the scopes of the patterns that have one, by name. They're
+	// read and written along with the patterns (see PatternScope).
+	Scopes    map[string]PatternScope `yaml:"-" json:"-"`
+	Condition string                  `yaml:"condition" json:"condition"`
+	Tags      []string                `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Meta      map[string]string       `yaml:"meta,omitempty" json:"meta,omitempty"`
+	// Include are the paths, relative to the file, to the files whose library
+	// patterns the signature can use in its condition.
+	Include []string `yaml:"include,omitempty" json:"include,omitempty"`
+	// Library is true if the document isn't a signature, but a set of patterns
+	// to be included by other signatures.
+	Library bool `yaml:"library,omitempty" json:"library,omitempty"`
+	// Private signatures can be referenced by other signatures' conditions, but
+	// their matches aren't reported.
+	Private bool `yaml:"private,omitempty" json:"private,omitempty"`
+	// Tests are the samples the signature should, or shouldn't, match.
+	Tests []SignatureTest `yaml:"tests,omitempty" json:"tests,omitempty"`
+	// Source is the path to the file the signature was read from, if any.
+	Source string `yaml:"-" json:"-"`
+	// Document is the 1-based index of the yaml document, in the source, the
+	// signature was read from, or of the item of a json list.
+	Document int `yaml:"-" json:"-"`
+}
+
+// A PatternScope restricts the region of the file where a pattern is searched
+// for (see signature.Scope). Patterns with a scope are written as a mapping,
+// with the pattern and its scope, which is either "overlay", a section or a
+// range of offsets, the end excluded:
+//
+//	patterns:
+//	  a: '{ 60 be ?? ?? }'
+//	  b:
+//	    pattern: '{ 8d be ?? ?? }'
+//	    scope: {section: .text}
+//	  c:
+//	    pattern: 'This program'
+//	    scope: {range: [0, 4096]}
+//	  d:
+//	    pattern: 'PK'
+//	    scope: overlay
+type PatternScope struct {
+	Section string `yaml:"section,omitempty" json:"section,omitempty"`
+	Range   []int  `yaml:"range,flow,omitempty" json:"range,omitempty"`
+	Overlay bool   `yaml:"-" json:"-"`
+}
+
+// overlayScope is how the overlay scope is written.
+const overlayScope = "overlay"
+
+// A scopedPattern is how patterns with a scope are written.
+type scopedPattern struct {
+	Pattern string       `yaml:"pattern" json:"pattern"`
+	Scope   PatternScope `yaml:"scope" json:"scope"`
+}
+
+func (p *PatternScope) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if node.Value != overlayScope {
+			return fmt.Errorf("line %d: unknown scope '%s'", node.Line, node.Value)
+		}
+		*p = PatternScope{Overlay: true}
+		return nil
+	}
+
+	type plain PatternScope
+	return node.Decode((*plain)(p))
+}
+
+func (p PatternScope) MarshalYAML() (any, error) {
+	if p.Overlay {
+		return overlayScope, nil
+	}
+
+	type plain PatternScope
+	return plain(p), nil
+}
+
+func (p *PatternScope) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		if name != overlayScope {
+			return fmt.Errorf("unknown scope '%s'", name)
+		}
+		*p = PatternScope{Overlay: true}
+		return nil
+	}
+
+	type plain PatternScope
+	return json.Unmarshal(data, (*plain)(p))
+}
+
+// toDomain checks that the scope has exactly one region, and maps it to the
+// domain scope.
+func (p PatternScope) toDomain() (signature.Scope, error) {
+	var regions int
+	for _, isSet := range []bool{p.Section != "", p.Range != nil, p.Overlay} {
+		if isSet {
+			regions++
+		}
+	}
+
+	switch {
+	case regions != 1:
+		return signature.Scope{}, errors.New("the scope must be either a section, a range or the overlay")
+	case p.Range != nil && (len(p.Range) != 2 || p.Range[0] < 0 || p.Range[0] >= p.Range[1]):
+		return signature.Scope{}, fmt.Errorf("the range must be a start and an end offset, the start first, got %v", p.Range)
+	case p.Range != nil:
+		return signature.Scope{Start: p.Range[0], End: p.Range[1]}, nil
+	}
+
+	return signature.Scope{Section: p.Section, Overlay: p.Overlay}, nil
+}
+
+// UnmarshalYAML decodes the signature, failing with an ErrPatternValue if any
+// of its patterns isn't a string, instead of the generic yaml decoding error.
+// The scopes of the patterns written with one are decoded into Scopes.
+func (s *Signature) UnmarshalYAML(node *yaml.Node) error {
+	var scopes map[string]PatternScope
+
+	if patterns := valueNode(node, "patterns"); patterns != nil && patterns.Kind == yaml.MappingNode {
+		// Scoped patterns are replaced by the pattern itself in a copy of the
+		// nodes, as the validator decodes the same nodes more than once.
+		flatPatterns := *patterns
+		flatPatterns.Content = slices.Clone(patterns.Content)
+
+		for i := 0; i+1 < len(flatPatterns.Content); i += 2 {
+			var (
+				name  = flatPatterns.Content[i].Value
+				value = flatPatterns.Content[i+1]
+			)
+
+			if patternNode := valueNode(value, "pattern"); patternNode != nil {
+				if kind := yamlValueKind(patternNode); kind != "" {
+					return ErrPatternValue{Pattern: name, Kind: kind, Line: patternNode.Line, Column: patternNode.Column}
+				}
+
+				var scoped scopedPattern
+				if err := value.Decode(&scoped); err != nil {
+					return err
+				}
+				if scopes == nil {
+					scopes = make(map[string]PatternScope)
+				}
+				scopes[name] = scoped.Scope
+				flatPatterns.Content[i+1] = patternNode
+				continue
+			}
+
+			if kind := yamlValueKind(value); kind != "" {
+				return ErrPatternValue{Pattern: name, Kind: kind, Line: value.Line, Column: value.Column}
+			}
+		}
+
+		flatNode := *node
+		flatNode.Content = slices.Clone(node.Content)
+		for i := 0; i+1 < len(flatNode.Content); i += 2 {
+			if flatNode.Content[i+1] == patterns {
+				flatNode.Content[i+1] = &flatPatterns
+			}
+		}
+		node = &flatNode
+	}
+
+	// The plain type doesn't have the UnmarshalYAML method, which would recurse.
+	type plain Signature
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+
+	s.Scopes = scopes
+	return nil
+}
+
+// MarshalYAML encodes the signature, writing the patterns that have a scope as
+// a mapping with the pattern and its scope.
+func (s Signature) MarshalYAML() (any, error) {
+	type plain Signature
+	if len(s.Scopes) == 0 {
+		return plain(s), nil
+	}
+
+	var node yaml.Node
+	if err := node.Encode(plain(s)); err != nil {
+		return nil, err
+	}
+
+	patterns := valueNode(&node, "patterns")
+	for i := 0; patterns != nil && i+1 < len(patterns.Content); i += 2 {
+		scope, ok := s.Scopes[patterns.Content[i].Value]
+		if !ok {
+			continue
+		}
+
+		var scoped yaml.Node
+		if err := scoped.Encode(scopedPattern{Pattern: patterns.Content[i+1].Value, Scope: scope}); err != nil {
+			return nil, err
+		}
+		patterns.Content[i+1] = &scoped
+	}
+
+	return &node, nil
+}
+
+// UnmarshalJSON decodes the signature, reading the patterns written with a
+// scope into the Patterns and Scopes.
+func (s *Signature) UnmarshalJSON(data []byte) error {
+	// The plain type doesn't have the UnmarshalJSON method, which would recurse,
+	// and its patterns are shadowed by the raw ones.
+	type plain Signature
+	sig := struct {
+		*plain
+		Patterns map[string]json.RawMessage `json:"patterns"`
+	}{plain: (*plain)(s)}
+
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return err
+	}
+
+	if sig.Patterns == nil {
+		return nil
+	}
+
+	s.Patterns = make(map[string]string, len(sig.Patterns))
+	for name, raw := range sig.Patterns {
+		var pattern string
+		if err := json.Unmarshal(raw, &pattern); err == nil {
+			s.Patterns[name] = pattern
+			continue
+		}
+
+		var scoped scopedPattern
+		if err := json.Unmarshal(raw, &scoped); err != nil {
+			return err
+		}
+		if s.Scopes == nil {
+			s.Scopes = make(map[string]PatternScope)
+		}
+		s.Patterns[name] = scoped.Pattern
+		s.Scopes[name] = scoped.Scope
+	}
+
+	return nil
+}
+
+// yamlValueKind returns the kind of value in the node, if it isn't a string.
+// Otherwise, it returns an empty string.
+func yamlValueKind(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "map"
+	case yaml.SequenceNode:
+		return "list"
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!float":
+			return "number"
+		case "!!bool":
+			return "boolean"
+		case "!!null":
+			return "null"
+		}
+	}
+
+	return ""
 }
 
 // ReadFromYaml attempts to decode a Signature from a yaml file.
+// Only the first document in the file is read.
 func ReadFromYaml(r io.Reader) (Signature, error) {
 	var (
 		decoder   = yaml.NewDecoder(r)
@@ -32,29 +301,138 @@ func ReadFromYaml(r io.Reader) (Signature, error) {
 	return signature, err
 }
 
+// ReadAllFromYaml attempts to decode all the signatures in a yaml file.
+// The file can contain several documents, separated by "---", and each document
+// can be either a single signature or a list of signatures.
+//
+// If a document can't be decoded, an ErrDocument is returned with the
+// signatures read up to that point.
+func ReadAllFromYaml(r io.Reader) ([]Signature, error) {
+	var (
+		decoder    = yaml.NewDecoder(r)
+		signatures []Signature
+	)
+
+	for docIdx := 1; ; docIdx++ {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			return signatures, nil
+		}
+		if err != nil {
+			return signatures, ErrDocument{Document: docIdx, cause: err}
+		}
+
+		if isEmptyDocument(&doc) {
+			continue
+		}
+
+		for i, node := range documentSigNodes(&doc) {
+			var signature Signature
+			if err := node.Decode(&signature); err != nil {
+				docErr := ErrDocument{Document: docIdx, cause: err}
+				if doc.Content[0].Kind == yaml.SequenceNode {
+					docErr.Item = i + 1
+				}
+
+				return signatures, docErr
+			}
+
+			signature.Document = docIdx
+			signatures = append(signatures, signature)
+		}
+	}
+}
+
+// WriteAllToYaml encodes the signatures into a yaml file, one per document,
+// that ReadAllFromYaml can read back.
+func WriteAllToYaml(w io.Writer, sigs []Signature) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	for _, signature := range sigs {
+		if err := encoder.Encode(signature); err != nil {
+			return err
+		}
+	}
+
+	return encoder.Close()
+}
+
+// isEmptyDocument returns true if the yaml document doesn't have any content,
+// like the one after a trailing "---". Empty documents don't hold any signature.
+func isEmptyDocument(doc *yaml.Node) bool {
+	return len(doc.Content) == 0 || doc.Content[0].ShortTag() == "!!null"
+}
+
+// documentSigNodes returns the nodes holding the signatures in the document:
+// the items when the document is a list, or the document content otherwise.
+func documentSigNodes(doc *yaml.Node) []*yaml.Node {
+	root := doc.Content[0]
+	if root.Kind == yaml.SequenceNode {
+		return root.Content
+	}
+
+	return []*yaml.Node{root}
+}
+
 // ToDomain maps the signature to a domain instance of the signature.
 // The returned error can be:
 //   - ErrSignature: if the error happens in the creation of the signature
 //   - error: if there's an error parsing a pattern
 func (s Signature) ToDomain() (signature.Signature, error) {
+	return s.toDomain(nil)
+}
+
+// toDomain maps the signature to a domain instance of the signature, whose
+// condition can reference the signatures with the names in refs.
+func (s Signature) toDomain(refs []string) (signature.Signature, error) {
 	var (
 		patterns = make(map[string]*signature.SignaturePattern)
 		err      error
 	)
 
-	for name, pattern := range s.Patterns {
-		patterns[name], err = patternToDomain(pattern)
+	for name := range s.Patterns {
+		patterns[name], err = s.patternToDomain(name)
 		if err != nil {
-			return signature.Signature{}, err
+			return signature.Signature{}, fmt.Errorf("pattern '%s': %w", name, err)
 		}
 	}
 
-	return signature.Make(s.Name, s.Description, patterns, s.Condition)
+	sig, err := signature.MakeWithRefs(s.Name, s.Description, patterns, s.Condition, refs)
+	sig.Private = s.Private
+	sig.Tags = s.Tags
+	sig.Meta = s.Meta
+	sig.Source = s.Source
+
+	return sig, err
 }
 
+// patternToDomain parses the signature's pattern with the given name, and its
+// scope, if any, into a domain SignaturePattern.
+func (s Signature) patternToDomain(name string) (*signature.SignaturePattern, error) {
+	pattern, err := patternToDomain(s.Patterns[name])
+	if err != nil {
+		return nil, err
+	}
+
+	if scope, ok := s.Scopes[name]; ok {
+		domainScope, err := scope.toDomain()
+		if err != nil {
+			return nil, err
+		}
+		pattern = pattern.WithScope(domainScope)
+	}
+
+	return pattern, nil
+}
+
+// errEmptyPattern is returned for patterns without bytes, which can't match.
+var errEmptyPattern = errors.New("the pattern is empty")
+
 // patternToDomain parses a given pattern into a domain SignaturePattern.
 // Patterns can be binary sequences or strings.
-// Returns an error if the pattern can't be parsed.
+// Returns an error if the pattern can't be parsed, or is empty.
 func patternToDomain(pattern string) (*signature.SignaturePattern, error) {
 	if bytePatternRe.MatchString(pattern) {
 		var (
@@ -82,28 +460,61 @@ func patternToDomain(pattern string) (*signature.SignaturePattern, error) {
 			}
 		}
 
+		if len(fields) == 0 {
+			return nil, errEmptyPattern
+		}
+
 		return signature.MakePatternWithMask(bytePattern, byteMask), nil
 	}
 
+	if pattern == "" {
+		return nil, errEmptyPattern
+	}
+
 	// The sequence appears to be a string. Convert to its ascii bytes.
 	return signature.MakePattern([]byte(pattern)), nil
 }
 
-func signaturesToDomain(sigs []Signature) ([]signature.Signature, error) {
+// signaturesToDomain maps the signatures to domain instances, skipping the
+// library documents, and links them (see signature.Link).
+// Conditions can reference any of the signatures whose name is a valid
+// condition variable name.
+func signaturesToDomain(sigs []Signature) (signature.Signatures, error) {
 	var (
-		domainSigs = make([]signature.Signature, len(sigs))
+		domainSigs = make([]signature.Signature, 0, len(sigs))
 		domainSig  signature.Signature
+		refs       = referenceableNames(sigs)
 		err        error
 	)
 
-	for i, sig := range sigs {
-		domainSig, err = sig.ToDomain()
+	for _, sig := range sigs {
+		if sig.Library {
+			continue
+		}
+
+		domainSig, err = sig.toDomain(refs)
 		if err != nil {
+			if sig.Source != "" {
+				err = fmt.Errorf("%s: document %d: %w", sig.Source, sig.Document, err)
+			}
 			return domainSigs, err
 		}
 
-		domainSigs[i] = domainSig
+		domainSigs = append(domainSigs, domainSig)
+	}
+
+	return signature.Link(domainSigs)
+}
+
+// referenceableNames returns the names of the signatures that can be used as
+// variables in other signatures' conditions.
+func referenceableNames(sigs []Signature) []string {
+	var names []string
+	for _, sig := range sigs {
+		if !sig.Library && bexpr.IsValidVarName(sig.Name) {
+			names = append(names, sig.Name)
+		}
 	}
 
-	return domainSigs, nil
+	return names
 }
@@ -3,6 +3,7 @@ package io
 import (
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -75,3 +76,331 @@ func TestIOSignature(t *testing.T) {
 		assert.NotNil(t, err)
 	})
 }
+
+func TestReadAllFromYaml(t *testing.T) {
+	t.Run("documents separated by '---'", func(t *testing.T) {
+		yaml := `
+name: first
+patterns:
+  a: '{ 01 02 03 }'
+condition: a
+---
+name: second
+patterns:
+  a: '{ 04 05 06 }'
+condition: a
+---
+`
+		sigs, err := ReadAllFromYaml(strings.NewReader(yaml))
+
+		assert.Nil(t, err)
+		if assert.Len(t, sigs, 2) {
+			assert.Equal(t, "first", sigs[0].Name)
+			assert.Equal(t, 1, sigs[0].Document)
+			assert.Equal(t, "second", sigs[1].Name)
+			assert.Equal(t, 2, sigs[1].Document)
+		}
+	})
+
+	t.Run("top-level lists of signatures", func(t *testing.T) {
+		yaml := `
+- name: first
+  patterns:
+    a: '{ 01 02 03 }'
+  condition: a
+- name: second
+  patterns:
+    a: '{ 04 05 06 }'
+  condition: a
+---
+name: third
+patterns:
+  a: '{ 07 08 09 }'
+condition: a
+`
+		sigs, err := ReadAllFromYaml(strings.NewReader(yaml))
+
+		assert.Nil(t, err)
+		if assert.Len(t, sigs, 3) {
+			assert.Equal(t, "first", sigs[0].Name)
+			assert.Equal(t, "second", sigs[1].Name)
+			assert.Equal(t, 1, sigs[1].Document)
+			assert.Equal(t, "third", sigs[2].Name)
+			assert.Equal(t, 2, sigs[2].Document)
+		}
+	})
+
+	t.Run("errors report the failing document and item", func(t *testing.T) {
+		yaml := `
+name: first
+patterns:
+  a: '{ 01 02 03 }'
+condition: a
+---
+- name: second
+  patterns:
+    a: '{ 04 05 06 }'
+  condition: a
+- name: third
+  patterns: [a, b]
+  condition: a
+`
+		sigs, err := ReadAllFromYaml(strings.NewReader(yaml))
+
+		var docErr ErrDocument
+		if assert.ErrorAs(t, err, &docErr) {
+			assert.Equal(t, 2, docErr.Document)
+			assert.Equal(t, 2, docErr.Item)
+		}
+		assert.Len(t, sigs, 2)
+	})
+
+	t.Run("syntax errors report the failing document", func(t *testing.T) {
+		yaml := "name: first\n---\nname: [second\n"
+		_, err := ReadAllFromYaml(strings.NewReader(yaml))
+
+		var docErr ErrDocument
+		if assert.ErrorAs(t, err, &docErr) {
+			assert.Equal(t, 2, docErr.Document)
+			assert.Equal(t, 0, docErr.Item)
+		}
+	})
+}
+
+func TestWriteAllToYaml(t *testing.T) {
+	sigs := []Signature{
+		{
+			Name:      "first",
+			Patterns:  map[string]string{"a": "{ 01 02 03 }", "b": "text"},
+			Condition: "a OR b",
+			Tags:      []string{"packer"},
+			Meta:      map[string]string{"severity": "low"},
+		},
+		{
+			Name:      "second",
+			Patterns:  map[string]string{},
+			Condition: "first",
+			Private:   true,
+		},
+	}
+
+	var yaml strings.Builder
+	if err := WriteAllToYaml(&yaml, sigs); err != nil {
+		t.Fatalf("Want no error, got %s", err)
+	}
+	got, err := ReadAllFromYaml(strings.NewReader(yaml.String()))
+
+	assert.Nil(t, err)
+	if assert.Len(t, got, 2) {
+		for i := range got {
+			got[i].Document = 0
+		}
+		assert.Equal(t, sigs, got)
+	}
+}
+
+func TestIOSignatureTagsAndMeta(t *testing.T) {
+	yaml := `
+name: upx
+tags: [packer, upx]
+meta:
+  author: jdoe
+  severity: medium
+  date: 2024-01-31
+patterns:
+  a: UPX!
+condition: a
+`
+	ioSig, err := ReadFromYaml(strings.NewReader(yaml))
+	assert.Nil(t, err)
+
+	sig, err := ioSig.ToDomain()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"packer", "upx"}, sig.Tags)
+	assert.Equal(t, map[string]string{"author": "jdoe", "severity": "medium", "date": "2024-01-31"}, sig.Meta)
+	assert.Equal(t, signature.SeverityMedium, sig.Severity())
+}
+
+func TestSignatureReferences(t *testing.T) {
+	t.Run("conditions reference other signatures by name", func(t *testing.T) {
+		dir := writeSigFiles(t, map[string]string{
+			"a.yaml": `name: dropper
+patterns:
+  a: '{ 01 02 03 }'
+condition: upx_packed AND a
+`,
+			"b.yaml": `name: upx_packed
+private: true
+patterns:
+  upx: UPX!
+condition: upx
+`,
+		})
+
+		sigs, err := LoadSignatures(dir)
+
+		assert.Nil(t, err)
+		if assert.Len(t, sigs, 2) {
+			assert.Equal(t, "upx_packed", sigs[0].Name)
+			assert.True(t, sigs[0].Private)
+			assert.Equal(t, "dropper", sigs[1].Name)
+			assert.Equal(t, []string{"upx_packed"}, sigs[1].Refs)
+		}
+	})
+
+	t.Run("reference cycles are reported", func(t *testing.T) {
+		dir := writeSigFiles(t, map[string]string{
+			"a.yaml": "name: one\npatterns:\n  a: '{ 01 02 03 }'\ncondition: a AND two\n",
+			"b.yaml": "name: two\npatterns:\n  a: '{ 01 02 03 }'\ncondition: a AND one\n",
+		})
+
+		_, err := LoadSignatures(dir)
+		assert.ErrorContains(t, err, "one -> two -> one")
+
+		issues, err := Validate(dir)
+		assert.Nil(t, err)
+		if assert.Len(t, issues, 1) {
+			assert.Equal(t, filepath.Join(dir, "b.yaml"), issues[0].File)
+			assert.Equal(t, 1, issues[0].Line)
+			assert.Equal(t, SeverityError, issues[0].Severity)
+		}
+	})
+}
+
+func TestReadPatternsThatArentStrings(t *testing.T) {
+	for _, tCase := range []struct {
+		value string
+		kind  string
+	}{
+		{value: "{ 74 fc ff ff }", kind: "map"},
+		{value: "[74, fc]", kind: "list"},
+		{value: "1234", kind: "number"},
+		{value: "true", kind: "boolean"},
+		{value: "", kind: "null"},
+	} {
+		t.Run(tCase.kind, func(t *testing.T) {
+			yaml := "name: ls\npatterns:\n  b: text\n  a: " + tCase.value + "\ncondition: a\n"
+			_, err := ReadAllFromYaml(strings.NewReader(yaml))
+
+			var patternErr ErrPatternValue
+			if assert.ErrorAs(t, err, &patternErr) {
+				assert.Equal(t, "a", patternErr.Pattern)
+				assert.Equal(t, tCase.kind, patternErr.Kind)
+				assert.Equal(t, 4, patternErr.Line)
+			}
+		})
+	}
+}
+
+func TestPatternScopes(t *testing.T) {
+	yaml := `name: scoped
+patterns:
+  a: '{ 60 be ?? ?? }'
+  b:
+    pattern: '{ 8d be ?? ?? }'
+    scope: {section: .text}
+  c:
+    pattern: This program
+    scope: {range: [0, 4096]}
+  d:
+    pattern: PK
+    scope: overlay
+condition: a AND b AND c AND d
+`
+	wantScopes := map[string]PatternScope{
+		"b": {Section: ".text"},
+		"c": {Range: []int{0, 4096}},
+		"d": {Overlay: true},
+	}
+
+	sigs, err := ReadAllFromYaml(strings.NewReader(yaml))
+	if !assert.Nil(t, err) || !assert.Len(t, sigs, 1) {
+		return
+	}
+
+	t.Run("scoped patterns are read with their scope", func(t *testing.T) {
+		assert.Equal(t, map[string]string{
+			"a": "{ 60 be ?? ?? }",
+			"b": "{ 8d be ?? ?? }",
+			"c": "This program",
+			"d": "PK",
+		}, sigs[0].Patterns)
+		assert.Equal(t, wantScopes, sigs[0].Scopes)
+	})
+
+	t.Run("scopes are mapped to the domain patterns", func(t *testing.T) {
+		sig, err := sigs[0].ToDomain()
+
+		assert.Nil(t, err)
+		assert.True(t, sig.Patterns["a"].Scope().IsZero())
+		assert.Equal(t, signature.Scope{Section: ".text"}, sig.Patterns["b"].Scope())
+		assert.Equal(t, signature.Scope{Start: 0, End: 4096}, sig.Patterns["c"].Scope())
+		assert.Equal(t, signature.Scope{Overlay: true}, sig.Patterns["d"].Scope())
+	})
+
+	t.Run("scoped patterns are written back", func(t *testing.T) {
+		var written strings.Builder
+		if err := WriteAllToYaml(&written, sigs); err != nil {
+			t.Fatalf("Want no error, got %s", err)
+		}
+		got, err := ReadAllFromYaml(strings.NewReader(written.String()))
+
+		assert.Nil(t, err)
+		assert.Equal(t, sigs, got)
+		assert.Contains(t, written.String(), "scope: overlay")
+	})
+
+	t.Run("scoped patterns are read from json", func(t *testing.T) {
+		json := `{
+  "name": "scoped",
+  "patterns": {
+    "a": "{ 60 be ?? ?? }",
+    "b": {"pattern": "{ 8d be ?? ?? }", "scope": {"section": ".text"}},
+    "c": {"pattern": "This program", "scope": {"range": [0, 4096]}},
+    "d": {"pattern": "PK", "scope": "overlay"}
+  },
+  "condition": "a AND b AND c AND d"
+}`
+		got, err := ReadAllFromJson(strings.NewReader(json))
+
+		assert.Nil(t, err)
+		if assert.Len(t, got, 1) {
+			assert.Equal(t, sigs[0].Patterns, got[0].Patterns)
+			assert.Equal(t, wantScopes, got[0].Scopes)
+		}
+	})
+
+	for _, tCase := range []struct {
+		name  string
+		scope string
+	}{
+		{name: "unknown scope", scope: "header"},
+		{name: "two regions", scope: "{section: .text, range: [0, 16]}"},
+		{name: "no region", scope: "{}"},
+		{name: "range without end", scope: "{range: [16]}"},
+		{name: "range ending before its start", scope: "{range: [16, 8]}"},
+		{name: "negative range", scope: "{range: [-1, 8]}"},
+	} {
+		t.Run(tCase.name+" is invalid", func(t *testing.T) {
+			yaml := "name: bad\npatterns:\n  a:\n    pattern: PK\n    scope: " + tCase.scope + "\ncondition: a\n"
+
+			sigs, err := ReadAllFromYaml(strings.NewReader(yaml))
+			if err == nil {
+				_, err = sigs[0].ToDomain()
+			}
+
+			assert.NotNil(t, err)
+		})
+	}
+
+	t.Run("scoped patterns that aren't strings", func(t *testing.T) {
+		yaml := "name: bad\npatterns:\n  a:\n    pattern: 1234\n    scope: overlay\ncondition: a\n"
+		_, err := ReadAllFromYaml(strings.NewReader(yaml))
+
+		var patternErr ErrPatternValue
+		if assert.ErrorAs(t, err, &patternErr) {
+			assert.Equal(t, ErrPatternValue{Pattern: "a", Kind: "number", Line: 4, Column: 14}, patternErr)
+		}
+	})
+}
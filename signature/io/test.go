@@ -0,0 +1,130 @@
+package io
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/angelsolaorbaiceta/binmat/signature"
+)
+
+// A SignatureTest is a known sample that a signature should, or shouldn't,
+// match. The sample is either a file or inline hex bytes.
+type SignatureTest struct {
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// File is the path to the sample, relative to the signature file, unless
+	// absolute.
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+	// Hex are the bytes of the sample, written like byte patterns without
+	// wildcards (e.g. "{ 4d 5a 90 00 }").
+	Hex   string `yaml:"hex,omitempty" json:"hex,omitempty"`
+	Match bool   `yaml:"match" json:"match"`
+	// Offsets are the expected offsets where the patterns match the sample.
+	Offsets map[string][]int `yaml:"offsets,omitempty" json:"offsets,omitempty"`
+}
+
+// LoadTests loads the signatures from the files found at the given paths, like
+// LoadSignatures does, together with their tests.
+//
+// Tests are returned in the order they're defined, and their samples are read
+// from disk. An error is returned if a test is malformed or its sample can't
+// be read.
+func LoadTests(paths ...string) (signature.Signatures, []signature.Test, error) {
+	signatures, err := loadIOSignatures(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	domainSigs, err := signaturesToDomain(signatures)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tests []signature.Test
+	for _, sig := range signatures {
+		if sig.Library {
+			continue
+		}
+
+		for i, test := range sig.Tests {
+			domainTest, err := test.toDomain(sig, i)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: document %d: %w", sig.Source, sig.Document, err)
+			}
+			tests = append(tests, domainTest)
+		}
+	}
+
+	return domainSigs, tests, nil
+}
+
+// toDomain maps the test, the idx-th of the signature, to a domain test,
+// reading the sample's bytes.
+func (t SignatureTest) toDomain(sig Signature, idx int) (signature.Test, error) {
+	name := t.testName(idx)
+
+	data, err := t.sample(sig.Source)
+	if err != nil {
+		return signature.Test{}, fmt.Errorf("signature '%s': test '%s': %w", sig.Name, name, err)
+	}
+
+	for pattern := range t.Offsets {
+		if _, ok := sig.Patterns[pattern]; !ok {
+			return signature.Test{}, fmt.Errorf(
+				"signature '%s': test '%s': offsets of unknown pattern '%s'", sig.Name, name, pattern,
+			)
+		}
+	}
+
+	return signature.Test{
+		Signature: sig.Name,
+		Name:      name,
+		Source:    sig.Source,
+		Data:      data,
+		Match:     t.Match,
+		Offsets:   t.Offsets,
+	}, nil
+}
+
+// testName returns the name of the test, or a name made after its sample file
+// or its position in the signature, if it has none.
+func (t SignatureTest) testName(idx int) string {
+	switch {
+	case t.Name != "":
+		return t.Name
+	case t.File != "":
+		return t.File
+	}
+
+	return fmt.Sprintf("#%d", idx+1)
+}
+
+// sample returns the bytes of the test's sample. Sample files are relative to
+// the directory of the signature file, sigSource.
+func (t SignatureTest) sample(sigSource string) ([]byte, error) {
+	switch {
+	case t.File != "" && t.Hex != "":
+		return nil, errors.New("the sample has to be either a file or hex bytes, not both")
+	case t.File != "":
+		return os.ReadFile(includePath(sigSource, t.File))
+	case t.Hex != "":
+		return sampleHex(t.Hex)
+	}
+
+	return nil, errors.New("the sample is missing, set either a file or hex bytes")
+}
+
+// sampleHex parses the inline hex bytes of a sample. The bytes can be enclosed
+// in braces, and separated by whitespace.
+func sampleHex(sample string) ([]byte, error) {
+	digits := strings.Join(strings.Fields(strings.Trim(sample, "{ }")), "")
+
+	data, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex sample: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,101 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testedSig = `name: packed
+patterns:
+  mz: '{ 4d 5a }'
+  upx: UPX!
+condition: mz AND upx
+tests:
+  - name: packed sample
+    file: samples/packed.bin
+    match: true
+    offsets:
+      upx: [4]
+  - hex: '{ 4d 5a 90 00 }'
+    match: false
+`
+
+func TestLoadTests(t *testing.T) {
+	t.Run("loads the tests with their samples", func(t *testing.T) {
+		dir := writeSigFiles(t, map[string]string{"sigs.yaml": testedSig})
+		writeSample(t, dir, "MZ..UPX!..")
+
+		sigs, tests, err := LoadTests(dir)
+
+		assert.Nil(t, err)
+		assert.Len(t, sigs, 1)
+		if assert.Len(t, tests, 2) {
+			assert.Equal(t, "packed", tests[0].Signature)
+			assert.Equal(t, "packed sample", tests[0].Name)
+			assert.Equal(t, []byte("MZ..UPX!.."), tests[0].Data)
+			assert.True(t, tests[0].Match)
+			assert.Equal(t, map[string][]int{"upx": {4}}, tests[0].Offsets)
+
+			assert.Equal(t, "#2", tests[1].Name)
+			assert.Equal(t, []byte{0x4d, 0x5a, 0x90, 0x00}, tests[1].Data)
+			assert.False(t, tests[1].Match)
+		}
+
+		for _, result := range sigs.RunTests(tests) {
+			assert.True(t, result.Passed(), result.Failures)
+		}
+	})
+
+	t.Run("fails if a sample can't be read", func(t *testing.T) {
+		dir := writeSigFiles(t, map[string]string{"sigs.yaml": testedSig})
+
+		_, _, err := LoadTests(dir)
+
+		assert.ErrorContains(t, err, "test 'packed sample'")
+	})
+
+	for _, tCase := range []struct {
+		test string
+		want string
+	}{
+		{test: "{ match: true }", want: "the sample is missing"},
+		{test: "{ hex: '4d 5a', file: a.bin }", want: "not both"},
+		{test: "{ hex: '4d 5' }", want: "invalid hex sample"},
+		{test: "{ hex: '4d 5a', offsets: { pe: [0] } }", want: "offsets of unknown pattern 'pe'"},
+	} {
+		t.Run("fails with malformed test "+tCase.test, func(t *testing.T) {
+			dir := writeSigFiles(t, map[string]string{
+				"sigs.yaml": "name: mz\npatterns:\n  mz: '{ 4d 5a }'\ncondition: mz\ntests:\n  - " + tCase.test + "\n",
+			})
+
+			_, _, err := LoadTests(dir)
+			assert.ErrorContains(t, err, tCase.want)
+
+			issues, err := Validate(dir)
+			assert.Nil(t, err)
+			var errIssues []Issue
+			for _, issue := range issues {
+				if issue.Severity == SeverityError {
+					errIssues = append(errIssues, issue)
+				}
+			}
+			if assert.Len(t, errIssues, 1) {
+				assert.Equal(t, 6, errIssues[0].Line)
+				assert.Contains(t, errIssues[0].Message, tCase.want)
+			}
+		})
+	}
+}
+
+func writeSample(t *testing.T, dir, content string) {
+	samplesDir := filepath.Join(dir, "samples")
+	if err := os.MkdirAll(samplesDir, 0o755); err != nil {
+		t.Fatalf("Can't create test samples directory: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(samplesDir, "packed.bin"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Can't write test sample: %s", err)
+	}
+}
@@ -0,0 +1,483 @@
+package io
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/angelsolaorbaiceta/binmat/signature"
+	"gopkg.in/yaml.v3"
+)
+
+// minPatternLength is the minimum number of bytes a pattern should have not to
+// be reported as too short. Shorter patterns match almost any binary file.
+const minPatternLength = 3
+
+var yamlErrLineRe = regexp.MustCompile(`line (\d+):`)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// An Issue is a problem found when validating a signature file.
+// Errors prevent the signature from being loaded, whereas warnings point at
+// signatures that load, but are likely to be wrong.
+type Issue struct {
+	File     string
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", i.File, i.Line, i.Column, i.Severity, i.Message)
+}
+
+// Validate reads every signature file found at the given paths, the same way
+// LoadSignatures does, and returns all the issues found in them, sorted by file
+// and position. Unlike LoadSignatures, it doesn't stop at the first error.
+//
+// The returned error is only non nil when the paths can't be read.
+func Validate(paths ...string) ([]Issue, error) {
+	sigFilePaths, err := findSigFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		issues    []Issue
+		resolver  = newIncludeResolver()
+		validSigs []validatedSig
+		allSigs   []Signature
+		// sigNames maps each signature name to the position where it was first defined.
+		sigNames = make(map[string]Issue)
+	)
+
+	// Conditions can reference signatures in any of the files, so their names are
+	// needed beforehand. Errors reading the files are reported when validating them.
+	for _, filePath := range sigFilePaths {
+		sigs, _ := readSigFile(filePath)
+		allSigs = append(allSigs, sigs...)
+	}
+	refs := referenceableNames(allSigs)
+
+	for _, filePath := range sigFilePaths {
+		v := validator{filePath: filePath, resolver: resolver, refs: refs}
+		v.validateFile()
+
+		for _, sig := range v.sigs {
+			if sig.made != nil {
+				validSigs = append(validSigs, sig)
+			}
+
+			if first, ok := sigNames[sig.name]; ok {
+				v.warnAt(
+					sig.nameNode,
+					"duplicate signature name '%s', also defined in %s:%d:%d",
+					sig.name, first.File, first.Line, first.Column,
+				)
+			} else {
+				sigNames[sig.name] = v.issueAt(sig.nameNode, SeverityWarning, "")
+			}
+		}
+
+		issues = append(issues, v.issues...)
+	}
+
+	issues = append(issues, linkIssues(validSigs)...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return issues, nil
+}
+
+// A validator collects the issues found in a single signature file.
+type validator struct {
+	filePath string
+	issues   []Issue
+	resolver *includeResolver
+	// refs are the names of the signatures conditions can reference.
+	refs []string
+	// sigs are the named signatures decoded from the file.
+	sigs []validatedSig
+}
+
+// A validatedSig is a named signature found by the validator, and the node
+// where its name is defined.
+type validatedSig struct {
+	name     string
+	file     string
+	nameNode *yaml.Node
+	// made is the domain signature, if it could be created.
+	made *signature.Signature
+}
+
+// linkIssues links the signatures (see signature.Link), and returns an issue
+// for the offending reference if they can't be linked.
+func linkIssues(validSigs []validatedSig) []Issue {
+	sigs := make([]signature.Signature, len(validSigs))
+	for i, sig := range validSigs {
+		sigs[i] = *sig.made
+	}
+
+	_, err := signature.Link(sigs)
+
+	var refErr signature.ErrRef
+	if !errors.As(err, &refErr) {
+		return nil
+	}
+
+	for _, sig := range validSigs {
+		if sig.name == refErr.Signature {
+			return []Issue{{
+				File:     sig.file,
+				Line:     sig.nameNode.Line,
+				Column:   sig.nameNode.Column,
+				Severity: SeverityError,
+				Message:  err.Error(),
+			}}
+		}
+	}
+
+	return nil
+}
+
+func (v *validator) validateFile() {
+	data, err := os.ReadFile(v.filePath)
+	if err != nil {
+		v.issues = append(v.issues, Issue{
+			File:     v.filePath,
+			Severity: SeverityError,
+			Message:  err.Error(),
+		})
+		return
+	}
+
+	// Json is valid yaml, so json files are validated as yaml, which keeps the
+	// position of every node, once they're known to be valid json.
+	if isJsonFile(v.filePath) {
+		var patternErr ErrPatternValue
+		if _, err := ReadAllFromJson(bytes.NewReader(data)); err != nil && !errors.As(err, &patternErr) {
+			v.errorAtLine(err.Error())
+			return
+		}
+	}
+
+	var (
+		decoder = yaml.NewDecoder(bytes.NewReader(data))
+		sigs    int
+	)
+	for docIdx := 1; ; docIdx++ {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			// Files with no signatures load fine, but they're likely a mistake.
+			switch {
+			case docIdx == 1:
+				v.errorAt(nil, "the file is empty")
+			case sigs == 0:
+				v.warnAt(nil, "the file has no signatures")
+			}
+			return
+		}
+		if err != nil {
+			// The decoder can't recover from syntax errors: stop at the first one.
+			v.yamlError(err, docIdx)
+			return
+		}
+
+		if isEmptyDocument(&doc) {
+			continue
+		}
+
+		for _, root := range documentSigNodes(&doc) {
+			v.validateSignature(root, docIdx)
+			sigs++
+		}
+	}
+}
+
+func (v *validator) validateSignature(root *yaml.Node, docIdx int) {
+	var (
+		orRoot = func(node *yaml.Node) *yaml.Node {
+			if node == nil {
+				return root
+			}
+			return node
+		}
+		nameNode = orRoot(valueNode(root, "name"))
+		ioSig    Signature
+	)
+
+	if err := root.Decode(&ioSig); err != nil {
+		var patternErr ErrPatternValue
+		if errors.As(err, &patternErr) {
+			v.issues = append(v.issues, Issue{
+				File:     v.filePath,
+				Line:     patternErr.Line,
+				Column:   patternErr.Column,
+				Severity: SeverityError,
+				Message:  patternErr.message(),
+			})
+			return
+		}
+
+		v.yamlError(err, docIdx)
+		return
+	}
+
+	var validSig *validatedSig
+	if ioSig.Name != "" && !ioSig.Library {
+		v.sigs = append(v.sigs, validatedSig{name: ioSig.Name, file: v.filePath, nameNode: nameNode})
+		validSig = &v.sigs[len(v.sigs)-1]
+	}
+
+	var (
+		patternsNode = valueNode(root, "patterns")
+		patterns     = make(map[string]*signature.SignaturePattern)
+		names        = sortedPatternNames(ioSig.Patterns)
+		hasErrors    = false
+	)
+
+	for _, name := range names {
+		pattern, err := ioSig.patternToDomain(name)
+		if err != nil {
+			v.errorAt(valueNode(patternsNode, name), "pattern '%s': %s", name, err)
+			hasErrors = true
+			continue
+		}
+
+		patterns[name] = pattern
+
+		if pattern.Length() < minPatternLength {
+			v.warnAt(
+				orRoot(keyNode(patternsNode, name)),
+				"pattern '%s' is shorter than %d bytes", name, minPatternLength,
+			)
+		}
+	}
+
+	for i, name := range names {
+		for _, other := range names[:i] {
+			a, b := patterns[other], patterns[name]
+			if a != nil && b != nil && a.Equal(b) {
+				v.warnAt(
+					orRoot(keyNode(patternsNode, name)),
+					"pattern '%s' has the same bytes as pattern '%s'", name, other,
+				)
+				break
+			}
+		}
+	}
+
+	if ioSig.Library && len(ioSig.Tests) > 0 {
+		v.errorAt(orRoot(keyNode(root, "tests")), "libraries can't have tests")
+	}
+
+	// Libraries don't have a condition to check the patterns against.
+	if hasErrors || ioSig.Library {
+		return
+	}
+
+	if len(ioSig.Include) > 0 {
+		ioSig.Source = v.filePath
+		resolved, err := v.resolver.resolve(ioSig)
+		if err != nil {
+			v.errorAt(orRoot(valueNode(root, "include")), "%s", err)
+			return
+		}
+
+		for _, name := range sortedPatternNames(resolved.Patterns) {
+			if _, ok := patterns[name]; ok {
+				continue
+			}
+
+			pattern, err := resolved.patternToDomain(name)
+			if err != nil {
+				v.errorAt(orRoot(valueNode(root, "include")), "included pattern '%s': %s", name, err)
+				return
+			}
+			patterns[name] = pattern
+		}
+	}
+
+	v.validateTests(root, ioSig, patterns)
+
+	var (
+		conditionNode = valueNode(root, "condition")
+		sig, err      = signature.MakeWithRefs(ioSig.Name, ioSig.Description, patterns, ioSig.Condition, v.refs)
+		sigErr        signature.ErrSignature
+	)
+
+	if err == nil && validSig != nil {
+		validSig.made = &sig
+	}
+
+	if err == nil {
+		for _, warning := range sig.Warnings() {
+			if warning.Pattern == "" {
+				v.warnAt(orRoot(conditionNode), "%s", warning.Reason)
+			} else {
+				v.warnAt(orRoot(keyNode(patternsNode, warning.Pattern)), "pattern '%s': %s", warning.Pattern, warning.Reason)
+			}
+		}
+	}
+
+	if errors.As(err, &sigErr) {
+		switch sigErr.Reason() {
+		case signature.ErrSigEmptyName:
+			v.errorAt(nameNode, "%s", err)
+		case signature.ErrSigEmptyPatterns:
+			v.errorAt(orRoot(patternsNode), "%s", err)
+		default:
+			v.errorAt(orRoot(conditionNode), "%s", err)
+		}
+	} else if err != nil {
+		v.errorAt(root, "%s", err)
+	}
+}
+
+// validateTests adds an error issue for each test of the signature whose sample
+// can't be read, or that expects offsets for patterns the signature doesn't have.
+func (v *validator) validateTests(root *yaml.Node, ioSig Signature, patterns map[string]*signature.SignaturePattern) {
+	testsNode := valueNode(root, "tests")
+	if testsNode == nil || testsNode.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for i, test := range ioSig.Tests {
+		var (
+			testNode = testsNode.Content[i]
+			name     = test.testName(i)
+		)
+
+		if _, err := test.sample(v.filePath); err != nil {
+			v.errorAt(testNode, "test '%s': %s", name, err)
+		}
+
+		offsetsNode := valueNode(testNode, "offsets")
+		for _, pattern := range sortedOffsetNames(test.Offsets) {
+			if _, ok := patterns[pattern]; !ok {
+				v.errorAt(keyNode(offsetsNode, pattern), "test '%s': offsets of unknown pattern '%s'", name, pattern)
+			}
+		}
+	}
+}
+
+// yamlError adds an error issue for each of the problems reported by the yaml
+// decoder in the given document, extracting the line number from the messages.
+func (v *validator) yamlError(err error, docIdx int) {
+	messages := []string{err.Error()}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	for _, message := range messages {
+		v.errorAtLine(fmt.Sprintf("document %d: %s", docIdx, message))
+	}
+}
+
+// errorAtLine adds an error issue with the message, extracting the line number
+// from it, as found in yaml and json decoding errors.
+func (v *validator) errorAtLine(message string) {
+	issue := Issue{
+		File:     v.filePath,
+		Severity: SeverityError,
+		Message:  message,
+	}
+	if match := yamlErrLineRe.FindStringSubmatch(message); match != nil {
+		issue.Line, _ = strconv.Atoi(match[1])
+	}
+
+	v.issues = append(v.issues, issue)
+}
+
+func (v *validator) issueAt(node *yaml.Node, severity Severity, message string) Issue {
+	issue := Issue{File: v.filePath, Severity: severity, Message: message}
+	if node != nil {
+		issue.Line = node.Line
+		issue.Column = node.Column
+	}
+
+	return issue
+}
+
+func (v *validator) errorAt(node *yaml.Node, format string, args ...any) {
+	v.issues = append(v.issues, v.issueAt(node, SeverityError, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) warnAt(node *yaml.Node, format string, args ...any) {
+	v.issues = append(v.issues, v.issueAt(node, SeverityWarning, fmt.Sprintf(format, args...)))
+}
+
+// keyNode returns the key node for the given key in a mapping node, or the
+// mapping node itself if the key isn't found.
+func keyNode(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil {
+		return nil
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i]
+		}
+	}
+
+	return mapping
+}
+
+// valueNode returns the value node for the given key in a mapping node, or nil
+// if the key isn't found.
+func valueNode(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func sortedPatternNames(patterns map[string]string) []string {
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func sortedOffsetNames(offsets map[string][]int) []string {
+	names := make([]string, 0, len(offsets))
+	for name := range offsets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
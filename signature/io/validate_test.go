@@ -0,0 +1,140 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeSigFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Can't write test file: %s", err)
+		}
+	}
+
+	return dir
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("valid signature has no issues", func(t *testing.T) {
+		dir := writeSigFiles(t, map[string]string{
+			"ok.yaml": "name: ok\npatterns:\n  a: '{ 01 02 03 }'\ncondition: a\n",
+		})
+		issues, err := Validate(dir)
+
+		assert.Nil(t, err)
+		assert.Empty(t, issues)
+	})
+
+	t.Run("empty patterns are errors", func(t *testing.T) {
+		dir := writeSigFiles(t, map[string]string{
+			"empty.yaml": "name: empty\npatterns:\n  a: '{ }'\n  b: 'abc'\n  c: ''\ncondition: a OR b OR c\n",
+		})
+		issues, err := Validate(dir)
+
+		assert.Nil(t, err)
+		if assert.Len(t, issues, 2) {
+			assert.Equal(t, Issue{
+				File:     issues[0].File,
+				Line:     3,
+				Column:   6,
+				Severity: SeverityError,
+				Message:  "pattern 'a': the pattern is empty",
+			}, issues[0])
+			assert.Equal(t, 5, issues[1].Line)
+			assert.Equal(t, "pattern 'c': the pattern is empty", issues[1].Message)
+		}
+	})
+
+	t.Run("reports every problem with its position", func(t *testing.T) {
+		dir := writeSigFiles(t, map[string]string{
+			"a.yaml": "name: dup\npatterns:\n  a: '{ 01 02 b 03 }'\n  b: '{ 01 02 }'\ncondition: a\n",
+			"b.yaml": "name: dup\npatterns:\n  a: '{ 01 02 03 }'\n  b: '{ 01 02 03 }'\ncondition: a AND b\n",
+			"c.yaml": "name: broken\npatterns:\n  a: { 74 fc }\ncondition: a\n",
+			"d.yaml": "name: cond\npatterns:\n  a: '{ 01 02 03 }'\ncondition: a AND c\n",
+			"e.yaml": "name: never\npatterns:\n  a: '{ 01 02 03 }'\n  b: '{ 04 05 06 }'\ncondition: a AND NOT a\n",
+		})
+		issues, err := Validate(dir)
+
+		assert.Nil(t, err)
+
+		want := []Issue{
+			{File: "a.yaml", Line: 3, Column: 6, Severity: SeverityError},
+			{File: "a.yaml", Line: 4, Column: 3, Severity: SeverityWarning},
+			{File: "b.yaml", Line: 1, Column: 7, Severity: SeverityWarning},
+			{File: "b.yaml", Line: 4, Column: 3, Severity: SeverityWarning},
+			{File: "c.yaml", Line: 3, Column: 6, Severity: SeverityError},
+			{File: "d.yaml", Line: 4, Column: 12, Severity: SeverityError},
+			{File: "e.yaml", Line: 4, Column: 3, Severity: SeverityWarning},
+			{File: "e.yaml", Line: 5, Column: 12, Severity: SeverityWarning},
+		}
+
+		if !assert.Len(t, issues, len(want)) {
+			return
+		}
+		for i, issue := range issues {
+			assert.Equal(t, want[i].File, filepath.Base(issue.File))
+			assert.Equal(t, want[i].Line, issue.Line, issue.String())
+			assert.Equal(t, want[i].Column, issue.Column, issue.String())
+			assert.Equal(t, want[i].Severity, issue.Severity, issue.String())
+		}
+	})
+
+	t.Run("validates every signature in multi-document files", func(t *testing.T) {
+		dir := writeSigFiles(t, map[string]string{
+			"multi.yaml": `name: one
+patterns:
+  a: '{ 01 02 03 }'
+condition: a
+---
+- name: two
+  patterns:
+    a: '{ 01 02 03 }'
+  condition: a AND b
+- name: one
+  patterns:
+    a: '{ 01 02 03 }'
+  condition: a
+`,
+		})
+		issues, err := Validate(dir)
+
+		assert.Nil(t, err)
+		if assert.Len(t, issues, 2) {
+			assert.Equal(t, 9, issues[0].Line)
+			assert.Equal(t, SeverityError, issues[0].Severity)
+			assert.Equal(t, 10, issues[1].Line)
+			assert.Contains(t, issues[1].Message, "duplicate signature name 'one'")
+		}
+	})
+
+	t.Run("reports files without signatures", func(t *testing.T) {
+		dir := writeSigFiles(t, map[string]string{
+			"a_empty.yaml":     "",
+			"b_comments.yaml":  "# nothing here yet\n",
+			"c_documents.yaml": "---\n---\n",
+			"d_list.json":      "[]",
+		})
+		issues, err := Validate(dir)
+
+		assert.Nil(t, err)
+		if assert.Len(t, issues, 4) {
+			for _, issue := range issues[:2] {
+				assert.Equal(t, Issue{File: issue.File, Severity: SeverityError, Message: "the file is empty"}, issue)
+			}
+			for _, issue := range issues[2:] {
+				assert.Equal(t, Issue{File: issue.File, Severity: SeverityWarning, Message: "the file has no signatures"}, issue)
+			}
+		}
+	})
+
+	t.Run("fails if the directory can't be read", func(t *testing.T) {
+		_, err := Validate(filepath.Join(t.TempDir(), "missing"))
+
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,258 @@
+package signature
+
+import (
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	"github.com/angelsolaorbaiceta/binmat/bexpr"
+)
+
+var (
+	machoCPUs = map[macho.Cpu]string{
+		macho.Cpu386:   "i386",
+		macho.CpuAmd64: "x86_64",
+		macho.CpuArm:   "arm",
+		macho.CpuArm64: "arm64",
+		macho.CpuPpc:   "ppc",
+		macho.CpuPpc64: "ppc64",
+	}
+
+	machoTypes = map[macho.Type]string{
+		macho.TypeObj:    "object",
+		macho.TypeExec:   "execute",
+		3:                "fvmlib",
+		4:                "core",
+		5:                "preload",
+		macho.TypeDylib:  "dylib",
+		7:                "dylinker",
+		macho.TypeBundle: "bundle",
+		9:                "dylib_stub",
+		10:               "dsym",
+		11:               "kext_bundle",
+	}
+
+	// machoLoadCommands are the load commands that can be checked by name: the
+	// name of their LC_ constant, without the prefix, in lowercase.
+	machoLoadCommands = map[string]uint32{
+		"segment":             0x1,
+		"symtab":              0x2,
+		"thread":              0x4,
+		"unixthread":          0x5,
+		"dysymtab":            0xb,
+		"load_dylib":          0xc,
+		"id_dylib":            0xd,
+		"load_dylinker":       0xe,
+		"load_weak_dylib":     0x80000018,
+		"segment_64":          0x19,
+		"uuid":                0x1b,
+		"rpath":               0x8000001c,
+		"code_signature":      0x1d,
+		"reexport_dylib":      0x8000001f,
+		"encryption_info":     0x21,
+		"dyld_info":           0x22,
+		"dyld_info_only":      0x80000022,
+		"version_min_macosx":  0x24,
+		"function_starts":     0x26,
+		"main":                0x80000028,
+		"data_in_code":        0x29,
+		"source_version":      0x2a,
+		"encryption_info_64":  0x2c,
+		"build_version":       0x32,
+		"dyld_exports_trie":   0x80000033,
+		"dyld_chained_fixups": 0x80000034,
+	}
+)
+
+// A machoFile is the Mach-O file parsed from the scanned data: either a thin
+// file, or the slices of a fat (universal) file.
+type machoFile struct {
+	file *macho.File
+	// slices are the architecture slices of fat files, which are also checked
+	// on their own.
+	slices []machoSlice
+	// imports are the symbols thin files import.
+	imports []string
+}
+
+// A machoSlice is the slice of a fat Mach-O file for an architecture.
+type machoSlice struct {
+	arch   string
+	offset int64
+	data   []byte
+}
+
+// parseMachO parses the data as a thin or fat Mach-O file, returning nil if it
+// isn't one.
+func parseMachO(data []byte) (parsed any) {
+	// Malformed files shouldn't stop the scan, they're just not Mach-O files.
+	defer func() {
+		if recover() != nil {
+			parsed = (*machoFile)(nil)
+		}
+	}()
+
+	if fat, err := macho.NewFatFile(bytes.NewReader(data)); err == nil {
+		parsedFile := &machoFile{}
+		for _, arch := range fat.Arches {
+			end := int64(arch.Offset) + int64(arch.Size)
+			if end > int64(len(data)) {
+				continue
+			}
+
+			parsedFile.slices = append(parsedFile.slices, machoSlice{
+				arch:   machoCPUName(arch.Cpu),
+				offset: int64(arch.Offset),
+				data:   data[arch.Offset:end],
+			})
+		}
+		return parsedFile
+	}
+
+	file, err := macho.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return (*machoFile)(nil)
+	}
+
+	// Files without a symbol table import nothing.
+	imports, _ := file.ImportedSymbols()
+	return &machoFile{file: file, imports: imports}
+}
+
+func machoCPUName(cpu macho.Cpu) string {
+	if name, ok := machoCPUs[cpu]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("0x%x", uint32(cpu))
+}
+
+// hasLoadCommand returns true if the file has a load command of the given type.
+func (m *machoFile) hasLoadCommand(cmd uint32) bool {
+	return slices.ContainsFunc(m.file.Loads, func(load macho.Load) bool {
+		raw := load.Raw()
+		return len(raw) >= 4 && m.file.ByteOrder.Uint32(raw) == cmd
+	})
+}
+
+// section returns the section of the segment with the given names, or nil if
+// the file doesn't have it.
+func (m *machoFile) section(segment, name string) *macho.Section {
+	for _, section := range m.file.Sections {
+		if section.Seg == segment && section.Name == name {
+			return section
+		}
+	}
+
+	return nil
+}
+
+// machoValue returns the file value of a thin Mach-O file, undefined for files
+// that aren't Mach-O files. Fat files are checked slice by slice, so their
+// values are undefined as well, except for macho.is_fat.
+func machoValue(kind valueKind, args []valueKind, get func(f *machoFile, args []valueArg) (any, bool)) fileValue {
+	return fileValue{
+		kind: kind,
+		args: args,
+		get: func(f *scannedFile, args []valueArg) (any, bool) {
+			parsed := f.module("macho", parseMachO).(*machoFile)
+			if parsed == nil || parsed.file == nil {
+				return nil, false
+			}
+			return get(parsed, args)
+		},
+	}
+}
+
+func machoValues() map[string]fileValue {
+	hasLoadCommand := machoValue(kindBool, []valueKind{kindString}, func(f *machoFile, args []valueArg) (any, bool) {
+		return f.hasLoadCommand(machoLoadCommands[args[0].Str]), true
+	})
+	hasLoadCommand.checkArgs = func(args []bexpr.Operand) error {
+		if _, ok := machoLoadCommands[args[0].Str]; !ok {
+			return fmt.Errorf("unknown load command %s", args[0])
+		}
+		return nil
+	}
+
+	return map[string]fileValue{
+		"macho.is_fat": {
+			kind: kindBool,
+			get: func(f *scannedFile, _ []valueArg) (any, bool) {
+				parsed := f.module("macho", parseMachO).(*machoFile)
+				if parsed == nil {
+					return nil, false
+				}
+				return parsed.file == nil, true
+			},
+		},
+		"macho.cpu": machoValue(kindString, nil, func(f *machoFile, _ []valueArg) (any, bool) {
+			return machoCPUName(f.file.Cpu), true
+		}),
+		"macho.type": machoValue(kindString, nil, func(f *machoFile, _ []valueArg) (any, bool) {
+			if name, ok := machoTypes[f.file.Type]; ok {
+				return name, true
+			}
+			return fmt.Sprintf("0x%x", uint32(f.file.Type)), true
+		}),
+		"macho.number_of_load_commands": machoValue(kindInt, nil, func(f *machoFile, _ []valueArg) (any, bool) {
+			return int64(len(f.file.Loads)), true
+		}),
+		"macho.has_load_command": hasLoadCommand,
+		"macho.number_of_segments": machoValue(kindInt, nil, func(f *machoFile, _ []valueArg) (any, bool) {
+			var segments int64
+			for _, load := range f.file.Loads {
+				if _, ok := load.(*macho.Segment); ok {
+					segments++
+				}
+			}
+			return segments, true
+		}),
+		"macho.has_segment": machoValue(kindBool, []valueKind{kindString}, func(f *machoFile, args []valueArg) (any, bool) {
+			return f.file.Segment(args[0].Str) != nil, true
+		}),
+		// The size of a segment the file doesn't have is undefined.
+		"macho.segment_size": machoValue(kindInt, []valueKind{kindString}, func(f *machoFile, args []valueArg) (any, bool) {
+			segment := f.file.Segment(args[0].Str)
+			if segment == nil {
+				return nil, false
+			}
+			return int64(segment.Filesz), true
+		}),
+		"macho.has_section": machoValue(kindBool, []valueKind{kindString, kindString}, func(f *machoFile, args []valueArg) (any, bool) {
+			return f.section(args[0].Str, args[1].Str) != nil, true
+		}),
+		"macho.section_size": machoValue(kindInt, []valueKind{kindString, kindString}, func(f *machoFile, args []valueArg) (any, bool) {
+			section := f.section(args[0].Str, args[1].Str)
+			if section == nil {
+				return nil, false
+			}
+			return int64(section.Size), true
+		}),
+		"macho.links": machoValue(kindBool, []valueKind{kindString}, func(f *machoFile, args []valueArg) (any, bool) {
+			libraries, _ := f.file.ImportedLibraries()
+			return slices.Contains(libraries, args[0].Str), true
+		}),
+		"macho.imports": machoValue(kindBool, []valueKind{kindString}, func(f *machoFile, args []valueArg) (any, bool) {
+			return slices.Contains(f.imports, args[0].Str), true
+		}),
+	}
+}
+
+// machoSlices returns the architecture slices of the file, if it's a fat
+// Mach-O file.
+func machoSlices(f *scannedFile) []machoSlice {
+	// Only fat files start with the fat magic, which avoids parsing the rest.
+	if len(f.data) < 4 || binary.BigEndian.Uint32(f.data) != macho.MagicFat {
+		return nil
+	}
+
+	parsed := f.module("macho", parseMachO).(*machoFile)
+	if parsed == nil {
+		return nil
+	}
+
+	return parsed.slices
+}
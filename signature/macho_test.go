@@ -0,0 +1,132 @@
+package signature
+
+import (
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDylib = "/usr/lib/libSystem.B.dylib"
+
+// makeTestMachO returns a 64 bit executable for the CPU, with a __TEXT segment
+// whose __text section holds "UPX!" at offset 0x200, and which links
+// testDylib and has a UUID.
+func makeTestMachO(cpu macho.Cpu) []byte {
+	var (
+		buf   bytes.Buffer
+		write = func(data any) { binary.Write(&buf, binary.LittleEndian, data) }
+		pad   = func(offset int) { write(make([]byte, offset-buf.Len())) }
+
+		segment = macho.Segment64{
+			Cmd:    macho.LoadCmdSegment64,
+			Len:    72 + 80,
+			Addr:   0x100000000,
+			Memsz:  0x400,
+			Filesz: 0x400,
+			Nsect:  1,
+		}
+		section = macho.Section64{Addr: 0x100000200, Size: 0x10, Offset: 0x200}
+		dylib   = macho.DylibCmd{Cmd: macho.LoadCmdDylib, Len: 56, Name: 24}
+	)
+
+	copy(segment.Name[:], "__TEXT")
+	copy(section.Name[:], "__text")
+	copy(section.Seg[:], "__TEXT")
+
+	write(macho.FileHeader{
+		Magic: macho.Magic64,
+		Cpu:   cpu,
+		Type:  macho.TypeExec,
+		Ncmd:  3,
+		Cmdsz: segment.Len + dylib.Len + 24,
+	})
+	write(uint32(0))
+	write(segment)
+	write(section)
+	write(dylib)
+	write([]byte(testDylib))
+	write(make([]byte, 32-len(testDylib)))
+	write([]uint32{machoLoadCommands["uuid"], 24})
+	write(make([]byte, 16))
+
+	pad(0x200)
+	write([]byte("UPX!"))
+	pad(0x400)
+
+	return buf.Bytes()
+}
+
+// makeTestFatMachO returns a fat file with x86_64 and arm64 slices, as returned
+// by makeTestMachO, at offsets 0x1000 and 0x2000.
+func makeTestFatMachO() []byte {
+	var (
+		buf   bytes.Buffer
+		write = func(data any) { binary.Write(&buf, binary.BigEndian, data) }
+		pad   = func(offset int) { write(make([]byte, offset-buf.Len())) }
+	)
+
+	write([]uint32{macho.MagicFat, 2})
+	write(macho.FatArchHeader{Cpu: macho.CpuAmd64, Offset: 0x1000, Size: 0x400, Align: 12})
+	write(macho.FatArchHeader{Cpu: macho.CpuArm64, Offset: 0x2000, Size: 0x400, Align: 12})
+
+	pad(0x1000)
+	write(makeTestMachO(macho.CpuAmd64))
+	pad(0x2000)
+	write(makeTestMachO(macho.CpuArm64))
+
+	return buf.Bytes()
+}
+
+func TestMachOValues(t *testing.T) {
+	testValues(t, makeTestMachO(macho.CpuAmd64), []valueTestCase{
+		{condition: `upx AND macho.cpu == "x86_64" AND macho.has_section("__TEXT", "__text")`, want: true},
+		{condition: `macho.type == "execute"`, want: true},
+		{condition: `NOT macho.is_fat`, want: true, wantOther: true},
+		{condition: `macho.number_of_load_commands == 3`, want: true},
+		{condition: `macho.has_load_command("uuid") AND NOT macho.has_load_command("code_signature")`, want: true},
+		{condition: `macho.number_of_segments == 1`, want: true},
+		{condition: `macho.has_segment("__TEXT") AND NOT macho.has_segment("__DATA")`, want: true},
+		{condition: `macho.segment_size("__TEXT") == 0x400`, want: true},
+		{condition: `macho.section_size("__TEXT", "__text") == 16`, want: true},
+		{condition: `macho.section_size("__DATA", "__data") >= 0`, want: false},
+		{condition: fmt.Sprintf(`macho.links("%s")`, testDylib), want: true},
+		{condition: `macho.imports("_ptrace")`, want: false},
+		{condition: `upx AND NOT macho.has_segment("__TEXT")`, want: false, wantOther: true},
+	})
+}
+
+func TestCheckFatMachO(t *testing.T) {
+	var (
+		fatPath   = "fat"
+		upx       = map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}
+		armUPX, _ = Make("arm_upx", "", upx, `upx AND macho.cpu == "arm64"`)
+		universal = map[string]*SignaturePattern{"magic": MakePattern([]byte{0xca, 0xfe, 0xba, 0xbe})}
+		isFat, _  = Make("fat", "", universal, "magic AND macho.is_fat")
+		matches   = Signatures{armUPX, isFat}.CheckData(fatPath, makeTestFatMachO(), CheckOptions{})
+	)
+
+	if !assert.Len(t, matches, 6) {
+		return
+	}
+
+	t.Run("the whole file is checked first", func(t *testing.T) {
+		assert.Equal(t, SigMatchMeta{FilePath: fatPath}, matches[0].Meta)
+		assert.False(t, matches[0].IsMatch)
+		assert.True(t, matches[1].IsMatch)
+	})
+
+	t.Run("each slice is checked on its own", func(t *testing.T) {
+		assert.Equal(t, SigMatchMeta{FilePath: fatPath, Arch: "x86_64", Offset: 0x1000}, matches[2].Meta)
+		assert.False(t, matches[2].IsMatch)
+		assert.False(t, matches[3].IsMatch)
+
+		assert.Equal(t, SigMatchMeta{FilePath: fatPath, Arch: "arm64", Offset: 0x2000}, matches[4].Meta)
+		assert.True(t, matches[4].IsMatch)
+		assert.Equal(t, matchOffsets{0x200}, matches[4].Offsets["upx"])
+		assert.False(t, matches[5].IsMatch)
+	})
+}
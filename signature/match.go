@@ -1,12 +1,28 @@
 package signature
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 )
 
 type SigMatchMeta struct {
 	FilePath string
+	// Arch is the architecture of the fat Mach-O file slice the matches are in,
+	// or empty if they're in the whole file.
+	Arch string
+	// Offset is where the slice starts in the file. Match offsets are relative
+	// to it.
+	Offset int64
+	// Hashes are the hashes of the file, if they were computed.
+	Hashes Hashes
+	// Entropy is the entropy of the file, in bits per byte, if it was computed.
+	Entropy *float64
+	// Region is the region of a process' memory the matches are in, if they're
+	// in one rather than in a file. Match offsets are virtual addresses.
+	Region *MemoryRegion
 }
 
 // A SigMatch is the result of attempting to match a file against a signature.
@@ -24,8 +40,28 @@ func (sm *SigMatch) Len() int {
 func (sm *SigMatch) Write(w io.StringWriter) {
 	w.WriteString("================================================================================\n")
 	w.WriteString(fmt.Sprintf("File:         %s\n", sm.Meta.FilePath))
+	if sm.Meta.Arch != "" {
+		w.WriteString(fmt.Sprintf("Slice:        %s at offset %d\n", sm.Meta.Arch, sm.Meta.Offset))
+	}
+	if sm.Meta.Region != nil {
+		w.WriteString(fmt.Sprintf("Region:       %s\n", sm.Meta.Region))
+	}
+	if !sm.Meta.Hashes.IsZero() {
+		w.WriteString(fmt.Sprintf("MD5:          %s\n", sm.Meta.Hashes.MD5))
+		w.WriteString(fmt.Sprintf("SHA-1:        %s\n", sm.Meta.Hashes.SHA1))
+		w.WriteString(fmt.Sprintf("SHA-256:      %s\n", sm.Meta.Hashes.SHA256))
+	}
+	if sm.Meta.Entropy != nil {
+		w.WriteString(fmt.Sprintf("Entropy:      %.4f\n", *sm.Meta.Entropy))
+	}
 	w.WriteString(fmt.Sprintf("Signature:    %s\n", sm.Signature.Name))
 	w.WriteString(fmt.Sprintf("Description:  %s\n", sm.Signature.Description))
+	if len(sm.Signature.Tags) > 0 {
+		w.WriteString(fmt.Sprintf("Tags:         %s\n", strings.Join(sm.Signature.Tags, ", ")))
+	}
+	for _, key := range sortedKeys(sm.Signature.Meta) {
+		w.WriteString(fmt.Sprintf("%-14s%s\n", key+":", sm.Signature.Meta[key]))
+	}
 	w.WriteString("================================================================================\n")
 
 	if !sm.IsMatch {
@@ -36,3 +72,95 @@ func (sm *SigMatch) Write(w io.StringWriter) {
 	w.WriteString(fmt.Sprintf("%d Matches found at offsets: \n", sm.Len()))
 	w.WriteString("\n")
 }
+
+// A fileReport is the JSON report of the signatures that matched a file, or a
+// slice of a fat Mach-O file.
+type fileReport struct {
+	File    string       `json:"file"`
+	Arch    string       `json:"arch,omitempty"`
+	Offset  int64        `json:"offset,omitempty"`
+	Region  *regionEntry `json:"region,omitempty"`
+	MD5     string       `json:"md5,omitempty"`
+	SHA1    string       `json:"sha1,omitempty"`
+	SHA256  string       `json:"sha256,omitempty"`
+	Entropy *float64     `json:"entropy,omitempty"`
+	Matches []matchEntry `json:"matches"`
+}
+
+// A regionEntry is the JSON report of the region of a process' memory that was
+// checked.
+type regionEntry struct {
+	PID   int    `json:"pid"`
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	Perms string `json:"perms"`
+	Path  string `json:"path,omitempty"`
+}
+
+// A matchEntry is the JSON report of a signature that matched a file.
+type matchEntry struct {
+	Signature   string                  `json:"signature"`
+	Description string                  `json:"description"`
+	Tags        []string                `json:"tags,omitempty"`
+	Meta        map[string]string       `json:"meta,omitempty"`
+	Offsets     map[string]matchOffsets `json:"offsets"`
+}
+
+// WriteJSON writes the matches as a JSON list with an entry for each checked
+// file, each slice of fat Mach-O files and each region of a process' memory, in
+// order, with the file's meta and the signatures that matched it.
+func WriteJSON(w io.Writer, matches []SigMatch) error {
+	reports := []fileReport{}
+
+	for i, match := range matches {
+		if i == 0 || match.Meta.FilePath != matches[i-1].Meta.FilePath || match.Meta.Arch != matches[i-1].Meta.Arch ||
+			match.Meta.Region != matches[i-1].Meta.Region {
+			var region *regionEntry
+			if r := match.Meta.Region; r != nil {
+				region = &regionEntry{PID: r.PID, Start: r.Start, End: r.End, Perms: r.Perms, Path: r.Path}
+			}
+
+			reports = append(reports, fileReport{
+				File:    match.Meta.FilePath,
+				Arch:    match.Meta.Arch,
+				Offset:  match.Meta.Offset,
+				Region:  region,
+				MD5:     match.Meta.Hashes.MD5,
+				SHA1:    match.Meta.Hashes.SHA1,
+				SHA256:  match.Meta.Hashes.SHA256,
+				Entropy: match.Meta.Entropy,
+				Matches: []matchEntry{},
+			})
+		}
+
+		if !match.IsMatch {
+			continue
+		}
+
+		report := &reports[len(reports)-1]
+		report.Matches = append(report.Matches, matchEntry{
+			Signature:   match.Signature.Name,
+			Description: match.Signature.Description,
+			Tags:        match.Signature.Tags,
+			Meta:        match.Signature.Meta,
+			Offsets:     match.Offsets,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	// Paths, like that of the standard input, aren't HTML.
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(reports)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -1,6 +1,7 @@
 package signature
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,6 +37,34 @@ func TestMatchPatternWithoutMask(t *testing.T) {
 		assert.Equal(t, matchOffsets{2}, matches)
 	})
 
+	t.Run("One match ending at the end of the data", func(t *testing.T) {
+		var (
+			data    = []byte{0x00, 0x00, 0x01, 0x02, 0x03}
+			matches = sig.checkMatch(data)
+		)
+
+		assert.Equal(t, matchOffsets{2}, matches)
+	})
+
+	t.Run("Same length as the data", func(t *testing.T) {
+		var (
+			data    = []byte{0x01, 0x02, 0x03}
+			matches = sig.checkMatch(data)
+		)
+
+		assert.Equal(t, matchOffsets{0}, matches)
+	})
+
+	t.Run("Single byte pattern", func(t *testing.T) {
+		var (
+			single  = MakePattern([]byte{0x01})
+			data    = []byte{0x01, 0x02, 0x01}
+			matches = single.checkMatch(data)
+		)
+
+		assert.Equal(t, matchOffsets{0, 2}, matches)
+	})
+
 	t.Run("No match", func(t *testing.T) {
 		var (
 			data    = []byte{0x01, 0x02, 0x04, 0x05, 0x06, 0x07}
@@ -85,7 +114,7 @@ func TestMatchPatternWithMask(t *testing.T) {
 			matches = sig.checkMatch(data)
 		)
 
-		assert.Equal(t, 2, matches.len())
+		assert.Equal(t, matchOffsets{2}, matches)
 	})
 
 	t.Run("No match", func(t *testing.T) {
@@ -106,3 +135,85 @@ func TestMatchPatternWithMask(t *testing.T) {
 		assert.Equal(t, matchOffsets{0, 5}, matches)
 	})
 }
+
+func TestMatchPatternStartingWithWildcard(t *testing.T) {
+	sig := MakePatternWithMask(
+		[]byte{0xff, 0x02, 0x03},
+		[]byte{anyByte, matchByte, matchByte},
+	)
+
+	assert.Equal(t, matchOffsets{1}, sig.checkMatch([]byte{0x00, 0xab, 0x02, 0x03}))
+}
+
+func TestMatchPatternLengths(t *testing.T) {
+	data := []byte{0x01, 0x02, 0x03}
+
+	for _, tCase := range []struct {
+		name    string
+		pattern *SignaturePattern
+		data    []byte
+		want    matchOffsets
+	}{
+		{name: "An empty pattern", pattern: &SignaturePattern{}, data: data},
+		{name: "An empty pattern in empty data", pattern: &SignaturePattern{}},
+		{name: "A pattern longer than the data", pattern: MakePattern([]byte{0x01, 0x02, 0x03, 0x04}), data: data},
+		{name: "A pattern in empty data", pattern: MakePattern([]byte{0x01})},
+		{name: "A pattern as long as the data", pattern: MakePattern(data), data: data, want: matchOffsets{0}},
+		{name: "A single byte pattern at the end", pattern: MakePattern([]byte{0x03}), data: data, want: matchOffsets{2}},
+	} {
+		t.Run(tCase.name, func(t *testing.T) {
+			assert.Equal(t, tCase.want, tCase.pattern.checkMatch(tCase.data))
+		})
+	}
+}
+
+func TestMakeEmptyPattern(t *testing.T) {
+	assert.Panics(t, func() { MakePattern(nil) })
+	assert.Panics(t, func() { MakePatternWithMask([]byte{}, []byte{}) })
+}
+
+func TestPatternEqual(t *testing.T) {
+	pattern := MakePatternWithMask(
+		[]byte{0x01, 0x02, 0x03},
+		[]byte{matchByte, anyByte, matchByte},
+	)
+
+	t.Run("Same bytes in the masked positions", func(t *testing.T) {
+		other := MakePatternWithMask(
+			[]byte{0x01, 0xab, 0x03},
+			[]byte{matchByte, anyByte, matchByte},
+		)
+
+		assert.True(t, pattern.Equal(other))
+	})
+
+	t.Run("Different mask", func(t *testing.T) {
+		other := MakePattern([]byte{0x01, 0x02, 0x03})
+
+		assert.False(t, pattern.Equal(other))
+	})
+}
+
+func TestWriteJSON(t *testing.T) {
+	var (
+		upx, _   = Make("upx", "UPX packed", map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}, "upx")
+		entropy  = 4.5
+		report   strings.Builder
+		fileMeta = SigMatchMeta{FilePath: "a.exe", Entropy: &entropy}
+	)
+
+	err := WriteJSON(&report, []SigMatch{
+		{Meta: fileMeta, Signature: &upx, IsMatch: true, Offsets: map[string]matchOffsets{"upx": {4}}},
+		{Meta: SigMatchMeta{FilePath: "b.exe"}, Signature: &upx},
+	})
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `[
+		{
+			"file": "a.exe",
+			"entropy": 4.5,
+			"matches": [{"signature": "upx", "description": "UPX packed", "offsets": {"upx": [4]}}]
+		},
+		{"file": "b.exe", "matches": []}
+	]`, report.String())
+}
@@ -0,0 +1,69 @@
+package signature
+
+import "math"
+
+// mathValues are the size of the file and the values computed from its bytes,
+// like the entropy of a region (e.g. math.entropy(0, filesize) > 7.2) or of a
+// section (e.g. math.section_entropy(".text") > 7).
+func mathValues() map[string]fileValue {
+	return map[string]fileValue{
+		"filesize": {
+			kind: kindInt,
+			get: func(f *scannedFile, _ []valueArg) (any, bool) {
+				return int64(len(f.data)), true
+			},
+		},
+		// Regions that start outside the data, or are empty, are undefined, and
+		// those that end past it are cut at its end.
+		"math.entropy": {
+			kind: kindFloat,
+			args: []valueKind{kindOffset, kindOffset},
+			get: func(f *scannedFile, args []valueArg) (any, bool) {
+				offset, size := args[0].Int, args[1].Int
+				if offset < 0 || offset >= int64(len(f.data)) || size <= 0 {
+					return nil, false
+				}
+				if offset == 0 && size >= int64(len(f.data)) {
+					return f.fileEntropy(), true
+				}
+
+				end := offset + min(size, int64(len(f.data))-offset)
+				return dataEntropy(f.data[offset:end]), true
+			},
+		},
+		// Sections that the file doesn't have, or that take no space in it, are
+		// undefined.
+		"math.section_entropy": {
+			kind: kindFloat,
+			args: []valueKind{kindString},
+			get: func(f *scannedFile, args []valueArg) (any, bool) {
+				start, end, ok := Scope{Section: args[0].Str}.region(f)
+				if !ok || start == end {
+					return nil, false
+				}
+
+				return dataEntropy(f.data[start:end]), true
+			},
+		},
+	}
+}
+
+// dataEntropy returns the Shannon entropy of the data, in bits per byte, from
+// 0, for data made of a single repeated byte, to 8, for data where every byte
+// value is as frequent.
+func dataEntropy(data []byte) float64 {
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	var entropy float64
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(data))
+			entropy -= p * math.Log2(p)
+		}
+	}
+
+	return entropy
+}
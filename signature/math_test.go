@@ -0,0 +1,97 @@
+package signature
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMathValues(t *testing.T) {
+	var (
+		patterns = map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}
+		pe       = makeTestPE()
+		// The .upx section of the ELF file starts far past the end of the data,
+		// and its size wraps its end around to the start of the data.
+		malformedELF = makeTestELF()
+		// 256 zeros, then every byte value once.
+		data = make([]byte, 512)
+	)
+
+	for i := range 256 {
+		data[256+i] = byte(i)
+	}
+	binary.LittleEndian.PutUint64(malformedELF[224+24:], 1<<62)
+	binary.LittleEndian.PutUint64(malformedELF[224+32:], 3<<62+8)
+
+	for _, tCase := range []struct {
+		name      string
+		data      []byte
+		condition string
+		want      bool
+	}{
+		{name: "data", data: data, condition: "filesize == 512 AND filesize > 0x100", want: true},
+		{name: "data", data: data, condition: "math.entropy(0, 256) == 0", want: true},
+		{name: "data", data: data, condition: "math.entropy(256, 256) == 8 AND math.entropy(0x100, filesize) >= 8.0", want: true},
+		{name: "data", data: data, condition: "math.entropy(0, filesize) > 4.9 AND math.entropy(0, filesize) < 5", want: true},
+		{name: "data", data: data, condition: "math.entropy(filesize, 1) >= 0", want: false},
+		{name: "data", data: data, condition: "math.entropy(0, 0) >= 0", want: false},
+		{name: "data", data: data, condition: "math.section_entropy(\".text\") >= 0", want: false},
+		{name: "PE", data: pe, condition: "math.section_entropy(\".text\") > 0 AND math.section_entropy(\".text\") < 8", want: true},
+		{name: "PE", data: pe, condition: "math.section_entropy(\".upx0\") >= 0", want: false},
+		{name: "PE", data: pe, condition: "math.entropy(@upx[1], 4) == 2", want: true},
+		{name: "malformed ELF", data: malformedELF, condition: "math.section_entropy(\".upx\") >= 0", want: false},
+	} {
+		t.Run(
+			fmt.Sprintf("'%s' evaluates to %t for %s", tCase.condition, tCase.want, tCase.name),
+			func(t *testing.T) {
+				sig, err := Make("math", "", patterns, tCase.condition)
+				if err != nil {
+					t.Fatalf("Want no error, got %s", err)
+				}
+
+				assert.Equal(t, tCase.want, sig.CheckMatch(tCase.data).IsMatch)
+			})
+	}
+
+	for _, condition := range []string{
+		"math.entropy(0) > 7",
+		`math.entropy(0, 1) == "8"`,
+		"math.entropy(0, pe.machine) > 7",
+		"math.section_entropy(1) > 7",
+		"filesize == 1.5",
+		"math.entropy > 7",
+	} {
+		t.Run(
+			fmt.Sprintf("'%s' is an invalid comparison", condition),
+			func(t *testing.T) {
+				_, err := Make("math", "", patterns, condition)
+
+				if assert.NotNil(t, err) {
+					assert.Equal(t, ErrSigInvalidComparison, err.(ErrSignature).reason)
+				}
+			})
+	}
+
+	t.Run("entropy is only reported when asked for", func(t *testing.T) {
+		var (
+			binPath = filepath.Join(t.TempDir(), "bin")
+			sig, _  = Make("upx", "", patterns, "upx")
+		)
+
+		if err := os.WriteFile(binPath, data, 0o644); err != nil {
+			t.Fatalf("Can't write test file: %s", err)
+		}
+
+		matches, _ := Signatures{sig}.Check(binPath)
+		assert.Nil(t, matches[0].Meta.Entropy)
+
+		matches, _ = Signatures{sig}.CheckWithOptions(binPath, CheckOptions{Entropy: true})
+		if assert.NotNil(t, matches[0].Meta.Entropy) {
+			assert.InDelta(t, 4.98, *matches[0].Meta.Entropy, 0.01)
+		}
+	})
+}
@@ -1,5 +1,7 @@
 package signature
 
+import "bytes"
+
 const (
 	matchByte = 0xff
 	anyByte   = 0x00
@@ -24,6 +26,7 @@ type SignaturePattern struct {
 	mask    []byte
 	// maskedPattern is the pattern with the mask applied.
 	maskedPattern []byte
+	scope         Scope
 }
 
 // Length returns the Length of the pattern and mask.
@@ -31,6 +34,38 @@ func (s *SignaturePattern) Length() int {
 	return len(s.pattern)
 }
 
+// Bytes returns the sequence of bytes the pattern was made with, unmasked.
+// The bytes at positions that match any byte are kept as they were given, which
+// is zero for patterns read from signature files: use Mask to tell them apart.
+func (s *SignaturePattern) Bytes() []byte {
+	return s.pattern
+}
+
+// Mask returns the mask applied to the pattern: 0xff for bytes that must be
+// matched, 0x00 for bytes that match any byte.
+func (s *SignaturePattern) Mask() []byte {
+	return s.mask
+}
+
+// Scope returns the region of the file where the pattern is searched for.
+func (s *SignaturePattern) Scope() Scope {
+	return s.scope
+}
+
+// WithScope returns a copy of the pattern that is only searched for in the
+// given region of the file.
+func (s *SignaturePattern) WithScope(scope Scope) *SignaturePattern {
+	scoped := *s
+	scoped.scope = scope
+
+	return &scoped
+}
+
+// Equal returns true if both patterns match exactly the same byte sequences.
+func (s *SignaturePattern) Equal(other *SignaturePattern) bool {
+	return bytes.Equal(s.maskedPattern, other.maskedPattern) && bytes.Equal(s.mask, other.mask)
+}
+
 func MakePattern(pattern []byte) *SignaturePattern {
 	mask := make([]byte, len(pattern))
 	for i := range mask {
@@ -40,10 +75,16 @@ func MakePattern(pattern []byte) *SignaturePattern {
 	return MakePatternWithMask(pattern, mask)
 }
 
+// MakePatternWithMask returns a pattern that matches the bytes whose mask is
+// 0xff, and any byte where the mask is 0x00. It panics if the pattern is empty,
+// or the mask has a different length.
 func MakePatternWithMask(pattern, mask []byte) *SignaturePattern {
 	if len(pattern) != len(mask) {
 		panic("pattern and mask length mismatch")
 	}
+	if len(pattern) == 0 {
+		panic("empty pattern")
+	}
 
 	maskedPattern := make([]byte, len(pattern))
 	for i := range pattern {
@@ -63,37 +104,65 @@ func MakePatternWithMask(pattern, mask []byte) *SignaturePattern {
 // The function expects the full file contents in a byte slice, as binaries themselves
 // are usually small enough to fit in memory.
 func (s *SignaturePattern) checkMatch(data []byte) matchOffsets {
+	offsets, _ := s.scan(data)
+	return offsets
+}
+
+// scanFile returns the offsets where the pattern matches in its scope of the
+// file, and the number of candidate offsets, like scan does.
+func (s *SignaturePattern) scanFile(f *scannedFile) (matchOffsets, int) {
+	start, end, ok := s.scope.region(f)
+	if !ok {
+		return nil, 0
+	}
+
+	offsets, candidates := s.scan(f.data[start:end])
+	for i := range offsets {
+		offsets[i] += start
+	}
+
+	return offsets, candidates
+}
+
+// scan returns the offsets where the pattern matches the data, and the number
+// of candidate offsets: those where the first byte of the pattern matched, and
+// the rest of the pattern had to be compared.
+func (s *SignaturePattern) scan(data []byte) (matchOffsets, int) {
+	// Empty patterns can't be made, but a zero value pattern is empty.
+	if len(s.maskedPattern) == 0 {
+		return nil, 0
+	}
+
 	var (
-		offsets     []int
-		fileByte    byte
-		patternByte byte
+		offsets    []int
+		candidates int
 
-		patternFirstByte = s.pattern[0]
+		patternFirstByte = s.maskedPattern[0]
 		maskFirstByte    = s.mask[0]
 	)
 
-	for i := 0; i < len(data)-s.Length(); i++ {
-		fileByte = data[i] & maskFirstByte
-
-		if fileByte != patternFirstByte {
+	// The pattern can match up to the very end of the data.
+	for i := 0; i <= len(data)-s.Length(); i++ {
+		if data[i]&maskFirstByte != patternFirstByte {
 			continue
 		}
 
+		candidates++
+
 		// The byte at i matches the first byte of the pattern.
 		// Check if the rest of the pattern matches.
+		matches := true
 		for j := 1; j < s.Length(); j++ {
-			fileByte = data[i+j] & s.mask[j]
-			patternByte = s.maskedPattern[j]
-
-			if patternByte != fileByte {
+			if data[i+j]&s.mask[j] != s.maskedPattern[j] {
+				matches = false
 				break
 			}
+		}
 
-			if j == s.Length()-1 {
-				offsets = append(offsets, i)
-			}
+		if matches {
+			offsets = append(offsets, i)
 		}
 	}
 
-	return offsets
+	return offsets, candidates
 }
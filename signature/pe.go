@@ -0,0 +1,380 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/md5"
+	"debug/pe"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/angelsolaorbaiceta/binmat/bexpr"
+)
+
+// maxPEEntries is the maximum number of imports or exports read from a PE
+// file, so that malformed tables can't make the parsing loop for too long.
+const maxPEEntries = 1 << 16
+
+var (
+	peMachines = map[uint16]string{
+		pe.IMAGE_FILE_MACHINE_I386:        "i386",
+		pe.IMAGE_FILE_MACHINE_AMD64:       "amd64",
+		pe.IMAGE_FILE_MACHINE_ARM:         "arm",
+		pe.IMAGE_FILE_MACHINE_ARMNT:       "armnt",
+		pe.IMAGE_FILE_MACHINE_ARM64:       "arm64",
+		pe.IMAGE_FILE_MACHINE_IA64:        "ia64",
+		pe.IMAGE_FILE_MACHINE_POWERPC:     "powerpc",
+		pe.IMAGE_FILE_MACHINE_RISCV32:     "riscv32",
+		pe.IMAGE_FILE_MACHINE_RISCV64:     "riscv64",
+		pe.IMAGE_FILE_MACHINE_LOONGARCH64: "loongarch64",
+	}
+
+	peSubsystems = map[uint16]string{
+		pe.IMAGE_SUBSYSTEM_NATIVE:                   "native",
+		pe.IMAGE_SUBSYSTEM_WINDOWS_GUI:              "windows_gui",
+		pe.IMAGE_SUBSYSTEM_WINDOWS_CUI:              "windows_cui",
+		pe.IMAGE_SUBSYSTEM_OS2_CUI:                  "os2_cui",
+		pe.IMAGE_SUBSYSTEM_POSIX_CUI:                "posix_cui",
+		pe.IMAGE_SUBSYSTEM_NATIVE_WINDOWS:           "native_windows",
+		pe.IMAGE_SUBSYSTEM_WINDOWS_CE_GUI:           "windows_ce_gui",
+		pe.IMAGE_SUBSYSTEM_EFI_APPLICATION:          "efi_application",
+		pe.IMAGE_SUBSYSTEM_EFI_BOOT_SERVICE_DRIVER:  "efi_boot_service_driver",
+		pe.IMAGE_SUBSYSTEM_EFI_RUNTIME_DRIVER:       "efi_runtime_driver",
+		pe.IMAGE_SUBSYSTEM_EFI_ROM:                  "efi_rom",
+		pe.IMAGE_SUBSYSTEM_XBOX:                     "xbox",
+		pe.IMAGE_SUBSYSTEM_WINDOWS_BOOT_APPLICATION: "windows_boot_application",
+	}
+
+	// peSectionFlags are the section characteristics that can be checked by name.
+	peSectionFlags = map[string]uint32{
+		"code":               pe.IMAGE_SCN_CNT_CODE,
+		"initialized_data":   pe.IMAGE_SCN_CNT_INITIALIZED_DATA,
+		"uninitialized_data": pe.IMAGE_SCN_CNT_UNINITIALIZED_DATA,
+		"discardable":        pe.IMAGE_SCN_MEM_DISCARDABLE,
+		"shared":             0x10000000,
+		"execute":            pe.IMAGE_SCN_MEM_EXECUTE,
+		"read":               pe.IMAGE_SCN_MEM_READ,
+		"write":              pe.IMAGE_SCN_MEM_WRITE,
+	}
+)
+
+// A peFile is the PE file parsed from the scanned data, with the tables
+// debug/pe doesn't read.
+type peFile struct {
+	file *pe.File
+	// optional is false for files without an optional header, like object files.
+	optional   bool
+	entryPoint uint32
+	subsystem  uint16
+	sections   []peSection
+	imports    []peImport
+	exports    []string
+}
+
+// A peSection is a section of a PE file and its raw data.
+type peSection struct {
+	*pe.Section
+	data []byte
+}
+
+// A peImport is a function imported from a library. Functions imported by
+// ordinal are named "ord" followed by the ordinal (e.g. "ord23").
+type peImport struct {
+	library, function string
+}
+
+// parsePE parses the data as a PE file, returning nil if it isn't one.
+func parsePE(data []byte) (parsed any) {
+	// Malformed files shouldn't stop the scan, they're just not PE files.
+	defer func() {
+		if recover() != nil {
+			parsed = (*peFile)(nil)
+		}
+	}()
+
+	file, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return (*peFile)(nil)
+	}
+
+	parsedFile := &peFile{file: file}
+	for _, section := range file.Sections {
+		// Sections without raw data, like .bss, have nothing to read.
+		sectionData, _ := section.Data()
+		parsedFile.sections = append(parsedFile.sections, peSection{Section: section, data: sectionData})
+	}
+
+	var (
+		dirs []pe.DataDirectory
+		pe64 bool
+	)
+	switch header := file.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		parsedFile.optional = true
+		parsedFile.entryPoint, parsedFile.subsystem = header.AddressOfEntryPoint, header.Subsystem
+		dirs = header.DataDirectory[:min(header.NumberOfRvaAndSizes, uint32(len(header.DataDirectory)))]
+	case *pe.OptionalHeader64:
+		parsedFile.optional = true
+		parsedFile.entryPoint, parsedFile.subsystem = header.AddressOfEntryPoint, header.Subsystem
+		dirs = header.DataDirectory[:min(header.NumberOfRvaAndSizes, uint32(len(header.DataDirectory)))]
+		pe64 = true
+	}
+
+	if len(dirs) > pe.IMAGE_DIRECTORY_ENTRY_EXPORT {
+		parsedFile.readExports(dirs[pe.IMAGE_DIRECTORY_ENTRY_EXPORT].VirtualAddress)
+	}
+	if len(dirs) > pe.IMAGE_DIRECTORY_ENTRY_IMPORT {
+		parsedFile.readImports(dirs[pe.IMAGE_DIRECTORY_ENTRY_IMPORT].VirtualAddress, pe64)
+	}
+
+	return parsedFile
+}
+
+// at returns the data at the relative virtual address, up to the end of the
+// section holding it, or nil if it isn't in any section's raw data.
+func (p *peFile) at(rva uint32) []byte {
+	for _, section := range p.sections {
+		if rva >= section.VirtualAddress && rva-section.VirtualAddress < uint32(len(section.data)) {
+			return section.data[rva-section.VirtualAddress:]
+		}
+	}
+
+	return nil
+}
+
+// uint32At returns the little endian 32 bit integer at the relative virtual
+// address, or false if it can't be read.
+func (p *peFile) uint32At(rva uint32) (uint32, bool) {
+	data := p.at(rva)
+	if len(data) < 4 {
+		return 0, false
+	}
+
+	return binary.LittleEndian.Uint32(data), true
+}
+
+// stringAt returns the null terminated string at the relative virtual address.
+func (p *peFile) stringAt(rva uint32) string {
+	data := p.at(rva)
+	if end := bytes.IndexByte(data, 0); end >= 0 {
+		data = data[:end]
+	}
+
+	return string(data)
+}
+
+// readExports reads the names of the functions in the export directory at the
+// relative virtual address.
+func (p *peFile) readExports(rva uint32) {
+	dir := p.at(rva)
+	if rva == 0 || len(dir) < 40 {
+		return
+	}
+
+	var (
+		numberOfNames  = binary.LittleEndian.Uint32(dir[24:])
+		addressOfNames = binary.LittleEndian.Uint32(dir[32:])
+	)
+
+	for i := uint32(0); i < min(numberOfNames, maxPEEntries); i++ {
+		nameRVA, ok := p.uint32At(addressOfNames + 4*i)
+		if !ok {
+			return
+		}
+		p.exports = append(p.exports, p.stringAt(nameRVA))
+	}
+}
+
+// readImports reads the functions in the import directory at the relative
+// virtual address, in the order they're imported.
+func (p *peFile) readImports(rva uint32, pe64 bool) {
+	if rva == 0 {
+		return
+	}
+
+	thunkSize, ordinalFlag := uint32(4), uint64(1)<<31
+	if pe64 {
+		thunkSize, ordinalFlag = 8, uint64(1)<<63
+	}
+
+	for descriptor := rva; len(p.imports) < maxPEEntries; descriptor += 20 {
+		entry := p.at(descriptor)
+		if len(entry) < 20 {
+			return
+		}
+
+		var (
+			originalFirstThunk = binary.LittleEndian.Uint32(entry[0:])
+			name               = binary.LittleEndian.Uint32(entry[12:])
+			firstThunk         = binary.LittleEndian.Uint32(entry[16:])
+		)
+		if originalFirstThunk == 0 && firstThunk == 0 {
+			return
+		}
+		if originalFirstThunk == 0 {
+			originalFirstThunk = firstThunk
+		}
+
+		library := p.stringAt(name)
+		for thunk := originalFirstThunk; len(p.imports) < maxPEEntries; thunk += thunkSize {
+			data := p.at(thunk)
+			if uint32(len(data)) < thunkSize {
+				break
+			}
+
+			value := uint64(binary.LittleEndian.Uint32(data))
+			if pe64 {
+				value = binary.LittleEndian.Uint64(data)
+			}
+			if value == 0 {
+				break
+			}
+
+			function := fmt.Sprintf("ord%d", value&0xffff)
+			if value&ordinalFlag == 0 {
+				// Skip the hint before the name.
+				function = p.stringAt(uint32(value) + 2)
+			}
+			p.imports = append(p.imports, peImport{library: library, function: function})
+		}
+	}
+}
+
+// section returns the first section with the given name, or nil if the file
+// doesn't have one.
+func (p *peFile) section(name string) *peSection {
+	for i := range p.sections {
+		if p.sections[i].Name == name {
+			return &p.sections[i]
+		}
+	}
+
+	return nil
+}
+
+// imphash returns the MD5 of the imported functions, as computed by pefile:
+// the lowercase library names, without extension, and function names, joined
+// by a dot, in import order and separated by commas.
+func (p *peFile) imphash() string {
+	names := make([]string, len(p.imports))
+	for i, imp := range p.imports {
+		library := strings.ToLower(imp.library)
+		for _, ext := range []string{".dll", ".ocx", ".sys"} {
+			library = strings.TrimSuffix(library, ext)
+		}
+		names[i] = library + "." + strings.ToLower(imp.function)
+	}
+
+	sum := md5.Sum([]byte(strings.Join(names, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// peValue returns the file value of a PE file, undefined for files that aren't
+// PE files.
+func peValue(kind valueKind, args []valueKind, get func(f *peFile, args []valueArg) (any, bool)) fileValue {
+	return fileValue{
+		kind: kind,
+		args: args,
+		get: func(f *scannedFile, args []valueArg) (any, bool) {
+			parsed := f.module("pe", parsePE).(*peFile)
+			if parsed == nil {
+				return nil, false
+			}
+			return get(parsed, args)
+		},
+	}
+}
+
+// peSectionValue returns the file value of a PE file's section, named by the
+// first argument, undefined if the file doesn't have the section.
+func peSectionValue(kind valueKind, args []valueKind, get func(s *peSection, args []valueArg) any) fileValue {
+	return peValue(kind, append([]valueKind{kindString}, args...), func(f *peFile, args []valueArg) (any, bool) {
+		section := f.section(args[0].Str)
+		if section == nil {
+			return nil, false
+		}
+		return get(section, args[1:]), true
+	})
+}
+
+func peValues() map[string]fileValue {
+	sectionFlag := peSectionValue(kindBool, []valueKind{kindString}, func(s *peSection, args []valueArg) any {
+		flag := peSectionFlags[args[0].Str]
+		return s.Characteristics&flag == flag
+	})
+	sectionFlag.checkArgs = func(args []bexpr.Operand) error {
+		if _, ok := peSectionFlags[args[1].Str]; !ok {
+			return fmt.Errorf("unknown section flag %s", args[1])
+		}
+		return nil
+	}
+
+	imphash := peValue(kindString, nil, func(f *peFile, _ []valueArg) (any, bool) {
+		return f.imphash(), true
+	})
+	imphash.literal = hashLiteral(32)
+
+	return map[string]fileValue{
+		"pe.machine": peValue(kindString, nil, func(f *peFile, _ []valueArg) (any, bool) {
+			if name, ok := peMachines[f.file.Machine]; ok {
+				return name, true
+			}
+			return fmt.Sprintf("0x%x", f.file.Machine), true
+		}),
+		"pe.timestamp": peValue(kindInt, nil, func(f *peFile, _ []valueArg) (any, bool) {
+			return int64(f.file.TimeDateStamp), true
+		}),
+		"pe.entry_point": peValue(kindInt, nil, func(f *peFile, _ []valueArg) (any, bool) {
+			return int64(f.entryPoint), f.optional
+		}),
+		"pe.subsystem": peValue(kindString, nil, func(f *peFile, _ []valueArg) (any, bool) {
+			if name, ok := peSubsystems[f.subsystem]; ok {
+				return name, f.optional
+			}
+			return "unknown", f.optional
+		}),
+		"pe.number_of_sections": peValue(kindInt, nil, func(f *peFile, _ []valueArg) (any, bool) {
+			return int64(len(f.sections)), true
+		}),
+		"pe.has_section": peValue(kindBool, []valueKind{kindString}, func(f *peFile, args []valueArg) (any, bool) {
+			return f.section(args[0].Str) != nil, true
+		}),
+		"pe.section_size": peSectionValue(kindInt, nil, func(s *peSection, _ []valueArg) any {
+			return int64(s.Size)
+		}),
+		"pe.section_characteristics": peSectionValue(kindInt, nil, func(s *peSection, _ []valueArg) any {
+			return int64(s.Characteristics)
+		}),
+		"pe.section_flag": sectionFlag,
+		// Patterns match in a section if any of their matches starts in the
+		// section's raw data.
+		"pe.in_section": peValue(kindBool, []valueKind{kindPattern, kindString}, func(f *peFile, args []valueArg) (any, bool) {
+			section := f.section(args[1].Str)
+			if section == nil {
+				return nil, false
+			}
+
+			start, end := int(section.Offset), int(section.Offset)+len(section.data)
+			return slices.ContainsFunc(args[0].offsets, func(offset int) bool {
+				return offset >= start && offset < end
+			}), true
+		}),
+		// Library names are case insensitive, as they are in Windows.
+		"pe.imports": peValue(kindBool, []valueKind{kindString, kindString}, func(f *peFile, args []valueArg) (any, bool) {
+			return slices.ContainsFunc(f.imports, func(imp peImport) bool {
+				return strings.EqualFold(imp.library, args[0].Str) && imp.function == args[1].Str
+			}), true
+		}),
+		"pe.imports_library": peValue(kindBool, []valueKind{kindString}, func(f *peFile, args []valueArg) (any, bool) {
+			return slices.ContainsFunc(f.imports, func(imp peImport) bool {
+				return strings.EqualFold(imp.library, args[0].Str)
+			}), true
+		}),
+		"pe.exports": peValue(kindBool, []valueKind{kindString}, func(f *peFile, args []valueArg) (any, bool) {
+			return slices.Contains(f.exports, args[0].Str), true
+		}),
+		"pe.imphash": imphash,
+	}
+}
@@ -0,0 +1,131 @@
+package signature
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testImphash is the imphash of the file returned by makeTestPE.
+const testImphash = "1c3228373c5fb482e3406d806642ff82"
+
+// makeTestPE returns a 64 bit x86 console executable with two sections: .text,
+// holding "UPX!", and .rdata, with the imports of VirtualAlloc, ExitProcess and
+// ordinal 23 from KERNEL32.dll, and the export of Install.
+func makeTestPE() []byte {
+	var (
+		buf   bytes.Buffer
+		write = func(data any) { binary.Write(&buf, binary.LittleEndian, data) }
+		pad   = func(offset int) { write(make([]byte, offset-buf.Len())) }
+
+		rdata     = make([]byte, 0x200)
+		put32     = func(rva, value uint32) { binary.LittleEndian.PutUint32(rdata[rva-0x2000:], value) }
+		put64     = func(rva uint32, value uint64) { binary.LittleEndian.PutUint64(rdata[rva-0x2000:], value) }
+		putString = func(rva uint32, str string) { copy(rdata[rva-0x2000:], str) }
+
+		sectionHeader = func(name string, rva, offset, characteristics uint32) pe.SectionHeader32 {
+			header := pe.SectionHeader32{
+				VirtualSize:      0x200,
+				VirtualAddress:   rva,
+				SizeOfRawData:    0x200,
+				PointerToRawData: offset,
+				Characteristics:  characteristics,
+			}
+			copy(header.Name[:], name)
+			return header
+		}
+	)
+
+	// Import descriptor, followed by an empty one.
+	put32(0x2000, 0x2040)
+	put32(0x200c, 0x2080)
+	put32(0x2010, 0x2040)
+	put64(0x2040, 0x2090)
+	put64(0x2048, 0x20a0)
+	put64(0x2050, 1<<63|23)
+	putString(0x2080, "KERNEL32.dll")
+	putString(0x2092, "VirtualAlloc")
+	putString(0x20a2, "ExitProcess")
+
+	// Export directory, with a single name.
+	put32(0x2100+24, 1)
+	put32(0x2100+32, 0x2140)
+	put32(0x2140, 0x2150)
+	putString(0x2150, "Install")
+
+	header := pe.OptionalHeader64{
+		Magic:               0x20b,
+		AddressOfEntryPoint: 0x1000,
+		ImageBase:           0x140000000,
+		SectionAlignment:    0x1000,
+		FileAlignment:       0x200,
+		Subsystem:           pe.IMAGE_SUBSYSTEM_WINDOWS_CUI,
+		NumberOfRvaAndSizes: 16,
+	}
+	header.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_EXPORT] = pe.DataDirectory{VirtualAddress: 0x2100, Size: 40}
+	header.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_IMPORT] = pe.DataDirectory{VirtualAddress: 0x2000, Size: 40}
+
+	write([]byte("MZ"))
+	pad(0x3c)
+	write(uint32(0x40))
+	write([]byte("PE\x00\x00"))
+	write(pe.FileHeader{
+		Machine:              pe.IMAGE_FILE_MACHINE_AMD64,
+		NumberOfSections:     2,
+		TimeDateStamp:        0x5f5e1000,
+		SizeOfOptionalHeader: uint16(binary.Size(header)),
+		Characteristics:      pe.IMAGE_FILE_EXECUTABLE_IMAGE | pe.IMAGE_FILE_LARGE_ADDRESS_AWARE,
+	})
+	write(header)
+	write(sectionHeader(".text", 0x1000, 0x200, pe.IMAGE_SCN_CNT_CODE|pe.IMAGE_SCN_MEM_EXECUTE|pe.IMAGE_SCN_MEM_READ))
+	write(sectionHeader(".rdata", 0x2000, 0x400, pe.IMAGE_SCN_CNT_INITIALIZED_DATA|pe.IMAGE_SCN_MEM_READ))
+
+	pad(0x200)
+	write([]byte("UPX!"))
+	pad(0x400)
+	write(rdata)
+
+	return buf.Bytes()
+}
+
+func TestPEValues(t *testing.T) {
+	testValues(t, makeTestPE(), []valueTestCase{
+		{condition: `upx AND pe.machine == "amd64" AND pe.in_section(upx, ".text")`, want: true},
+		{condition: `pe.in_section(upx, ".rdata")`, want: false},
+		{condition: `upx AND NOT pe.in_section(upx, ".text")`, want: false, wantOther: true},
+		{condition: `pe.timestamp == 0x5f5e1000`, want: true},
+		{condition: `pe.entry_point == 0x1000`, want: true},
+		{condition: `pe.subsystem == "windows_cui"`, want: true},
+		{condition: `pe.number_of_sections == 2`, want: true},
+		{condition: `pe.has_section(".rdata") AND NOT pe.has_section(".upx0")`, want: true},
+		{condition: `pe.section_size(".text") == 0x200`, want: true},
+		{condition: `pe.section_size(".upx0") >= 0`, want: false},
+		{condition: `pe.section_characteristics(".text") == 0x60000020`, want: true},
+		{condition: `pe.section_flag(".text", "execute") AND NOT pe.section_flag(".rdata", "execute")`, want: true},
+		{condition: `pe.imports("kernel32.dll", "VirtualAlloc")`, want: true},
+		{condition: `pe.imports("kernel32.dll", "virtualalloc")`, want: false},
+		{condition: `pe.imports("kernel32.dll", "ord23")`, want: true},
+		{condition: `pe.imports_library("KERNEL32.DLL") AND NOT pe.imports_library("user32.dll")`, want: true},
+		{condition: `pe.exports("Install")`, want: true},
+		{condition: fmt.Sprintf(`pe.imphash == "%s"`, testImphash), want: true},
+		{condition: `elf.machine == "x86_64"`, want: false},
+	})
+
+	t.Run("patterns used as arguments are used", func(t *testing.T) {
+		patterns := map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}
+		sig, err := Make("pe", "", patterns, `pe.in_section(upx, ".text")`)
+
+		assert.Nil(t, err)
+		assert.Empty(t, sig.Warnings())
+	})
+
+	t.Run("malformed PE files aren't PE files", func(t *testing.T) {
+		data := makeTestPE()[:0x100]
+
+		assert.Nil(t, parsePE(data).(*peFile))
+	})
+}
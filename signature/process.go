@@ -0,0 +1,178 @@
+package signature
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrProcessUnsupported is returned when scanning the memory of processes
+// isn't supported in the operating system.
+var ErrProcessUnsupported = errors.New("scanning processes is only supported on Linux")
+
+// A MemoryRegion is a mapping in the virtual memory of a process, as listed in
+// /proc/<pid>/maps.
+type MemoryRegion struct {
+	PID int
+	// Start and End are the virtual addresses of the region, End excluded.
+	Start, End uint64
+	// Perms are the region's permissions, as in "r-xp": read, write, execute,
+	// and either private or shared.
+	Perms string
+	// Path is the file mapped in the region, a pseudo-path like "[heap]" or
+	// "[stack]", or empty for anonymous mappings.
+	Path string
+}
+
+// Readable returns true if the region can be read.
+func (r MemoryRegion) Readable() bool {
+	return strings.HasPrefix(r.Perms, "r")
+}
+
+func (r MemoryRegion) String() string {
+	region := fmt.Sprintf("pid %d, 0x%x-0x%x %s", r.PID, r.Start, r.End, r.Perms)
+	if r.Path != "" {
+		region += " " + r.Path
+	}
+
+	return region
+}
+
+// parseMemoryMaps parses the regions of the process from the lines of its
+// /proc/<pid>/maps file (e.g. "7f3c2a000000-7f3c2a021000 r-xp 00000000 08:01
+// 1234 /usr/lib/libc.so.6").
+func parseMemoryMaps(pid int, r io.Reader) ([]MemoryRegion, error) {
+	var (
+		regions []MemoryRegion
+		scanner = bufio.NewScanner(r)
+	)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("invalid memory map line '%s'", scanner.Text())
+		}
+
+		start, end, ok := strings.Cut(fields[0], "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid memory map addresses '%s'", fields[0])
+		}
+
+		region := MemoryRegion{PID: pid, Perms: fields[1]}
+		var err error
+		if region.Start, err = strconv.ParseUint(start, 16, 64); err != nil {
+			return nil, fmt.Errorf("invalid memory map addresses '%s'", fields[0])
+		}
+		if region.End, err = strconv.ParseUint(end, 16, 64); err != nil {
+			return nil, fmt.Errorf("invalid memory map addresses '%s'", fields[0])
+		}
+		// Paths can have spaces, and deleted files a " (deleted)" suffix.
+		if len(fields) > 5 {
+			region.Path = strings.Join(fields[5:], " ")
+		}
+
+		regions = append(regions, region)
+	}
+
+	return regions, scanner.Err()
+}
+
+// processChunkSize is the size of the chunks memory regions are read and
+// checked in, so that large mappings don't have to be read whole.
+const processChunkSize = 64 << 20
+
+// checkMemoryRegion reads the region from the process' memory, in chunks of at
+// most chunkSize bytes, and checks each of them like a region of its own. The
+// chunks overlap by one byte less than the longest pattern, so that matches
+// across chunks aren't missed, and matches that fit in the overlap are only
+// reported in the first chunk. Regions are read until they can't be anymore.
+func (s Signatures) checkMemoryRegion(
+	mem io.ReaderAt,
+	region MemoryRegion,
+	chunkSize uint64,
+	opts CheckOptions,
+) []SigMatch {
+	var (
+		overlap = uint64(max(s.longestPattern()-1, 0))
+		matches []SigMatch
+	)
+
+	chunkSize = max(chunkSize, overlap+1)
+	buf := make([]byte, min(chunkSize, region.End-region.Start))
+
+	for start := region.Start; start < region.End; start += chunkSize - overlap {
+		end := min(start+chunkSize, region.End)
+		n, err := mem.ReadAt(buf[:end-start], int64(start))
+		if n == 0 && err != nil {
+			break
+		}
+
+		chunk := region
+		chunk.Start, chunk.End = start, start+uint64(n)
+
+		var skip int
+		if start > region.Start {
+			skip = int(overlap)
+		}
+		matches = append(matches, s.checkRegion(chunk, buf[:n], skip, opts)...)
+
+		if end == region.End || uint64(n) < end-start {
+			break
+		}
+	}
+
+	return matches
+}
+
+// longestPattern returns the length of the longest pattern of the signatures.
+func (s Signatures) longestPattern() int {
+	var longest int
+	for _, sig := range s {
+		for _, pattern := range sig.Patterns {
+			longest = max(longest, pattern.Length())
+		}
+	}
+
+	return longest
+}
+
+// checkRegion checks if the signatures match the data of the process' memory
+// region, like a file, and returns the matches of the public signatures, with
+// the region in their meta and their offsets moved to virtual addresses.
+//
+// When skip isn't zero, the data is a chunk of the region after the first one,
+// whose first skip bytes were checked in the previous chunk: offsets that end
+// in them are left out, and so are the matches left without offsets, which
+// were already reported, together with those of signatures without patterns.
+func (s Signatures) checkRegion(region MemoryRegion, data []byte, skip int, opts CheckOptions) []SigMatch {
+	var (
+		meta    = SigMatchMeta{FilePath: fmt.Sprintf("/proc/%d/mem", region.PID), Region: &region}
+		matches []SigMatch
+	)
+
+	for _, match := range s.checkFile(&scannedFile{data: data}, meta, opts) {
+		offsets := make(map[string]matchOffsets, len(match.Offsets))
+		for name, patternOffsets := range match.Offsets {
+			length := match.Signature.Patterns[name].Length()
+			for _, offset := range patternOffsets {
+				if offset+length > skip {
+					offsets[name] = append(offsets[name], int(region.Start)+offset)
+				}
+			}
+		}
+		if skip > 0 && len(offsets) == 0 {
+			continue
+		}
+
+		match.Offsets = offsets
+		matches = append(matches, match)
+	}
+
+	return matches
+}
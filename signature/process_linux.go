@@ -0,0 +1,47 @@
+package signature
+
+import (
+	"fmt"
+	"math"
+	"os"
+)
+
+// CheckProcess checks if the signatures match the memory of the process with
+// the given pid, with the optional features in the options. Each readable
+// region listed in /proc/<pid>/maps is read from /proc/<pid>/mem and checked
+// on its own, as if it were a separate file, skipping those that can't be read,
+// like [vvar]. Regions larger than 64 MiB are read and checked in chunks of
+// that size, each reported as a region of its own. Reading another process'
+// memory takes the same permissions as attaching a debugger to it.
+//
+// The matches have the region in their meta, and offsets that are virtual
+// addresses in the process.
+func (s Signatures) CheckProcess(pid int, opts CheckOptions) ([]SigMatch, error) {
+	maps, err := os.Open(fmt.Sprintf("/proc/%d/maps", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer maps.Close()
+
+	regions, err := parseMemoryMaps(pid, maps)
+	if err != nil {
+		return nil, err
+	}
+
+	mem, err := os.Open(fmt.Sprintf("/proc/%d/mem", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer mem.Close()
+
+	var matches []SigMatch
+	for _, region := range regions {
+		if !region.Readable() || region.End > math.MaxInt64 {
+			continue
+		}
+
+		matches = append(matches, s.checkMemoryRegion(mem, region, processChunkSize, opts)...)
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,58 @@
+package signature
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckProcess(t *testing.T) {
+	// The marker is only in the memory of the child process, in the environment
+	// strings at the top of its stack.
+	random := make([]byte, 8)
+	rand.Read(random)
+	marker := "BINMAT_TEST_MARKER=" + hex.EncodeToString(random)
+
+	child := exec.Command("sleep", "30")
+	child.Env = append(os.Environ(), marker)
+	if err := child.Start(); err != nil {
+		t.Skipf("Can't start the child process: %s", err)
+	}
+	defer func() {
+		child.Process.Kill()
+		child.Wait()
+	}()
+
+	var (
+		sig, _       = Make("marker", "", map[string]*SignaturePattern{"m": MakePattern([]byte(marker))}, "m")
+		matches, err = Signatures{sig}.CheckProcess(child.Process.Pid, CheckOptions{})
+		found        []SigMatch
+	)
+
+	assert.Nil(t, err)
+	for _, match := range matches {
+		if match.IsMatch {
+			found = append(found, match)
+		}
+	}
+
+	if assert.Len(t, found, 1) {
+		region := found[0].Meta.Region
+		assert.Equal(t, child.Process.Pid, region.PID)
+		assert.Equal(t, "[stack]", region.Path)
+		assert.True(t, region.Readable())
+
+		offset := uint64(found[0].Offsets["m"][0])
+		assert.True(t, offset >= region.Start && offset < region.End)
+	}
+
+	t.Run("processes that don't exist yield an error", func(t *testing.T) {
+		_, err := Signatures{sig}.CheckProcess(-1, CheckOptions{})
+
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package signature
+
+// CheckProcess checks if the signatures match the memory of the process with
+// the given pid, which is only supported on Linux.
+func (s Signatures) CheckProcess(pid int, opts CheckOptions) ([]SigMatch, error) {
+	return nil, ErrProcessUnsupported
+}
@@ -0,0 +1,120 @@
+package signature
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryMaps(t *testing.T) {
+	t.Run("parse the regions of a process", func(t *testing.T) {
+		maps := strings.Join([]string{
+			"561e1e88a000-561e1e88c000 r--p 00000000 fe:00 681885                     /usr/bin/head",
+			"561e1f000000-561e1f021000 rw-p 00000000 00:00 0                          [heap]",
+			"7f3c2a000000-7f3c2a021000 rw-p 00000000 00:00 0 ",
+			"7f3c2b000000-7f3c2b001000 ---p 00000000 fe:00 12 /tmp/my lib.so (deleted)",
+		}, "\n")
+
+		regions, err := parseMemoryMaps(42, strings.NewReader(maps))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []MemoryRegion{
+			{PID: 42, Start: 0x561e1e88a000, End: 0x561e1e88c000, Perms: "r--p", Path: "/usr/bin/head"},
+			{PID: 42, Start: 0x561e1f000000, End: 0x561e1f021000, Perms: "rw-p", Path: "[heap]"},
+			{PID: 42, Start: 0x7f3c2a000000, End: 0x7f3c2a021000, Perms: "rw-p"},
+			{PID: 42, Start: 0x7f3c2b000000, End: 0x7f3c2b001000, Perms: "---p", Path: "/tmp/my lib.so (deleted)"},
+		}, regions)
+		assert.True(t, regions[0].Readable())
+		assert.False(t, regions[3].Readable())
+		assert.Equal(t, "pid 42, 0x561e1f000000-0x561e1f021000 rw-p [heap]", regions[1].String())
+	})
+
+	for _, maps := range []string{
+		"561e1e88a000 r--p 00000000 fe:00 681885",
+		"561e1e88a000-zz r--p 00000000 fe:00 681885",
+		"561e1e88a000-561e1e88c000 r--p",
+	} {
+		t.Run("invalid line '"+maps+"' yields an error", func(t *testing.T) {
+			_, err := parseMemoryMaps(42, strings.NewReader(maps))
+
+			assert.NotNil(t, err)
+		})
+	}
+
+	t.Run("match offsets in a region are virtual addresses", func(t *testing.T) {
+		var (
+			sig, _  = Make("upx", "", map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}, "upx AND @upx == 4")
+			region  = MemoryRegion{PID: 42, Start: 0x1000, End: 0x100c, Perms: "r-xp"}
+			matches = Signatures{sig}.checkRegion(region, []byte("....UPX!...."), 0, CheckOptions{})
+		)
+
+		if assert.Len(t, matches, 1) {
+			assert.True(t, matches[0].IsMatch)
+			assert.Equal(t, matchOffsets{0x1004}, matches[0].Offsets["upx"])
+			assert.Equal(t, &region, matches[0].Meta.Region)
+			assert.Equal(t, "/proc/42/mem", matches[0].Meta.FilePath)
+		}
+	})
+
+	t.Run("large regions are checked in overlapping chunks", func(t *testing.T) {
+		var (
+			sig, _ = Make("upx", "", map[string]*SignaturePattern{
+				"upx": MakePattern([]byte("UPX!")),
+				"x":   MakePattern([]byte("X!")),
+			}, "upx OR x")
+			region  = MemoryRegion{PID: 42, Start: 0x1000, End: 0x1014, Perms: "rw-p"}
+			mem     = regionReader{start: 0x1000, data: []byte("UPX!..UPX!....UPX!..")}
+			matches = Signatures{sig}.checkMemoryRegion(mem, region, 8, CheckOptions{})
+			got     = make(map[string]matchOffsets)
+			chunks  []uint64
+		)
+
+		for _, match := range matches {
+			chunks = append(chunks, match.Meta.Region.Start)
+			for name, offsets := range match.Offsets {
+				got[name] = append(got[name], offsets...)
+			}
+		}
+		slices.Sort(got["upx"])
+		slices.Sort(got["x"])
+
+		// Chunks of 8 bytes overlap by 3, one less than the longest pattern. The
+		// last one, at 0x100f, only has matches already reported in the previous.
+		assert.Equal(t, []uint64{0x1000, 0x1005, 0x100a}, chunks)
+		assert.Equal(t, matchOffsets{0x1000, 0x1006, 0x100e}, got["upx"])
+		assert.Equal(t, matchOffsets{0x1002, 0x1008, 0x1010}, got["x"])
+	})
+
+	t.Run("matches in the overlap of two chunks are reported once", func(t *testing.T) {
+		var (
+			upx, _  = Make("upx", "", map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}, "upx")
+			x, _    = Make("x", "", map[string]*SignaturePattern{"x": MakePattern([]byte("X!"))}, "x")
+			size, _ = MakeWithRefs("size", "", nil, "filesize > 0", nil)
+			region  = MemoryRegion{PID: 42, Start: 0x1000, End: 0x1014, Perms: "rw-p"}
+			// "X!" is in the 3 bytes where the first two chunks overlap.
+			mem     = regionReader{start: 0x1000, data: []byte(".....X!.............")}
+			matches = Signatures{upx, x, size}.checkMemoryRegion(mem, region, 8, CheckOptions{})
+			got     []string
+		)
+
+		for _, match := range matches {
+			got = append(got, fmt.Sprintf("%s %t 0x%x", match.Signature.Name, match.IsMatch, match.Meta.Region.Start))
+		}
+
+		assert.Equal(t, []string{"upx false 0x1000", "x true 0x1000", "size true 0x1000"}, got)
+		assert.Equal(t, matchOffsets{0x1005}, matches[1].Offsets["x"])
+	})
+}
+
+// A regionReader reads the data of a memory region by its virtual addresses.
+type regionReader struct {
+	start int64
+	data  []byte
+}
+
+func (r regionReader) ReadAt(p []byte, addr int64) (int, error) {
+	return copy(p, r.data[addr-r.start:]), nil
+}
@@ -0,0 +1,137 @@
+package signature
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Profile records the time spent matching each signature's patterns, and the
+// number of candidate offsets each pattern had to compare: those where its
+// first byte matched. Patterns with many candidates are usually the slow ones.
+//
+// A Profile can be shared by concurrent checks.
+type Profile struct {
+	mu   sync.Mutex
+	sigs map[string]*SigProfile
+}
+
+// A SigProfile is the time spent matching a signature's patterns.
+type SigProfile struct {
+	Name string
+	// Duration is the total time spent matching the patterns, which are matched
+	// in parallel, so it can be longer than the wall time.
+	Duration time.Duration
+	// Files is the number of files the signature was checked against.
+	Files    int
+	Patterns map[string]*PatternProfile
+}
+
+// A PatternProfile is the time spent matching a pattern.
+type PatternProfile struct {
+	Name       string
+	Duration   time.Duration
+	Candidates int
+	Matches    int
+}
+
+func NewProfile() *Profile {
+	return &Profile{sigs: make(map[string]*SigProfile)}
+}
+
+// record adds a file's matching of the signature's patterns to the profile.
+func (p *Profile) record(sigName string, patterns []PatternProfile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sig, ok := p.sigs[sigName]
+	if !ok {
+		sig = &SigProfile{Name: sigName, Patterns: make(map[string]*PatternProfile)}
+		p.sigs[sigName] = sig
+	}
+
+	sig.Files++
+	for _, pattern := range patterns {
+		total, ok := sig.Patterns[pattern.Name]
+		if !ok {
+			total = &PatternProfile{Name: pattern.Name}
+			sig.Patterns[pattern.Name] = total
+		}
+
+		total.Duration += pattern.Duration
+		total.Candidates += pattern.Candidates
+		total.Matches += pattern.Matches
+		sig.Duration += pattern.Duration
+	}
+}
+
+// Slowest returns the n signatures that took the longest to match, slowest
+// first, or all of them if n isn't positive.
+func (p *Profile) Slowest(n int) []SigProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The patterns are copied, as they can change with the next check.
+	sigs := make([]SigProfile, 0, len(p.sigs))
+	for _, sig := range p.sigs {
+		sigCopy := *sig
+		sigCopy.Patterns = make(map[string]*PatternProfile, len(sig.Patterns))
+		for name, pattern := range sig.Patterns {
+			patternCopy := *pattern
+			sigCopy.Patterns[name] = &patternCopy
+		}
+		sigs = append(sigs, sigCopy)
+	}
+
+	sort.Slice(sigs, func(i, j int) bool {
+		if sigs[i].Duration != sigs[j].Duration {
+			return sigs[i].Duration > sigs[j].Duration
+		}
+		return sigs[i].Name < sigs[j].Name
+	})
+
+	if n > 0 && n < len(sigs) {
+		sigs = sigs[:n]
+	}
+
+	return sigs
+}
+
+// Write writes a report of the n slowest signatures, with the time spent on
+// each of their patterns, slowest first.
+func (p *Profile) Write(w io.StringWriter, n int) {
+	w.WriteString("================================================================================\n")
+	w.WriteString("Slowest signatures\n")
+	w.WriteString("================================================================================\n")
+
+	for _, sig := range p.Slowest(n) {
+		w.WriteString(fmt.Sprintf("%-40s %12s  %d files\n", sig.Name, sig.Duration, sig.Files))
+
+		for _, pattern := range sig.slowestPatterns() {
+			w.WriteString(fmt.Sprintf(
+				"    %-36s %12s  %d candidates, %d matches\n",
+				pattern.Name, pattern.Duration, pattern.Candidates, pattern.Matches,
+			))
+		}
+	}
+	w.WriteString("\n")
+}
+
+// slowestPatterns returns the signature's patterns, slowest first.
+func (s SigProfile) slowestPatterns() []PatternProfile {
+	patterns := make([]PatternProfile, 0, len(s.Patterns))
+	for _, pattern := range s.Patterns {
+		patterns = append(patterns, *pattern)
+	}
+
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Duration != patterns[j].Duration {
+			return patterns[i].Duration > patterns[j].Duration
+		}
+		return patterns[i].Name < patterns[j].Name
+	})
+
+	return patterns
+}
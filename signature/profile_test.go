@@ -0,0 +1,65 @@
+package signature
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile(t *testing.T) {
+	var (
+		mz, _ = Make("mz", "", map[string]*SignaturePattern{
+			"mz": MakePattern([]byte("MZ")),
+		}, "mz")
+		upx, _ = Make("upx", "", map[string]*SignaturePattern{
+			"upx": MakePattern([]byte("UPX!")),
+			"u":   MakePattern([]byte("UPX0")),
+		}, "upx OR u")
+		sigs    = Signatures{mz, upx}
+		profile = NewProfile()
+		binPath = filepath.Join(t.TempDir(), "bin")
+	)
+
+	if err := os.WriteFile(binPath, []byte("MZ..UPX!..UPX!..UM.."), 0o644); err != nil {
+		t.Fatalf("Can't write test file: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := sigs.CheckWithOptions(binPath, CheckOptions{Profile: profile}); err != nil {
+			t.Fatalf("Can't check the test file: %s", err)
+		}
+	}
+
+	slowest := profile.Slowest(0)
+
+	if !assert.Len(t, slowest, 2) {
+		return
+	}
+	assert.GreaterOrEqual(t, slowest[0].Duration, slowest[1].Duration)
+
+	for _, sig := range slowest {
+		assert.Equal(t, 2, sig.Files)
+
+		if sig.Name == "upx" {
+			// The last "U" can start either pattern, as it's 4 bytes from the end.
+			assert.Equal(t, 6, sig.Patterns["upx"].Candidates)
+			assert.Equal(t, 4, sig.Patterns["upx"].Matches)
+			assert.Equal(t, 6, sig.Patterns["u"].Candidates)
+			assert.Equal(t, 0, sig.Patterns["u"].Matches)
+		} else {
+			assert.Equal(t, 4, sig.Patterns["mz"].Candidates)
+			assert.Equal(t, 2, sig.Patterns["mz"].Matches)
+		}
+	}
+
+	t.Run("limits the reported signatures", func(t *testing.T) {
+		assert.Len(t, profile.Slowest(1), 1)
+
+		var report strings.Builder
+		profile.Write(&report, 1)
+		assert.Equal(t, 1, strings.Count(report.String(), "files\n"))
+	})
+}
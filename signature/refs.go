@@ -0,0 +1,115 @@
+package signature
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// An ErrRef is the cause of an ErrSignature linking signatures, and points at
+// the offending reference.
+type ErrRef struct {
+	// Signature is the name of the signature with the reference.
+	Signature string
+	// Ref is the referenced name.
+	Ref string
+	// Cycle are the names of the signatures in the reference cycle, if any.
+	// The first and last names are the same.
+	Cycle []string
+}
+
+func (e ErrRef) Error() string {
+	if len(e.Cycle) > 0 {
+		return strings.Join(e.Cycle, " -> ")
+	}
+
+	return fmt.Sprintf("'%s' references '%s'", e.Signature, e.Ref)
+}
+
+// Link checks that every signature referenced in the conditions exists, and
+// returns the signatures ordered so that each one comes after all the
+// signatures it references. Otherwise, the original order is kept.
+//
+// The returned error is an ErrSignature if:
+//   - a referenced signature doesn't exist (ErrSigUnknownRef)
+//   - more than one signature has the referenced name (ErrSigAmbiguousRef)
+//   - signatures reference each other in a cycle (ErrSigRefCycle)
+func Link(sigs []Signature) (Signatures, error) {
+	byName := make(map[string][]int, len(sigs))
+	for i, sig := range sigs {
+		byName[sig.Name] = append(byName[sig.Name], i)
+	}
+
+	for _, sig := range sigs {
+		for _, ref := range sig.Refs {
+			switch len(byName[ref]) {
+			case 0:
+				return nil, ErrSignature{
+					reason: ErrSigUnknownRef,
+					cause:  ErrRef{Signature: sig.Name, Ref: ref},
+				}
+			case 1:
+			default:
+				return nil, ErrSignature{
+					reason: ErrSigAmbiguousRef,
+					cause:  ErrRef{Signature: sig.Name, Ref: ref},
+				}
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var (
+		linked = make(Signatures, 0, len(sigs))
+		state  = make([]int, len(sigs))
+		// path are the names of the signatures being visited, to report cycles.
+		path  []string
+		visit func(i int) error
+	)
+
+	// visit appends the signature at index i after the signatures it references,
+	// using a depth-first traversal.
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			var (
+				cycle = append(path[slices.Index(path, sigs[i].Name):], sigs[i].Name)
+				last  = len(cycle) - 1
+			)
+			return ErrSignature{
+				reason: ErrSigRefCycle,
+				cause:  ErrRef{Signature: cycle[last-1], Ref: cycle[last], Cycle: cycle},
+			}
+		}
+
+		state[i] = visiting
+		path = append(path, sigs[i].Name)
+
+		for _, ref := range sigs[i].Refs {
+			if err := visit(byName[ref][0]); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[i] = visited
+		linked = append(linked, sigs[i])
+
+		return nil
+	}
+
+	for i := range sigs {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return linked, nil
+}
@@ -0,0 +1,126 @@
+package signature
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLink(t *testing.T) {
+	patterns := map[string]*SignaturePattern{
+		"a": MakePattern([]byte{0x01, 0x02, 0x03}),
+	}
+
+	mustMake := func(name, condition string, refs ...string) Signature {
+		sig, err := MakeWithRefs(name, "", patterns, condition, refs)
+		if err != nil {
+			t.Fatalf("Want no error, got %s", err)
+		}
+		return sig
+	}
+
+	names := func(sigs Signatures) []string {
+		var names []string
+		for _, sig := range sigs {
+			names = append(names, sig.Name)
+		}
+		return names
+	}
+
+	t.Run("references are recorded", func(t *testing.T) {
+		sig := mustMake("main", "a AND (upx OR NOT other)", "upx", "other", "unused")
+
+		assert.Equal(t, []string{"other", "upx"}, sig.Refs)
+	})
+
+	t.Run("signatures with only references don't need patterns", func(t *testing.T) {
+		_, err := MakeWithRefs("main", "", nil, "upx AND other", []string{"upx", "other"})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("referenced signatures come first", func(t *testing.T) {
+		linked, err := Link([]Signature{
+			mustMake("main", "a AND upx", "upx", "elf"),
+			mustMake("other", "a"),
+			mustMake("upx", "a AND NOT elf", "elf"),
+			mustMake("elf", "a"),
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"elf", "upx", "main", "other"}, names(linked))
+	})
+
+	t.Run("unknown references", func(t *testing.T) {
+		_, err := Link([]Signature{mustMake("main", "a AND upx", "upx")})
+
+		assert.Equal(t, ErrSigUnknownRef, err.(ErrSignature).Reason())
+	})
+
+	t.Run("ambiguous references", func(t *testing.T) {
+		_, err := Link([]Signature{
+			mustMake("main", "a AND upx", "upx"),
+			mustMake("upx", "a"),
+			mustMake("upx", "a"),
+		})
+
+		assert.Equal(t, ErrSigAmbiguousRef, err.(ErrSignature).Reason())
+	})
+
+	t.Run("reference cycles", func(t *testing.T) {
+		_, err := Link([]Signature{
+			mustMake("main", "a AND one", "one"),
+			mustMake("one", "a AND two", "two"),
+			mustMake("two", "a OR one", "one"),
+		})
+
+		var refErr ErrRef
+		assert.Equal(t, ErrSigRefCycle, err.(ErrSignature).Reason())
+		if assert.True(t, errors.As(err, &refErr)) {
+			assert.Equal(t, []string{"one", "two", "one"}, refErr.Cycle)
+		}
+	})
+
+	t.Run("check evaluates references and hides private signatures", func(t *testing.T) {
+		binPath := filepath.Join(t.TempDir(), "bin")
+		os.WriteFile(binPath, []byte{0x00, 0x01, 0x02, 0x03, 0x00}, 0o644)
+
+		helper := mustMake("helper", "a")
+		helper.Private = true
+		missing, _ := MakeWithRefs("missing", "", map[string]*SignaturePattern{
+			"b": MakePattern([]byte{0x04, 0x05, 0x06}),
+		}, "b", nil)
+
+		linked, err := Link([]Signature{
+			mustMake("main", "helper AND NOT missing", "helper", "missing"),
+			helper,
+			missing,
+		})
+		assert.Nil(t, err)
+
+		matches, err := linked.Check(binPath)
+
+		assert.Nil(t, err)
+		if assert.Len(t, matches, 2) {
+			assert.Equal(t, "missing", matches[0].Signature.Name)
+			assert.False(t, matches[0].IsMatch)
+			assert.Equal(t, "main", matches[1].Signature.Name)
+			assert.True(t, matches[1].IsMatch)
+		}
+	})
+
+	t.Run("filtering keeps the referenced signatures as private", func(t *testing.T) {
+		main := mustMake("main", "a AND upx", "upx")
+		main.Tags = []string{"selected"}
+		linked, _ := Link([]Signature{main, mustMake("upx", "a"), mustMake("other", "a")})
+
+		filtered := linked.Filter(ParseTagsFilter("selected"))
+
+		assert.Equal(t, []string{"upx", "main"}, names(filtered))
+		assert.True(t, filtered[0].Private)
+		assert.False(t, filtered[1].Private)
+	})
+}
@@ -0,0 +1,170 @@
+package signature
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"fmt"
+	"strings"
+)
+
+// A Scope restricts the region of the file where a pattern is searched for.
+// Matches outside the region are ignored, and the rest of the file isn't even
+// scanned. The zero Scope is the whole file.
+type Scope struct {
+	// Section is the name of a section of an ELF, PE or Mach-O file. Mach-O
+	// sections can be qualified with their segment, as in "__TEXT,__text".
+	Section string
+	// Start and End are the offsets of a range of the file, End excluded, if End
+	// isn't zero.
+	Start, End int
+	// Overlay is the data appended to an ELF, PE or Mach-O file, after the end
+	// of the file as described by its headers.
+	Overlay bool
+}
+
+// IsZero returns true if the scope is the whole file.
+func (s Scope) IsZero() bool {
+	return s == Scope{}
+}
+
+func (s Scope) String() string {
+	switch {
+	case s.Section != "":
+		return "section " + s.Section
+	case s.Overlay:
+		return "overlay"
+	case s.End > 0:
+		return fmt.Sprintf("range [%d, %d)", s.Start, s.End)
+	}
+
+	return "file"
+}
+
+// region returns the offsets where the scope starts and ends in the file, or
+// false if the file doesn't have the scope, like a section of a file that
+// isn't in any of the supported formats.
+func (s Scope) region(f *scannedFile) (int, int, bool) {
+	var (
+		start, end int
+		ok         = true
+	)
+
+	switch {
+	case s.Section != "":
+		start, end, ok = sectionRegion(f, s.Section)
+	case s.Overlay:
+		start, ok = overlayStart(f)
+		end = len(f.data)
+	case s.End > 0:
+		start, end = s.Start, s.End
+	default:
+		end = len(f.data)
+	}
+
+	// The headers of malformed files, and ranges, can point past the data.
+	end = min(max(end, 0), len(f.data))
+	start = min(max(start, 0), end)
+
+	return start, end, ok
+}
+
+// sectionRegion returns the offsets where the named section of the ELF, PE or
+// Mach-O file starts and ends, or false if the file doesn't have the section.
+func sectionRegion(f *scannedFile, name string) (int, int, bool) {
+	if parsed := f.module("pe", parsePE).(*peFile); parsed != nil {
+		if section := parsed.section(name); section != nil {
+			return int(section.Offset), int(section.Offset) + len(section.data), true
+		}
+		return 0, 0, false
+	}
+
+	if parsed := f.module("elf", parseELF).(*elfFile); parsed != nil {
+		section := parsed.file.Section(name)
+		if section == nil {
+			return 0, 0, false
+		}
+		// Sections like .bss take no space in the file.
+		if section.Type == elf.SHT_NOBITS {
+			return 0, 0, true
+		}
+		return int(section.Offset), int(section.Offset + section.FileSize), true
+	}
+
+	if parsed := f.module("macho", parseMachO).(*machoFile); parsed != nil && parsed.file != nil {
+		segment, sectionName, qualified := strings.Cut(name, ",")
+		for _, section := range parsed.file.Sections {
+			if qualified && section.Seg == segment && section.Name == sectionName ||
+				!qualified && section.Name == name {
+				return int(section.Offset), int(section.Offset) + int(section.Size), true
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// overlayStart returns the offset where the overlay of the ELF, PE or Mach-O
+// file starts: the end of the file as described by its headers. It returns
+// false if the file isn't in any of these formats.
+func overlayStart(f *scannedFile) (int, bool) {
+	var start uint64
+
+	if parsed := f.module("pe", parsePE).(*peFile); parsed != nil {
+		for _, section := range parsed.sections {
+			start = max(start, uint64(section.Offset)+uint64(len(section.data)))
+		}
+		return int(min(start, uint64(len(f.data)))), true
+	}
+
+	if parsed := f.module("elf", parseELF).(*elfFile); parsed != nil {
+		for _, section := range parsed.file.Sections {
+			if section.Type != elf.SHT_NOBITS {
+				start = max(start, section.Offset+section.FileSize)
+			}
+		}
+		for _, prog := range parsed.file.Progs {
+			start = max(start, prog.Off+prog.Filesz)
+		}
+		start = max(start, elfHeaderTablesEnd(parsed.file, f.data))
+		return int(min(start, uint64(len(f.data)))), true
+	}
+
+	if parsed := f.module("macho", parseMachO).(*machoFile); parsed != nil && parsed.file != nil {
+		// Segments hold every section, and the __LINKEDIT segment the symbols
+		// and the code signature.
+		for _, load := range parsed.file.Loads {
+			if segment, ok := load.(*macho.Segment); ok {
+				start = max(start, segment.Offset+segment.Filesz)
+			}
+		}
+		return int(min(start, uint64(len(f.data)))), true
+	}
+
+	return 0, false
+}
+
+// elfHeaderTablesEnd returns the offset where the program and section header
+// tables of the ELF file end, which debug/elf doesn't expose, reading them from
+// the data.
+func elfHeaderTablesEnd(file *elf.File, data []byte) uint64 {
+	var phoff, phentsize, phnum, shoff, shentsize, shnum uint64
+
+	switch {
+	case file.Class == elf.ELFCLASS64 && len(data) >= 64:
+		phoff = file.ByteOrder.Uint64(data[0x20:])
+		phentsize = uint64(file.ByteOrder.Uint16(data[0x36:]))
+		phnum = uint64(file.ByteOrder.Uint16(data[0x38:]))
+		shoff = file.ByteOrder.Uint64(data[0x28:])
+		shentsize = uint64(file.ByteOrder.Uint16(data[0x3a:]))
+		shnum = uint64(file.ByteOrder.Uint16(data[0x3c:]))
+	case file.Class == elf.ELFCLASS32 && len(data) >= 52:
+		phoff = uint64(file.ByteOrder.Uint32(data[0x1c:]))
+		phentsize = uint64(file.ByteOrder.Uint16(data[0x2a:]))
+		phnum = uint64(file.ByteOrder.Uint16(data[0x2c:]))
+		shoff = uint64(file.ByteOrder.Uint32(data[0x20:]))
+		shentsize = uint64(file.ByteOrder.Uint16(data[0x2e:]))
+		shnum = uint64(file.ByteOrder.Uint16(data[0x30:]))
+	}
+
+	return max(phoff+phentsize*phnum, shoff+shentsize*shnum)
+}
@@ -0,0 +1,81 @@
+package signature
+
+import (
+	"debug/macho"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatternScope(t *testing.T) {
+	var (
+		overlay = []byte("....UPX!....")
+		pe      = makeTestPE()
+		elf     = makeTestELF()
+		data    = []byte("....UPX!....")
+	)
+
+	for _, tCase := range []struct {
+		name  string
+		data  []byte
+		scope Scope
+		want  matchOffsets
+	}{
+		{name: "PE section", data: pe, scope: Scope{Section: ".text"}, want: matchOffsets{0x200}},
+		{name: "other PE section", data: pe, scope: Scope{Section: ".rdata"}},
+		{name: "missing PE section", data: pe, scope: Scope{Section: ".upx0"}},
+		{name: "ELF section", data: elf, scope: Scope{Section: ".upx"}, want: matchOffsets{88}},
+		{name: "other ELF section", data: elf, scope: Scope{Section: ".shstrtab"}},
+		{
+			name:  "Mach-O section",
+			data:  makeTestMachO(macho.CpuAmd64),
+			scope: Scope{Section: "__text"},
+			want:  matchOffsets{0x200},
+		},
+		{
+			name:  "Mach-O section of a segment",
+			data:  makeTestMachO(macho.CpuAmd64),
+			scope: Scope{Section: "__TEXT,__text"},
+			want:  matchOffsets{0x200},
+		},
+		{name: "Mach-O section of other segment", data: makeTestMachO(macho.CpuAmd64), scope: Scope{Section: "__DATA,__text"}},
+		{name: "section of other files", data: data, scope: Scope{Section: ".text"}},
+		{name: "range", data: pe, scope: Scope{Start: 0x100, End: 0x300}, want: matchOffsets{0x200}},
+		{name: "range before the match", data: pe, scope: Scope{Start: 0, End: 0x200}},
+		{name: "range after the match", data: pe, scope: Scope{Start: 0x201, End: 0x300}},
+		{name: "range ending at the end of the match", data: data, scope: Scope{Start: 0, End: 8}, want: matchOffsets{4}},
+		{name: "whole file ending at the end of the match", data: data[:8], want: matchOffsets{4}},
+		{name: "range past the end", data: data, scope: Scope{Start: 2, End: 4096}, want: matchOffsets{4}},
+		{name: "PE overlay", data: append(pe, overlay...), scope: Scope{Overlay: true}, want: matchOffsets{len(pe) + 4}},
+		{name: "PE without overlay", data: pe, scope: Scope{Overlay: true}},
+		{name: "ELF overlay", data: append(elf, overlay...), scope: Scope{Overlay: true}, want: matchOffsets{len(elf) + 4}},
+		{name: "ELF without overlay", data: elf, scope: Scope{Overlay: true}},
+		{name: "overlay of other files", data: data, scope: Scope{Overlay: true}},
+		{name: "whole file", data: data, want: matchOffsets{4}},
+	} {
+		t.Run(fmt.Sprintf("%s (%s)", tCase.name, tCase.scope), func(t *testing.T) {
+			var (
+				upx      = MakePattern([]byte("UPX!")).WithScope(tCase.scope)
+				sig, err = Make("scoped", "", map[string]*SignaturePattern{"upx": upx}, "upx")
+			)
+			if err != nil {
+				t.Fatalf("Want no error, got %s", err)
+			}
+
+			match := sig.CheckMatch(tCase.data)
+
+			assert.Equal(t, tCase.want != nil, match.IsMatch)
+			assert.Equal(t, tCase.want, match.Offsets["upx"])
+		})
+	}
+
+	t.Run("WithScope doesn't change the pattern", func(t *testing.T) {
+		pattern := MakePattern([]byte("UPX!"))
+		scoped := pattern.WithScope(Scope{Overlay: true})
+
+		assert.True(t, pattern.Scope().IsZero())
+		assert.Equal(t, Scope{Overlay: true}, scoped.Scope())
+		assert.True(t, pattern.Equal(scoped))
+	})
+}
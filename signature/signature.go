@@ -1,7 +1,9 @@
 package signature
 
 import (
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/angelsolaorbaiceta/binmat/bexpr"
 )
@@ -16,17 +18,49 @@ type Signature struct {
 	Description string
 	Patterns    map[string]*SignaturePattern
 	Condition   string
+	// Tags are free-form labels used to classify and select signatures.
+	Tags []string
+	// Meta are free-form key-value pairs, like the "author" or "severity".
+	Meta map[string]string
+	// Source is the path to the file the signature was loaded from, if any.
+	Source string
+	// Refs are the sorted names of the other signatures used in the condition.
+	Refs []string
+	// Private signatures are only meant to be referenced by other signatures, so
+	// their matches aren't reported.
+	Private bool
+
 	conditionFn bexpr.Condition
+	// comparisons are the comparisons of file values in the condition.
+	comparisons []comparison
 }
 
 // Make creates a new Signature with the given name, description, patterns,
 // and condition.
 // If the condition can't be successfully parsed, an error is returned.
 // If any of the pattern names doesn't adhere to the convention, an error is returned.
+//
+// The condition isn't statically analyzed, which can be slow for conditions
+// with many variables: call Warnings to find the likely mistakes in it.
 func Make(
 	name, description string,
 	patterns map[string]*SignaturePattern,
 	condition string,
+) (Signature, error) {
+	return MakeWithRefs(name, description, patterns, condition, nil)
+}
+
+// MakeWithRefs creates a new Signature, like Make does, whose condition can also
+// use the results of other signatures, named in refs, as variables.
+// Patterns take precedence over referenced signatures with the same name.
+//
+// A signature with references can't be checked on its own: it has to be part
+// of the Signatures returned by Link, which evaluates its references first.
+func MakeWithRefs(
+	name, description string,
+	patterns map[string]*SignaturePattern,
+	condition string,
+	refs []string,
 ) (Signature, error) {
 	var signature Signature
 
@@ -34,75 +68,178 @@ func Make(
 		return signature, ErrSignature{reason: ErrSigEmptyName}
 	}
 
-	if len(patterns) == 0 {
-		return signature, ErrSignature{reason: ErrSigEmptyPatterns}
-	}
-
 	if len(strings.TrimSpace(condition)) == 0 {
 		return signature, ErrSignature{reason: ErrSigWrongCondition}
 	}
 
-	conditionFn, err := bexpr.ParseCondition(condition)
+	expr, err := bexpr.Parse(condition)
 	if err != nil {
 		return signature, ErrSignature{reason: ErrSigWrongCondition, cause: err}
 	}
 
-	// Create a map where all pattern names are assigned "true" to test if the
-	// conditionFn has all the variables it needs.
+	// Signatures can do without patterns if their condition only compares file
+	// values (e.g. sha256 == "e3b0...").
+	if len(patterns) == 0 && len(refs) == 0 && len(expr.Comparisons()) == 0 {
+		return signature, ErrSignature{reason: ErrSigEmptyPatterns}
+	}
+
+	// Create a map where all pattern and referenced signature names are assigned
+	// "true" to test if the conditionFn has all the variables it needs.
 	varsMap := make(map[string]bool)
+	for _, ref := range refs {
+		varsMap[ref] = true
+	}
 	for name := range patterns {
 		varsMap[name] = true
 	}
+	for _, cmp := range expr.Comparisons() {
+		compiled, err := makeComparison(cmp, patterns)
+		if err != nil {
+			return signature, ErrSignature{reason: ErrSigInvalidComparison, cause: err}
+		}
 
-	if _, err := conditionFn(varsMap); err != nil {
+		signature.comparisons = append(signature.comparisons, compiled)
+		varsMap[compiled.key] = true
+	}
+
+	if _, err := expr.Eval(varsMap); err != nil {
 		return signature, ErrSignature{reason: ErrSigMissingPattern, cause: err}
 	}
 
+	for _, name := range expr.Vars() {
+		if _, isPattern := patterns[name]; !isPattern {
+			signature.Refs = append(signature.Refs, name)
+		}
+	}
+
+	if len(patterns) == 0 && len(signature.Refs) == 0 && len(signature.comparisons) == 0 {
+		return signature, ErrSignature{reason: ErrSigEmptyPatterns}
+	}
+
 	signature.Name = name
 	signature.Description = description
 	signature.Patterns = patterns
 	signature.Condition = condition
-	signature.conditionFn = conditionFn
+	signature.conditionFn = expr.Eval
 
 	return signature, nil
 }
 
+// Warnings statically analyzes the condition, and returns the problems found:
+// unused or irrelevant patterns, contradictions and tautologies. They don't
+// prevent the signature from being used, but are likely mistakes.
+//
+// The analysis evaluates the condition for every combination of its variables,
+// up to a limit, so it's only meant for validating signatures, not for loading
+// them to be checked. Nothing is stored in the signature: each call parses the
+// condition again and repeats the whole analysis, so keep the result instead of
+// calling it again.
+func (s *Signature) Warnings() []Warning {
+	expr, err := bexpr.Parse(s.Condition)
+	if err != nil {
+		return nil
+	}
+
+	return analyzeCondition(s.Patterns, expr)
+}
+
+// HasTag returns true if the signature is labeled with the given tag.
+func (s *Signature) HasTag(tag string) bool {
+	return slices.Contains(s.Tags, tag)
+}
+
+// Severity returns the severity set in the signature's meta, or SeverityNone
+// if it has no known severity.
+func (s *Signature) Severity() Severity {
+	severity, _ := ParseSeverity(s.Meta["severity"])
+	return severity
+}
+
 // CheckMatch reads the file from the byte slice and checks each of the patterns
 // in the signature in parallel. It returns a SigMatches struct with the results.
 //
 // The function expects the full file contents in a byte slice, as binaries themselves
 // are usually small enough to fit in memory.
+//
+// Signatures referencing other signatures never match when checked on their
+// own. Use Signatures.Check instead.
 func (s Signature) CheckMatch(data []byte) SigMatch {
+	file := &scannedFile{data: data}
+	return s.evaluate(s.matchPatterns(file, nil), nil, file)
+}
+
+// matchPatterns checks each of the patterns in the signature in parallel, in
+// their scope of the file, and returns the offsets where each of them matches.
+// The time spent on each pattern is recorded in the profile, if not nil.
+func (s *Signature) matchPatterns(file *scannedFile, profile *Profile) map[string]matchOffsets {
 	ch := make(chan struct {
-		name    string
 		matches matchOffsets
+		profile PatternProfile
 	})
 
 	for name, pattern := range s.Patterns {
 		go func(name string, pattern *SignaturePattern) {
+			start := time.Now()
+			matches, candidates := pattern.scanFile(file)
+
 			ch <- struct {
-				name    string
 				matches matchOffsets
+				profile PatternProfile
 			}{
-				name:    name,
-				matches: pattern.checkMatch(data),
+				matches: matches,
+				profile: PatternProfile{
+					Name:       name,
+					Duration:   time.Since(start),
+					Candidates: candidates,
+					Matches:    matches.len(),
+				},
 			}
 		}(name, pattern)
 	}
 
 	var (
 		matchOffs = make(map[string]matchOffsets)
-		matchVars = make(map[string]bool)
+		patterns  = make([]PatternProfile, 0, len(s.Patterns))
 	)
 	for range s.Patterns {
 		match := <-ch
-		matchOffs[match.name] = match.matches
-		matchVars[match.name] = match.matches.isMatch()
+		matchOffs[match.profile.Name] = match.matches
+		patterns = append(patterns, match.profile)
 	}
 
-	// All the variables names (patterns) in the condition have been checked to
-	// be present in the patterns map. No error should be returned here.
-	isMatch, _ := s.conditionFn(matchVars)
+	if profile != nil {
+		profile.record(s.Name, patterns)
+	}
+
+	return matchOffs
+}
+
+// evaluate applies the condition to the offsets where each pattern matched, to
+// the results of the referenced signatures and to the values of the file, and
+// returns the SigMatch.
+// Missing referenced signature results make the condition evaluate to false.
+func (s Signature) evaluate(
+	matchOffs map[string]matchOffsets,
+	refResults map[string]bool,
+	file *scannedFile,
+) SigMatch {
+	matchVars := make(map[string]bool)
+	for _, ref := range s.Refs {
+		matchVars[ref] = refResults[ref]
+	}
+	for _, cmp := range s.comparisons {
+		matchVars[cmp.key] = cmp.eval(file, matchOffs)
+	}
+	for name, offsets := range matchOffs {
+		matchVars[name] = offsets.isMatch()
+	}
+
+	var isMatch bool
+	if len(s.Refs) == 0 || hasAllRefs(s.Refs, refResults) {
+		// All the variables names (patterns and references) in the condition have
+		// been checked to be present in the map. No error should be returned here.
+		isMatch, _ = s.conditionFn(matchVars)
+	}
 
 	return SigMatch{
 		IsMatch:   isMatch,
@@ -110,3 +247,13 @@ func (s Signature) CheckMatch(data []byte) SigMatch {
 		Offsets:   matchOffs,
 	}
 }
+
+func hasAllRefs(refs []string, refResults map[string]bool) bool {
+	for _, ref := range refs {
+		if _, ok := refResults[ref]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
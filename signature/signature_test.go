@@ -125,3 +125,44 @@ func TestSignature(t *testing.T) {
 		assert.Nil(t, cOff)
 	})
 }
+
+func TestSignatureWarnings(t *testing.T) {
+	patterns := map[string]*SignaturePattern{
+		"a": nil,
+		"b": nil,
+		"c": nil,
+	}
+
+	for _, tCase := range []struct {
+		condition string
+		want      []Warning
+	}{
+		{
+			condition: "a AND (b OR c)",
+			want:      nil,
+		},
+		{
+			condition: "a AND c",
+			want:      []Warning{{Reason: WarnSigUnusedPattern, Pattern: "b"}},
+		},
+		{
+			condition: "c AND (a OR (a AND b))",
+			want:      []Warning{{Reason: WarnSigIrrelevantPattern, Pattern: "b"}},
+		},
+		{
+			condition: "b AND (c AND (a AND NOT a))",
+			want:      []Warning{{Reason: WarnSigContradiction}},
+		},
+		{
+			condition: "c OR (b OR (a OR NOT a))",
+			want:      []Warning{{Reason: WarnSigTautology}},
+		},
+	} {
+		t.Run(tCase.condition, func(t *testing.T) {
+			sig, err := Make("name", "description", patterns, tCase.condition)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tCase.want, sig.Warnings())
+		})
+	}
+}
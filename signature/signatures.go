@@ -1,6 +1,7 @@
 package signature
 
 import (
+	"io"
 	"io/fs"
 	"path/filepath"
 )
@@ -8,41 +9,181 @@ import (
 // Signatures is a collection of byte Signatures.
 type Signatures []Signature
 
+// CheckOptions are the optional features of a check.
+type CheckOptions struct {
+	// Profile records the time spent matching each signature, if not nil.
+	Profile *Profile
+	// Hashes computes the hashes of the checked files, to be reported in the
+	// matches' meta. Otherwise, hashes are only computed, and reported, if a
+	// condition compares them.
+	Hashes bool
+	// Entropy computes the entropy of the checked files, to be reported in the
+	// matches' meta.
+	Entropy bool
+	// Archives unpacks the members of zip, tar and gzip files, with these
+	// options, to check them as files of their own, if not nil.
+	Archives *ArchiveOptions
+}
+
 // Check reads the file from the byte slice and checks if the signatures match.
 // It returns all the matches found, or an error if there is a problem reading the file.
+//
+// The patterns of all signatures are checked in parallel. Then, the conditions
+// are evaluated in order, so signatures referencing others must come after them,
+// as returned by Link. Private signatures aren't included in the results.
 func (s Signatures) Check(binPath string) ([]SigMatch, error) {
-	var (
-		results = make(chan SigMatch)
-		matches []SigMatch
-		match   SigMatch
-	)
+	return s.CheckWithOptions(binPath, CheckOptions{})
+}
 
+// CheckWithOptions checks if the signatures match the file, like Check does,
+// with the optional features in the options.
+//
+// The architecture slices of fat Mach-O files are also checked on their own,
+// after the whole file, as if they were separate files. Their matches have the
+// slice's architecture in the meta, and offsets relative to the slice.
+//
+// If the options unpack archives, their members are checked after them, the
+// same way, up to the options' depth. Their matches have the path of the
+// archive and the member, joined by ArchiveSeparator, in the meta (e.g.
+// bundle.zip!inner/bin.exe).
+func (s Signatures) CheckWithOptions(binPath string, opts CheckOptions) ([]SigMatch, error) {
 	data, err := readFileBytes(binPath)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, sig := range s {
-		go func(sig *Signature) {
-			match = sig.CheckMatch(data)
-			match.Meta = SigMatchMeta{FilePath: binPath}
-			results <- match
-		}(&sig)
+	return s.CheckData(binPath, data, opts), nil
+}
+
+// CheckReader reads the data from the reader, like the contents of a pipe, and
+// checks if the signatures match it, as CheckWithOptions does with files. The
+// name is the file path of the matches' meta, to tell where the data came from.
+func (s Signatures) CheckReader(name string, r io.Reader, opts CheckOptions) ([]SigMatch, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
 	}
 
-	for range s {
-		match := <-results
-		if match.Len() > 0 {
+	return s.CheckData(name, data, opts), nil
+}
+
+// CheckData checks if the signatures match the data, as CheckWithOptions does
+// with files. The name is the file path of the matches' meta, to tell where the
+// data came from.
+func (s Signatures) CheckData(name string, data []byte, opts CheckOptions) []SigMatch {
+	var unpacker *archiveUnpacker
+	if opts.Archives != nil {
+		unpacker = newArchiveUnpacker(*opts.Archives)
+	}
+
+	return s.checkFileData(name, data, opts, unpacker, 0)
+}
+
+// checkFileData checks if the signatures match the data of the file at the
+// given path, its fat Mach-O slices and, if the unpacker isn't nil, the members
+// of the archives it's nested in up to the unpacker's depth, with the given
+// options.
+func (s Signatures) checkFileData(
+	filePath string,
+	data []byte,
+	opts CheckOptions,
+	unpacker *archiveUnpacker,
+	depth int,
+) []SigMatch {
+	var (
+		file    = &scannedFile{data: data}
+		matches = s.checkFile(file, SigMatchMeta{FilePath: filePath}, opts)
+	)
+
+	for _, slice := range machoSlices(file) {
+		sliceMeta := SigMatchMeta{FilePath: filePath, Arch: slice.arch, Offset: slice.offset}
+		matches = append(matches, s.checkFile(&scannedFile{data: slice.data}, sliceMeta, opts)...)
+	}
+
+	if unpacker != nil && depth < unpacker.opts.Depth {
+		unpacker.unpack(filePath, data, func(name string, memberData []byte) {
+			memberPath := filePath + ArchiveSeparator + name
+			matches = append(matches, s.checkFileData(memberPath, memberData, opts, unpacker, depth+1)...)
+		})
+	}
+
+	return matches
+}
+
+// checkFile checks if the signatures match the scanned file, and returns the
+// matches of the public signatures, with the given meta.
+func (s Signatures) checkFile(file *scannedFile, meta SigMatchMeta, opts CheckOptions) []SigMatch {
+	var (
+		matches []SigMatch
+		results = s.checkData(file, opts.Profile)
+	)
+
+	if opts.Hashes || file.hashes != nil {
+		meta.Hashes = file.fileHashes()
+	}
+	if opts.Entropy {
+		entropy := file.fileEntropy()
+		meta.Entropy = &entropy
+	}
+
+	for _, match := range results {
+		match.Meta = meta
+		if !match.Signature.Private {
 			matches = append(matches, match)
 		}
 	}
 
-	return matches, nil
+	return matches
+}
+
+// checkData checks if the signatures match the file, and returns the result of
+// every signature, private ones included, in order. The time spent matching
+// each signature is recorded in the profile, if not nil.
+func (s Signatures) checkData(file *scannedFile, profile *Profile) []SigMatch {
+	var (
+		results = make(chan struct {
+			idx       int
+			matchOffs map[string]matchOffsets
+		})
+		sigOffs    = make([]map[string]matchOffsets, len(s))
+		refResults = make(map[string]bool)
+		matches    = make([]SigMatch, len(s))
+	)
+
+	for i := range s {
+		go func(i int) {
+			results <- struct {
+				idx       int
+				matchOffs map[string]matchOffsets
+			}{
+				idx:       i,
+				matchOffs: s[i].matchPatterns(file, profile),
+			}
+		}(i)
+	}
+
+	for range s {
+		result := <-results
+		sigOffs[result.idx] = result.matchOffs
+	}
+
+	for i, sig := range s {
+		matches[i] = sig.evaluate(sigOffs[i], refResults, file)
+		refResults[sig.Name] = matches[i].IsMatch
+	}
+
+	return matches
 }
 
 // CheckDir checks every file inside the directory for matches against these
 // signatures.
 func (s Signatures) CheckDir(dirPath string) ([]SigMatch, error) {
+	return s.CheckDirWithOptions(dirPath, CheckOptions{})
+}
+
+// CheckDirWithOptions checks every file inside the directory, like CheckDir
+// does, with the optional features in the options.
+func (s Signatures) CheckDirWithOptions(dirPath string, opts CheckOptions) ([]SigMatch, error) {
 	var matches []SigMatch
 
 	err := filepath.Walk(dirPath, func(path string, info fs.FileInfo, err error) error {
@@ -51,7 +192,7 @@ func (s Signatures) CheckDir(dirPath string) ([]SigMatch, error) {
 		}
 
 		if !info.IsDir() {
-			fileMatches, err := s.Check(path)
+			fileMatches, err := s.CheckWithOptions(path, opts)
 			if err != nil {
 				return err
 			}
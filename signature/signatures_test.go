@@ -0,0 +1,50 @@
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReaderAndData(t *testing.T) {
+	var (
+		sig, _ = Make("upx", "", map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}, "upx")
+		sigs   = Signatures{sig}
+		data   = []byte("MZ..UPX!....")
+	)
+
+	t.Run("check the data read, named by the caller", func(t *testing.T) {
+		matches, err := sigs.CheckReader("<stdin>", bytes.NewReader(data), CheckOptions{Hashes: true})
+
+		assert.Nil(t, err)
+		if assert.Len(t, matches, 1) {
+			assert.True(t, matches[0].IsMatch)
+			assert.Equal(t, "<stdin>", matches[0].Meta.FilePath)
+			assert.Equal(t, hashData(data), matches[0].Meta.Hashes)
+			assert.Equal(t, matchOffsets{4}, matches[0].Offsets["upx"])
+		}
+	})
+
+	t.Run("errors reading the data are returned", func(t *testing.T) {
+		readErr := errors.New("broken pipe")
+
+		_, err := sigs.CheckReader("<stdin>", iotest.ErrReader(readErr), CheckOptions{})
+
+		assert.ErrorIs(t, err, readErr)
+	})
+
+	t.Run("check the data, and its archive members", func(t *testing.T) {
+		zipped := makeTestZip("", testMember{name: "bin.exe", data: data})
+
+		matches := sigs.CheckData("sample.zip", zipped, CheckOptions{Archives: &ArchiveOptions{}})
+
+		if assert.Len(t, matches, 2) {
+			assert.Equal(t, "sample.zip", matches[0].Meta.FilePath)
+			assert.Equal(t, "sample.zip!bin.exe", matches[1].Meta.FilePath)
+			assert.True(t, matches[1].IsMatch)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package signature
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// A Test checks the result of a signature on a known sample: whether the
+// signature matches it, and optionally where its patterns match.
+type Test struct {
+	// Signature is the name of the signature under test.
+	Signature string
+	// Name identifies the test among the signature's tests.
+	Name string
+	// Source is the path to the file the test was loaded from, if any.
+	Source string
+	// Data are the bytes of the sample.
+	Data []byte
+	// Match is true if the signature is expected to match the sample.
+	Match bool
+	// Offsets are the expected offsets where each pattern matches the sample.
+	// Patterns that aren't in the map aren't checked.
+	Offsets map[string][]int
+}
+
+// A TestResult is the outcome of running a Test.
+type TestResult struct {
+	Test *Test
+	// Failures describe each of the expectations the signature didn't meet.
+	Failures []string
+}
+
+// Passed returns true if the signature met all the expectations of the test.
+func (r TestResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// RunTests runs each of the tests against the signature it names, in the given
+// order. The signatures have to be in the order returned by Link, as the
+// signatures referenced by the one under test are evaluated too. Private
+// signatures can be tested like the rest.
+func (s Signatures) RunTests(tests []Test) []TestResult {
+	var (
+		results = make([]TestResult, len(tests))
+		byName  = make(map[string]int, len(s))
+	)
+
+	for i, sig := range s {
+		byName[sig.Name] = i
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		results[i].Test = test
+
+		sigIdx, ok := byName[test.Signature]
+		if !ok {
+			results[i].Failures = []string{fmt.Sprintf("signature '%s' not found", test.Signature)}
+			continue
+		}
+
+		// Only the signatures up to the one under test are needed, as it can only
+		// reference the signatures before it.
+		match := s[:sigIdx+1].checkData(&scannedFile{data: test.Data}, nil)[sigIdx]
+		results[i].Failures = testFailures(test, match)
+	}
+
+	return results
+}
+
+// testFailures returns the expectations of the test that the match doesn't meet.
+func testFailures(test *Test, match SigMatch) []string {
+	var failures []string
+
+	switch {
+	case test.Match && !match.IsMatch:
+		failures = append(failures, "expected the signature to match, but it didn't")
+	case !test.Match && match.IsMatch:
+		failures = append(failures, "expected the signature not to match, but it did")
+	}
+
+	names := make([]string, 0, len(test.Offsets))
+	for name := range test.Offsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var (
+			want = test.Offsets[name]
+			got  = match.Offsets[name]
+		)
+
+		if _, isPattern := match.Signature.Patterns[name]; !isPattern {
+			failures = append(failures, fmt.Sprintf("the signature has no pattern '%s'", name))
+			continue
+		}
+
+		if !slices.Equal(want, []int(got)) {
+			failures = append(failures, fmt.Sprintf(
+				"expected pattern '%s' to match at offsets %v, but it matched at %v", name, want, []int(got),
+			))
+		}
+	}
+
+	return failures
+}
@@ -0,0 +1,47 @@
+package signature
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTests(t *testing.T) {
+	var (
+		upx, _ = Make("upx", "", map[string]*SignaturePattern{
+			"upx": MakePattern([]byte("UPX!")),
+		}, "upx")
+		packed, _ = MakeWithRefs("packed", "", map[string]*SignaturePattern{
+			"mz": MakePattern([]byte("MZ")),
+		}, "mz AND upx", []string{"upx"})
+		data = []byte("MZ..UPX!..UPX!..")
+	)
+	upx.Private = true
+
+	sigs, err := Link([]Signature{packed, upx})
+	if err != nil {
+		t.Fatalf("Can't link the test signatures: %s", err)
+	}
+
+	results := sigs.RunTests([]Test{
+		{Signature: "packed", Name: "positive", Data: data, Match: true},
+		{Signature: "packed", Name: "negative", Data: []byte("MZ"), Match: false},
+		{Signature: "upx", Name: "private", Data: data, Match: true, Offsets: map[string][]int{"upx": {4, 10}}},
+		{Signature: "packed", Name: "wrong outcome", Data: []byte("MZ"), Match: true},
+		{Signature: "upx", Name: "wrong offsets", Data: data, Match: true, Offsets: map[string][]int{"upx": {4}}},
+		{Signature: "packed", Name: "unknown pattern", Data: data, Match: true, Offsets: map[string][]int{"pe": {}}},
+		{Signature: "missing", Name: "unknown signature", Data: data},
+	})
+
+	if !assert.Len(t, results, 7) {
+		return
+	}
+
+	for _, result := range results[:3] {
+		assert.True(t, result.Passed(), result.Test.Name)
+	}
+	assert.Equal(t, []string{"expected the signature to match, but it didn't"}, results[3].Failures)
+	assert.Equal(t, []string{"expected pattern 'upx' to match at offsets [4], but it matched at [4 10]"}, results[4].Failures)
+	assert.Equal(t, []string{"the signature has no pattern 'pe'"}, results[5].Failures)
+	assert.Equal(t, []string{"signature 'missing' not found"}, results[6].Failures)
+}
@@ -0,0 +1,497 @@
+package signature
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/angelsolaorbaiceta/binmat/bexpr"
+)
+
+// A scannedFile is the data signatures are checked against, and the values
+// computed from it, only when needed, for the comparisons in their conditions
+// and the scopes of their patterns, which are matched concurrently.
+type scannedFile struct {
+	data    []byte
+	mu      sync.Mutex
+	hashes  *Hashes
+	entropy *float64
+	// modules are the results of parsing the data with each module, by name.
+	modules map[string]any
+}
+
+// fileHashes returns the hashes of the file, computing them the first time.
+func (f *scannedFile) fileHashes() Hashes {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.hashes == nil {
+		hashes := hashData(f.data)
+		f.hashes = &hashes
+	}
+
+	return *f.hashes
+}
+
+// fileEntropy returns the entropy of the file, computing it the first time.
+func (f *scannedFile) fileEntropy() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.entropy == nil {
+		entropy := dataEntropy(f.data)
+		f.entropy = &entropy
+	}
+
+	return *f.entropy
+}
+
+// module returns the result of parsing the file with the named module, parsing
+// it the first time.
+func (f *scannedFile) module(name string, parse func(data []byte) any) any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if parsed, ok := f.modules[name]; ok {
+		return parsed
+	}
+
+	if f.modules == nil {
+		f.modules = make(map[string]any)
+	}
+	f.modules[name] = parse(f.data)
+
+	return f.modules[name]
+}
+
+// A valueKind is the type of a file value.
+type valueKind int
+
+const (
+	kindString valueKind = iota
+	kindInt
+	kindBool
+	// kindPattern is the kind of arguments naming one of the signature's
+	// patterns (e.g. pe.in_section(a, ".text")).
+	kindPattern
+	// kindOffset is the kind of arguments that are offsets in the file: integer
+	// literals, match offsets or integer values (e.g. uint32(@a[1]),
+	// uint16(uint32(0x3c))).
+	kindOffset
+	// kindFloat is the kind of decimal numbers, which are compared with both
+	// decimal and integer literals (e.g. math.entropy(0, filesize) > 7).
+	kindFloat
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindString:
+		return "string"
+	case kindInt:
+		return "integer"
+	case kindPattern:
+		return "pattern"
+	case kindOffset:
+		return "offset"
+	case kindFloat:
+		return "number"
+	}
+
+	return "boolean"
+}
+
+// operandKind returns the kind of the literals of the value kind.
+func (k valueKind) operandKind() bexpr.OperandKind {
+	switch k {
+	case kindInt, kindOffset:
+		return bexpr.OperandInt
+	case kindPattern:
+		return bexpr.OperandIdent
+	case kindFloat:
+		return bexpr.OperandFloat
+	}
+
+	return bexpr.OperandString
+}
+
+// A fileValue is a value of the scanned file that conditions can use, either
+// comparing it with a literal (e.g. sha256 == "e3b0...") or, if it's boolean,
+// on its own (e.g. elf.has_section(".upx")). Values with arguments are called
+// like functions.
+type fileValue struct {
+	kind valueKind
+	// args are the kinds of the arguments of the value, if it's a function.
+	args []valueKind
+	// ops are the comparison operators the value supports. If nil, strings
+	// support == and !=, and numbers every operator.
+	ops []string
+	// literal checks the literal the value is compared with, and returns it
+	// normalized, if not nil.
+	literal func(lit bexpr.Operand) (bexpr.Operand, error)
+	// checkArgs checks the values of the arguments, if not nil.
+	checkArgs func(args []bexpr.Operand) error
+	// get returns the value for the file and the arguments, or false if it's
+	// undefined for the file (e.g. the ELF machine of a file that isn't an ELF).
+	// Values are either strings, int64, float64 or booleans, as their kind says.
+	get func(f *scannedFile, args []valueArg) (any, bool)
+}
+
+// A valueArg is an argument of a file value: a literal, or the name of a
+// pattern and the offsets where it matched. Offset arguments are resolved, so
+// their Int is the offset.
+type valueArg struct {
+	bexpr.Operand
+	offsets matchOffsets
+}
+
+// supportedOps returns the comparison operators the value supports.
+func (v fileValue) supportedOps() []string {
+	switch {
+	case v.ops != nil:
+		return v.ops
+	case v.kind == kindInt || v.kind == kindFloat:
+		return []string{bexpr.CmpEq, bexpr.CmpNe, bexpr.CmpLt, bexpr.CmpLe, bexpr.CmpGt, bexpr.CmpGe}
+	}
+
+	return []string{bexpr.CmpEq, bexpr.CmpNe}
+}
+
+// fileValues are the values conditions can use, by name.
+var fileValues = joinValues(
+	hashValues(),
+	entryPointValues(),
+	integerValues(),
+	mathValues(),
+	elfValues(),
+	peValues(),
+	machoValues(),
+)
+
+// matchOffsetValue is the value of match offsets (e.g. @a[1]), which is the
+// offset they're resolved to, as arguments.
+var matchOffsetValue = fileValue{
+	kind: kindInt,
+	args: []valueKind{kindOffset},
+	get: func(_ *scannedFile, args []valueArg) (any, bool) {
+		return args[0].Int, true
+	},
+}
+
+func joinValues(valueSets ...map[string]fileValue) map[string]fileValue {
+	values := make(map[string]fileValue)
+	for _, set := range valueSets {
+		for name, value := range set {
+			values[name] = value
+		}
+	}
+
+	return values
+}
+
+func hashValues() map[string]fileValue {
+	return map[string]fileValue{
+		"md5":    hashValue(32, func(h Hashes) string { return h.MD5 }),
+		"sha1":   hashValue(40, func(h Hashes) string { return h.SHA1 }),
+		"sha256": hashValue(64, func(h Hashes) string { return h.SHA256 }),
+	}
+}
+
+// hashValue returns the file value of a hash, with the given number of hex
+// digits. Hashes can only be checked for equality, ignoring the case.
+func hashValue(digits int, hash func(h Hashes) string) fileValue {
+	return fileValue{
+		kind:    kindString,
+		literal: hashLiteral(digits),
+		get: func(f *scannedFile, _ []valueArg) (any, bool) {
+			return hash(f.fileHashes()), true
+		},
+	}
+}
+
+// hashLiteral returns a function that checks that literals are hashes with the
+// given number of hex digits, and lowercases them.
+func hashLiteral(digits int) func(lit bexpr.Operand) (bexpr.Operand, error) {
+	return func(lit bexpr.Operand) (bexpr.Operand, error) {
+		if _, err := hex.DecodeString(lit.Str); err != nil || len(lit.Str) != digits {
+			return lit, fmt.Errorf("%s isn't a hash of %d hexadecimal digits", lit, digits)
+		}
+		lit.Str = strings.ToLower(lit.Str)
+		return lit, nil
+	}
+}
+
+// A comparison is a comparison in a condition, of a file value with a literal,
+// a boolean file value on its own, or a check of where a pattern matches,
+// ready to be evaluated.
+type comparison struct {
+	// key is the name of the variable holding the result of the comparison.
+	key   string
+	value fileValue
+	args  []bexpr.Operand
+	// op is empty for boolean values on their own.
+	op      string
+	literal bexpr.Operand
+	// pattern is the name of the pattern that has to match at the offset given
+	// by the value, in bexpr.CmpAt comparisons.
+	pattern string
+}
+
+// flippedOps are the operators to use when the operands swap sides.
+var flippedOps = map[string]string{
+	bexpr.CmpEq: bexpr.CmpEq,
+	bexpr.CmpNe: bexpr.CmpNe,
+	bexpr.CmpLt: bexpr.CmpGt,
+	bexpr.CmpLe: bexpr.CmpGe,
+	bexpr.CmpGt: bexpr.CmpLt,
+	bexpr.CmpGe: bexpr.CmpLe,
+}
+
+// makeComparison checks that the comparison in the condition compares a known
+// file value with a literal of its type, using an operator the value supports,
+// or that it's a known boolean value on its own. Pattern arguments have to name
+// one of the patterns.
+func makeComparison(cmp bexpr.Comparison, patterns map[string]*SignaturePattern) (comparison, error) {
+	if cmp.Op == bexpr.CmpAt {
+		return makeAtComparison(cmp, patterns)
+	}
+
+	if cmp.Op == "" {
+		value, args, err := lookupValue(cmp.Lhs, patterns)
+		if err != nil {
+			return comparison{}, err
+		}
+		if value.kind != kindBool {
+			return comparison{}, fmt.Errorf("'%s' isn't a boolean, compare it with a value", cmp.Lhs)
+		}
+
+		return comparison{key: cmp.String(), value: value, args: args}, nil
+	}
+
+	var (
+		ident, lit = cmp.Lhs, cmp.Rhs
+		op         = cmp.Op
+	)
+
+	if !isValueOperand(ident) {
+		ident, lit, op = lit, ident, flippedOps[op]
+	}
+
+	if !isValueOperand(ident) || isValueOperand(lit) {
+		return comparison{}, fmt.Errorf("'%s' has to compare a value with a literal", cmp)
+	}
+
+	value, args, err := lookupValue(ident, patterns)
+	if err != nil {
+		return comparison{}, err
+	}
+	if value.kind == kindBool {
+		return comparison{}, fmt.Errorf("'%s' is a boolean, it can't be compared", ident)
+	}
+	if ops := value.supportedOps(); !slices.Contains(ops, op) {
+		return comparison{}, fmt.Errorf("'%s' can only be compared with %s", ident, strings.Join(ops, ", "))
+	}
+	if lit.Kind != value.kind.operandKind() && !(value.kind == kindFloat && lit.Kind == bexpr.OperandInt) {
+		return comparison{}, fmt.Errorf("'%s' compares '%s', which is a %s, with %s", cmp, ident, value.kind, lit)
+	}
+
+	if value.literal != nil {
+		if lit, err = value.literal(lit); err != nil {
+			return comparison{}, fmt.Errorf("'%s': %w", cmp, err)
+		}
+	}
+
+	return comparison{key: cmp.String(), value: value, args: args, op: op, literal: lit}, nil
+}
+
+// makeAtComparison checks that the comparison checks whether one of the
+// patterns matches at an offset: an integer literal or file value (e.g.
+// a at entrypoint).
+func makeAtComparison(cmp bexpr.Comparison, patterns map[string]*SignaturePattern) (comparison, error) {
+	if _, isPattern := patterns[cmp.Lhs.Ident]; cmp.Lhs.Kind != bexpr.OperandIdent || !isPattern {
+		return comparison{}, fmt.Errorf("'%s' has to check where a pattern matches, as in 'a at entrypoint'", cmp)
+	}
+
+	switch {
+	case cmp.Rhs.Kind == bexpr.OperandInt:
+		offset := cmp.Rhs.Int
+		value := fileValue{
+			kind: kindInt,
+			get:  func(*scannedFile, []valueArg) (any, bool) { return offset, true },
+		}
+		return comparison{key: cmp.String(), value: value, op: cmp.Op, pattern: cmp.Lhs.Ident}, nil
+
+	case isValueOperand(cmp.Rhs):
+		value, args, err := lookupValue(cmp.Rhs, patterns)
+		if err != nil {
+			return comparison{}, err
+		}
+		if value.kind != kindInt {
+			return comparison{}, fmt.Errorf("'%s' isn't an offset, it's a %s", cmp.Rhs, value.kind)
+		}
+		return comparison{key: cmp.String(), value: value, args: args, op: cmp.Op, pattern: cmp.Lhs.Ident}, nil
+	}
+
+	return comparison{}, fmt.Errorf("'%s' has to check where a pattern matches, as in 'a at entrypoint'", cmp)
+}
+
+// isValueOperand returns true if the operand is a file value, or a match
+// offset, rather than a literal.
+func isValueOperand(o bexpr.Operand) bool {
+	return o.Kind == bexpr.OperandIdent || o.Kind == bexpr.OperandCall || o.Kind == bexpr.OperandOffset
+}
+
+// lookupValue returns the file value the operand refers to, and the arguments
+// it's called with, checking their number, types and values.
+func lookupValue(o bexpr.Operand, patterns map[string]*SignaturePattern) (fileValue, []bexpr.Operand, error) {
+	if o.Kind == bexpr.OperandOffset {
+		if _, isPattern := patterns[o.Ident]; !isPattern {
+			return fileValue{}, nil, fmt.Errorf("'%s' isn't the offset of a pattern", o)
+		}
+		return matchOffsetValue, []bexpr.Operand{o}, nil
+	}
+
+	value, ok := fileValues[o.Ident]
+	switch {
+	case !ok:
+		return value, nil, fmt.Errorf("unknown value '%s'", o.Ident)
+	case o.Kind == bexpr.OperandIdent && value.args != nil:
+		return value, nil, fmt.Errorf("'%s' is a function, call it as in %s(...)", o.Ident, o.Ident)
+	case o.Kind == bexpr.OperandCall && value.args == nil:
+		return value, nil, fmt.Errorf("'%s' isn't a function", o.Ident)
+	case len(o.Args) != len(value.args):
+		return value, nil, fmt.Errorf("'%s' takes %d arguments, got %d", o.Ident, len(value.args), len(o.Args))
+	}
+
+	for i, arg := range o.Args {
+		if value.args[i] == kindOffset && isValueOperand(arg) {
+			argValue, _, err := lookupValue(arg, patterns)
+			if err != nil {
+				return value, nil, err
+			}
+			if argValue.kind != kindInt {
+				return value, nil, fmt.Errorf("argument %d of '%s' has to be an offset, got %s", i+1, o.Ident, arg)
+			}
+			continue
+		}
+
+		_, isPattern := patterns[arg.Ident]
+		switch {
+		case value.args[i] == kindPattern && (arg.Kind != bexpr.OperandIdent || !isPattern):
+			return value, nil, fmt.Errorf("argument %d of '%s' has to be a pattern, got %s", i+1, o.Ident, arg)
+		case arg.Kind != value.args[i].operandKind():
+			return value, nil, fmt.Errorf("argument %d of '%s' has to be a %s literal", i+1, o.Ident, value.args[i])
+		}
+	}
+
+	if value.checkArgs != nil {
+		if err := value.checkArgs(o.Args); err != nil {
+			return value, nil, fmt.Errorf("'%s': %w", o, err)
+		}
+	}
+
+	return value, o.Args, nil
+}
+
+// eval returns the result of the comparison for the scanned file, where the
+// signature's patterns matched at the given offsets. Comparisons of values that
+// are undefined for the file are always false (e.g. a at entrypoint, for files
+// that aren't executables).
+func (c comparison) eval(f *scannedFile, matchOffs map[string]matchOffsets) bool {
+	value, ok := getValue(f, c.value, c.args, matchOffs)
+	if !ok {
+		return false
+	}
+
+	switch c.op {
+	case "":
+		return value.(bool)
+	case bexpr.CmpAt:
+		return slices.Contains(matchOffs[c.pattern], int(value.(int64)))
+	}
+
+	var order int
+	switch typedValue := value.(type) {
+	case string:
+		order = strings.Compare(typedValue, c.literal.Str)
+	case int64:
+		switch {
+		case typedValue < c.literal.Int:
+			order = -1
+		case typedValue > c.literal.Int:
+			order = 1
+		}
+	case float64:
+		literal := c.literal.Float
+		if c.literal.Kind == bexpr.OperandInt {
+			literal = float64(c.literal.Int)
+		}
+		switch {
+		case typedValue < literal:
+			order = -1
+		case typedValue > literal:
+			order = 1
+		}
+	}
+
+	switch c.op {
+	case bexpr.CmpEq:
+		return order == 0
+	case bexpr.CmpNe:
+		return order != 0
+	case bexpr.CmpLt:
+		return order < 0
+	case bexpr.CmpLe:
+		return order <= 0
+	case bexpr.CmpGt:
+		return order > 0
+	}
+
+	return order >= 0
+}
+
+// getValue returns the file value called with the arguments, for the scanned
+// file where the signature's patterns matched at the given offsets, or false if
+// it's undefined, or any of its offset arguments is.
+func getValue(f *scannedFile, value fileValue, args []bexpr.Operand, matchOffs map[string]matchOffsets) (any, bool) {
+	valueArgs := make([]valueArg, len(args))
+	for i, arg := range args {
+		valueArgs[i] = valueArg{Operand: arg}
+
+		switch value.args[i] {
+		case kindPattern:
+			valueArgs[i].offsets = matchOffs[arg.Ident]
+		case kindOffset:
+			offset, ok := resolveOffset(f, arg, matchOffs)
+			if !ok {
+				return nil, false
+			}
+			valueArgs[i].Int = offset
+		}
+	}
+
+	return value.get(f, valueArgs)
+}
+
+// resolveOffset returns the offset an offset argument refers to, or false if
+// it's undefined, like the offset of a match that didn't happen.
+func resolveOffset(f *scannedFile, arg bexpr.Operand, matchOffs map[string]matchOffsets) (int64, bool) {
+	switch arg.Kind {
+	case bexpr.OperandInt:
+		return arg.Int, true
+	case bexpr.OperandOffset:
+		offsets := matchOffs[arg.Ident]
+		if arg.Int > int64(len(offsets)) {
+			return 0, false
+		}
+		return int64(offsets[arg.Int-1]), true
+	}
+
+	offset, ok := getValue(f, fileValues[arg.Ident], arg.Args, matchOffs)
+	if !ok {
+		return 0, false
+	}
+
+	return offset.(int64), true
+}
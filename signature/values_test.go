@@ -0,0 +1,180 @@
+package signature
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The hashes of testHashedData.
+const (
+	testHashedData = "MZ..UPX!.."
+	testMD5        = "5cb978e377926f5f3b444eb7e8241654"
+	testSHA1       = "a355e94351caf99c025e1db5138488402d35b52f"
+	testSHA256     = "263f71dfb24e3eaf131e031bf2e6ae958e7a2c2f0afa94c59a0e47c4666008ec"
+)
+
+func TestHashComparisons(t *testing.T) {
+	var (
+		binPath  = filepath.Join(t.TempDir(), "bin")
+		patterns = map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}
+	)
+
+	if err := os.WriteFile(binPath, []byte(testHashedData), 0o644); err != nil {
+		t.Fatalf("Can't write test file: %s", err)
+	}
+
+	t.Run("hashes the data", func(t *testing.T) {
+		assert.Equal(t, Hashes{MD5: testMD5, SHA1: testSHA1, SHA256: testSHA256}, hashData([]byte(testHashedData)))
+	})
+
+	for _, tCase := range []struct {
+		condition string
+		want      bool
+	}{
+		{condition: fmt.Sprintf(`md5 == "%s"`, testMD5), want: true},
+		{condition: fmt.Sprintf(`sha1 == "%s"`, strings.ToUpper(testSHA1)), want: true},
+		{condition: fmt.Sprintf(`"%s" == sha256`, testSHA256), want: true},
+		{condition: fmt.Sprintf(`upx AND sha256 != "%s"`, testSHA256), want: false},
+		{condition: fmt.Sprintf(`upx AND NOT md5 == "%s"`, strings.Repeat("0", 32)), want: true},
+	} {
+		t.Run(
+			fmt.Sprintf("'%s' evaluates to %t", tCase.condition, tCase.want),
+			func(t *testing.T) {
+				sig, err := Make("hashed", "", patterns, tCase.condition)
+				if err != nil {
+					t.Fatalf("Want no error, got %s", err)
+				}
+
+				matches, err := Signatures{sig}.Check(binPath)
+
+				assert.Nil(t, err)
+				if assert.Len(t, matches, 1) {
+					assert.Equal(t, tCase.want, matches[0].IsMatch)
+					assert.Equal(t, testSHA256, matches[0].Meta.Hashes.SHA256)
+				}
+			})
+	}
+
+	for _, condition := range []string{
+		`sha256 == "abc"`,
+		`sha256 > "` + testSHA256 + `"`,
+		"sha256 == 1",
+		`size == "1"`,
+		`md5 == sha1`,
+		`"a" == "a"`,
+	} {
+		t.Run(
+			fmt.Sprintf("'%s' is an invalid comparison", condition),
+			func(t *testing.T) {
+				_, err := Make("hashed", "", patterns, condition)
+
+				if assert.NotNil(t, err) {
+					assert.Equal(t, ErrSigInvalidComparison, err.(ErrSignature).reason)
+				}
+			})
+	}
+
+	t.Run("signatures don't need patterns to compare hashes", func(t *testing.T) {
+		knownGood, err := MakeWithRefs("known_good", "", nil, fmt.Sprintf(`sha256 == "%s"`, testSHA256), nil)
+		if err != nil {
+			t.Fatalf("Want no error, got %s", err)
+		}
+		knownGood.Private = true
+
+		packed, err := MakeWithRefs("packed", "", patterns, "upx AND NOT known_good", []string{"known_good"})
+		if err != nil {
+			t.Fatalf("Want no error, got %s", err)
+		}
+
+		sigs, _ := Link([]Signature{packed, knownGood})
+		matches, err := sigs.Check(binPath)
+
+		assert.Nil(t, err)
+		if assert.Len(t, matches, 1) {
+			assert.False(t, matches[0].IsMatch)
+		}
+	})
+
+	t.Run("hashes are only computed when needed or asked for", func(t *testing.T) {
+		sig, _ := Make("upx", "", patterns, "upx")
+
+		matches, _ := Signatures{sig}.Check(binPath)
+		assert.True(t, matches[0].Meta.Hashes.IsZero())
+
+		matches, _ = Signatures{sig}.CheckWithOptions(binPath, CheckOptions{Hashes: true})
+		assert.Equal(t, testMD5, matches[0].Meta.Hashes.MD5)
+	})
+}
+
+// A valueTestCase is a condition on the values of a file format, and whether
+// it's expected to match a file of that format and other data, testHashedData.
+type valueTestCase struct {
+	condition string
+	want      bool
+	wantOther bool
+}
+
+// testValues checks that each of the conditions, with an "upx" pattern,
+// evaluates as expected for the data and for testHashedData.
+func testValues(t *testing.T, data []byte, tCases []valueTestCase) {
+	patterns := map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}
+
+	for _, tCase := range tCases {
+		t.Run(
+			fmt.Sprintf("'%s' evaluates to %t", tCase.condition, tCase.want),
+			func(t *testing.T) {
+				sig, err := Make("values", "", patterns, tCase.condition)
+				if err != nil {
+					t.Fatalf("Want no error, got %s", err)
+				}
+
+				var (
+					got      = Signatures{sig}.checkData(&scannedFile{data: data}, nil)
+					gotOther = Signatures{sig}.checkData(&scannedFile{data: []byte(testHashedData)}, nil)
+				)
+
+				assert.Equal(t, tCase.want, got[0].IsMatch)
+				assert.Equal(t, tCase.wantOther, gotOther[0].IsMatch, "other file")
+			})
+	}
+}
+
+func TestModuleInvalidComparisons(t *testing.T) {
+	patterns := map[string]*SignaturePattern{"upx": MakePattern([]byte("UPX!"))}
+
+	for _, condition := range []string{
+		`elf.machine`,
+		`elf.has_section`,
+		`elf.has_section(".a") == 1`,
+		`elf.has_section(1)`,
+		`elf.has_section(".a", ".b")`,
+		`elf.machine(".a") == "x86_64"`,
+		`elf.entry_point == "0x401000"`,
+		`elf.magic == 1`,
+		`pe.in_section(other, ".text")`,
+		`pe.in_section(".text", upx)`,
+		`pe.in_section(upx)`,
+		`pe.section_flag(".text", "exec")`,
+		`pe.imports("kernel32.dll")`,
+		`pe.imphash == "abc"`,
+		`pe.subsystem == 3`,
+		`macho.has_load_command("LC_UUID")`,
+		`macho.has_section("__text")`,
+		`macho.cpu == 7`,
+	} {
+		t.Run(
+			fmt.Sprintf("'%s' is an invalid comparison", condition),
+			func(t *testing.T) {
+				_, err := Make("module", "", patterns, condition)
+
+				if assert.NotNil(t, err) {
+					assert.Equal(t, ErrSigInvalidComparison, err.(ErrSignature).reason)
+				}
+			})
+	}
+}
@@ -0,0 +1,92 @@
+package signature
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/angelsolaorbaiceta/binmat/bexpr"
+)
+
+type WarningReason string
+
+const (
+	WarnSigUnusedPattern     WarningReason = "the pattern isn't used in the condition"
+	WarnSigIrrelevantPattern WarningReason = "the pattern never changes the result of the condition"
+	WarnSigContradiction     WarningReason = "the condition can never be true"
+	WarnSigTautology         WarningReason = "the condition is always true"
+)
+
+// A Warning points at a problem in a well-formed signature that is likely a
+// mistake, like a condition that can never be met.
+type Warning struct {
+	Reason WarningReason
+	// Pattern is the name of the pattern the warning refers to, or an empty
+	// string if it refers to the condition as a whole.
+	Pattern string
+}
+
+func (w Warning) String() string {
+	if w.Pattern == "" {
+		return string(w.Reason)
+	}
+
+	return fmt.Sprintf("%s (%s)", w.Reason, w.Pattern)
+}
+
+// analyzeCondition statically analyzes the condition expression against the
+// signature patterns, and returns the warnings found.
+func analyzeCondition(
+	patterns map[string]*SignaturePattern,
+	expr *bexpr.Expression,
+) []Warning {
+	var (
+		warnings []Warning
+		used     = make(map[string]bool)
+		names    = make([]string, 0, len(patterns))
+	)
+
+	for _, name := range expr.Vars() {
+		used[name] = true
+	}
+
+	// Patterns can also be the arguments of file values (e.g. pe.in_section(a, ".text")),
+	// or be checked for where they match (e.g. a at entrypoint, @a[1]).
+	for _, cmp := range expr.Comparisons() {
+		for _, name := range cmp.Refs() {
+			used[name] = true
+		}
+	}
+
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !used[name] {
+			warnings = append(warnings, Warning{Reason: WarnSigUnusedPattern, Pattern: name})
+		}
+	}
+
+	analysis := expr.Analyze()
+	if !analysis.Complete {
+		return warnings
+	}
+
+	if !analysis.Satisfiable {
+		warnings = append(warnings, Warning{Reason: WarnSigContradiction})
+	} else if analysis.Tautology {
+		warnings = append(warnings, Warning{Reason: WarnSigTautology})
+	} else {
+		// In contradictions and tautologies no variable is relevant, so these
+		// warnings would add nothing to the previous ones.
+		for _, name := range analysis.Irrelevant {
+			if _, isPattern := patterns[name]; !isPattern {
+				continue
+			}
+			warnings = append(warnings, Warning{Reason: WarnSigIrrelevantPattern, Pattern: name})
+		}
+	}
+
+	return warnings
+}
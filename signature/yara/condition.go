@@ -0,0 +1,556 @@
+package yara
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A condNode is a node of a translated condition: a variable, or a boolean
+// operation over other nodes. Comparisons, which binmat evaluates like
+// variables, are "var" nodes too (e.g. "a at 0").
+type condNode struct {
+	// op is "var", "and", "or" or "not".
+	op       string
+	name     string
+	operands []*condNode
+}
+
+func varNode(name string) *condNode {
+	return &condNode{op: "var", name: name}
+}
+
+// joinNodes joins the nodes with the binary operation, flattening the operands
+// that are the same operation. A single node is returned as is.
+func joinNodes(op string, nodes ...*condNode) *condNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+
+	joined := &condNode{op: op}
+	for _, node := range nodes {
+		if node.op == op {
+			joined.operands = append(joined.operands, node.operands...)
+		} else {
+			joined.operands = append(joined.operands, node)
+		}
+	}
+
+	return joined
+}
+
+// String returns the node as a binmat condition, with parentheses only where
+// required by the operators' precedence.
+func (n *condNode) String() string {
+	switch n.op {
+	case "var":
+		return n.name
+
+	case "not":
+		operand := n.operands[0]
+		if operand.op == "and" || operand.op == "or" {
+			return "NOT (" + operand.String() + ")"
+		}
+		return "NOT " + operand.String()
+	}
+
+	operands := make([]string, len(n.operands))
+	for i, operand := range n.operands {
+		operands[i] = operand.String()
+		if n.op == "and" && operand.op == "or" {
+			operands[i] = "(" + operands[i] + ")"
+		}
+	}
+
+	return strings.Join(operands, " "+strings.ToUpper(n.op)+" ")
+}
+
+// An importedString is a YARA string and the binmat patterns it translates to.
+type importedString struct {
+	def stringDef
+	// names are the names of the patterns, one per variant.
+	names    []string
+	variants []stringVariant
+	// err is why the string can't be imported, if it can't.
+	err error
+}
+
+// node returns the condition node that matches the string: its only pattern,
+// or any of its variants.
+func (s *importedString) node() *condNode {
+	nodes := make([]*condNode, len(s.names))
+	for i, name := range s.names {
+		nodes[i] = varNode(name)
+	}
+
+	return joinNodes("or", nodes...)
+}
+
+// A condTranslator translates the tokens of a YARA condition into a binmat
+// condition. The YARA constructs binmat can't express yield an error.
+type condTranslator struct {
+	tokens  []token
+	pos     int
+	strings []*importedString
+	// rules are the binmat names of the rules imported so far, by YARA name.
+	rules map[string]string
+	// skippedRules are the YARA names of the rules skipped so far.
+	skippedRules map[string]bool
+}
+
+func (t *condTranslator) translate() (*condNode, error) {
+	node, err := t.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.pos < len(t.tokens) {
+		return nil, unsupported(t.tokens[t.pos])
+	}
+
+	return node, nil
+}
+
+func (t *condTranslator) peek() (token, bool) {
+	if t.pos < len(t.tokens) {
+		return t.tokens[t.pos], true
+	}
+	return token{}, false
+}
+
+func (t *condTranslator) next() (token, bool) {
+	tok, ok := t.peek()
+	if ok {
+		t.pos++
+	}
+	return tok, ok
+}
+
+// nextIs consumes the next token if it has the given text.
+func (t *condTranslator) nextIs(text string) bool {
+	if tok, ok := t.peek(); ok && tok.text == text && tok.kind != tokenString {
+		t.pos++
+		return true
+	}
+	return false
+}
+
+func (t *condTranslator) or() (*condNode, error) {
+	node, err := t.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for t.nextIs("or") {
+		rhs, err := t.and()
+		if err != nil {
+			return nil, err
+		}
+		node = joinNodes("or", node, rhs)
+	}
+
+	return node, nil
+}
+
+func (t *condTranslator) and() (*condNode, error) {
+	node, err := t.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for t.nextIs("and") {
+		rhs, err := t.operand()
+		if err != nil {
+			return nil, err
+		}
+		node = joinNodes("and", node, rhs)
+	}
+
+	return node, nil
+}
+
+// operand translates a primary expression, checking that it isn't followed by
+// operators other than the boolean ones (e.g. "$a at 0", "#a > 2").
+func (t *condTranslator) operand() (*condNode, error) {
+	node, err := t.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, ok := t.peek(); ok && tok.text != "and" && tok.text != "or" && tok.text != ")" {
+		return nil, unsupported(tok)
+	}
+
+	return node, nil
+}
+
+func (t *condTranslator) primary() (*condNode, error) {
+	tok, ok := t.next()
+	if !ok {
+		return nil, fmt.Errorf("incomplete condition")
+	}
+
+	switch tok.kind {
+	case tokenStringID:
+		str, err := t.string(tok.text)
+		if err != nil {
+			return nil, err
+		}
+		if t.nextIs("at") {
+			return t.at(str)
+		}
+		return str.node(), nil
+
+	case tokenStringCount:
+		return nil, fmt.Errorf("string counts ('%s') aren't supported", tok.text)
+	case tokenStringOffset:
+		return t.integerComparison(tok)
+	case tokenStringLength:
+		return nil, fmt.Errorf("string lengths ('%s') aren't supported", tok.text)
+
+	case tokenNumber:
+		if t.nextIs("of") {
+			return t.of(tok)
+		}
+		return nil, fmt.Errorf("numeric expressions ('%s') aren't supported", tok.text)
+
+	case tokenPunct:
+		if tok.text != "(" {
+			return nil, unsupported(tok)
+		}
+
+		node, err := t.or()
+		if err != nil {
+			return nil, err
+		}
+		if !t.nextIs(")") {
+			return nil, fmt.Errorf("unbalanced parentheses")
+		}
+		return node, nil
+
+	case tokenIdent:
+		return t.identifier(tok)
+	}
+
+	return nil, unsupported(tok)
+}
+
+// identifier translates a primary expression that starts with an identifier:
+// keywords, and references to rules and modules.
+func (t *condTranslator) identifier(tok token) (*condNode, error) {
+	switch tok.text {
+	case "not":
+		operand, err := t.primary()
+		if err != nil {
+			return nil, err
+		}
+		return &condNode{op: "not", operands: []*condNode{operand}}, nil
+
+	case "any", "all", "none":
+		if !t.nextIs("of") {
+			return nil, unsupported(tok)
+		}
+		return t.of(tok)
+
+	case "true", "false":
+		return nil, fmt.Errorf("boolean literals aren't supported")
+
+	case "for":
+		return nil, fmt.Errorf("'for' loops aren't supported")
+
+	case "filesize":
+		return t.integerComparison(tok)
+
+	case "entrypoint", "defined":
+		return nil, fmt.Errorf("'%s' isn't supported", tok.text)
+	}
+
+	next, hasNext := t.peek()
+	if hasNext && integerFunctions[tok.text] && next.text == "(" {
+		return t.integerComparison(tok)
+	}
+	if hasNext && (next.text == "." || next.text == "(") {
+		return nil, fmt.Errorf("modules ('%s') aren't supported", tok.text)
+	}
+
+	if name, ok := t.rules[tok.text]; ok {
+		return varNode(name), nil
+	}
+	if t.skippedRules[tok.text] {
+		return nil, fmt.Errorf("references rule '%s', which was skipped", tok.text)
+	}
+
+	return nil, fmt.Errorf("undefined identifier '%s'", tok.text)
+}
+
+// at translates an "at" expression (e.g. "$a at 0x100", "$a at entrypoint"),
+// whose string and "at" keyword have already been consumed. Strings with many
+// variants match if any of them matches at the offset.
+func (t *condTranslator) at(str *importedString) (*condNode, error) {
+	var offset string
+
+	switch tok, _ := t.next(); {
+	case tok.kind == tokenIdent && tok.text == "entrypoint":
+		offset = tok.text
+
+	case tok.kind == tokenNumber:
+		var err error
+		if offset, err = number(tok); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("'at' expressions are only supported with numbers and the entrypoint")
+	}
+
+	nodes := make([]*condNode, len(str.names))
+	for i, name := range str.names {
+		nodes[i] = varNode(name + " at " + offset)
+	}
+
+	return joinNodes("or", nodes...), nil
+}
+
+// integerFunctions are the YARA functions that read integers from the file,
+// which binmat conditions have too.
+var integerFunctions = map[string]bool{
+	"uint8": true, "uint16": true, "uint32": true,
+	"uint8be": true, "uint16be": true, "uint32be": true,
+}
+
+// comparisonOps are the YARA comparison operators, which binmat conditions
+// have too.
+var comparisonOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// integerComparison translates the comparison of an integer read from the file,
+// a string offset or the file size with a number (e.g. "uint16(0) == 0x5a4d",
+// "@a[1] < 0x100", "filesize < 2MB"), whose first token has already been
+// consumed.
+func (t *condTranslator) integerComparison(first token) (*condNode, error) {
+	lhs, err := t.integer(first)
+	if err != nil {
+		return nil, err
+	}
+
+	op, _ := t.next()
+	if op.kind != tokenPunct || !comparisonOps[op.text] {
+		return nil, fmt.Errorf("'%s' is only supported compared with a number", lhs)
+	}
+
+	rhs, _ := t.next()
+	if rhs.kind != tokenNumber {
+		return nil, fmt.Errorf("'%s' is only supported compared with a number", lhs)
+	}
+	value, err := number(rhs)
+	if err != nil {
+		return nil, err
+	}
+
+	return varNode(lhs + " " + op.text + " " + value), nil
+}
+
+// integer translates an integer expression binmat conditions have too: a
+// number, a string offset, the file size or an integer read from the file,
+// whose first token has already been consumed.
+func (t *condTranslator) integer(tok token) (string, error) {
+	switch {
+	case tok.kind == tokenNumber:
+		return number(tok)
+
+	case tok.kind == tokenIdent && tok.text == "filesize":
+		return tok.text, nil
+
+	case tok.kind == tokenStringOffset:
+		str, err := t.string("$" + strings.TrimPrefix(tok.text, "@"))
+		if err != nil {
+			return "", err
+		}
+		if len(str.names) != 1 {
+			return "", fmt.Errorf("offsets of strings with many variants ('%s') aren't supported", tok.text)
+		}
+
+		index := "1"
+		if t.nextIs("[") {
+			indexTok, _ := t.next()
+			if indexTok.kind != tokenNumber || !t.nextIs("]") {
+				return "", fmt.Errorf("string offset indexes ('%s[') other than numbers aren't supported", tok.text)
+			}
+			if index, err = number(indexTok); err != nil {
+				return "", err
+			}
+		}
+		return "@" + str.names[0] + "[" + index + "]", nil
+
+	case tok.kind == tokenIdent && integerFunctions[tok.text]:
+		if !t.nextIs("(") {
+			return "", unsupported(tok)
+		}
+		argTok, _ := t.next()
+		arg, err := t.integer(argTok)
+		if err != nil {
+			return "", err
+		}
+		if !t.nextIs(")") {
+			return "", fmt.Errorf("only single numbers, string offsets and integers are supported as the offset of '%s'", tok.text)
+		}
+		return tok.text + "(" + arg + ")", nil
+	}
+
+	return "", fmt.Errorf("numeric expressions ('%s') aren't supported", tok.text)
+}
+
+// number returns the number token as a decimal number. Decimal numbers can
+// have a KB or MB suffix, which multiplies them by its size (e.g. "2MB" is
+// 2097152).
+func number(tok token) (string, error) {
+	var (
+		value int64
+		err   error
+		unit  = int64(1)
+	)
+	if hexDigits, isHex := strings.CutPrefix(tok.text, "0x"); isHex {
+		value, err = strconv.ParseInt(hexDigits, 16, 64)
+	} else {
+		digits := tok.text
+		if kb, isKB := strings.CutSuffix(digits, "KB"); isKB {
+			digits, unit = kb, 1<<10
+		} else if mb, isMB := strings.CutSuffix(digits, "MB"); isMB {
+			digits, unit = mb, 1<<20
+		}
+		value, err = strconv.ParseInt(digits, 10, 64)
+	}
+	if err != nil || value > math.MaxInt64/unit {
+		return "", fmt.Errorf("number '%s' isn't supported", tok.text)
+	}
+
+	return strconv.FormatInt(value*unit, 10), nil
+}
+
+// of translates an "of" expression (e.g. "any of them", "2 of ($a, $b*)"),
+// whose quantifier and "of" keyword have already been consumed.
+func (t *condTranslator) of(quantifier token) (*condNode, error) {
+	var set []*importedString
+
+	switch tok, _ := t.next(); {
+	case tok.kind == tokenIdent && tok.text == "them":
+		var err error
+		if set, err = t.stringSet("$*"); err != nil {
+			return nil, err
+		}
+
+	case tok.kind == tokenPunct && tok.text == "(":
+		for {
+			item, _ := t.next()
+			if item.kind == tokenIdent {
+				return nil, fmt.Errorf("'of' expressions over rules aren't supported")
+			}
+			if item.kind != tokenStringID {
+				return nil, fmt.Errorf("malformed string set")
+			}
+
+			strs, err := t.stringSet(item.text)
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, strs...)
+
+			if t.nextIs(")") {
+				break
+			}
+			if !t.nextIs(",") {
+				return nil, fmt.Errorf("malformed string set")
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("malformed 'of' expression")
+	}
+
+	nodes := make([]*condNode, len(set))
+	for i, str := range set {
+		nodes[i] = str.node()
+	}
+
+	count, _ := strconv.Atoi(quantifier.text)
+	switch {
+	case quantifier.text == "any" || count == 1:
+		return joinNodes("or", nodes...), nil
+	case quantifier.text == "all" || count == len(nodes):
+		return joinNodes("and", nodes...), nil
+	case quantifier.text == "none":
+		return &condNode{op: "not", operands: []*condNode{joinNodes("or", nodes...)}}, nil
+	}
+
+	return nil, fmt.Errorf("'%s of' over %d strings isn't supported", quantifier.text, len(nodes))
+}
+
+// string returns the string with the given identifier.
+func (t *condTranslator) string(id string) (*importedString, error) {
+	if id == "$" || strings.HasSuffix(id, "*") {
+		return nil, fmt.Errorf("'%s' is only supported in 'of' expressions", id)
+	}
+
+	for _, str := range t.strings {
+		if str.def.id == id {
+			if str.err != nil {
+				return nil, fmt.Errorf("string '%s': %s", id, str.err)
+			}
+
+			return str, nil
+		}
+	}
+
+	return nil, fmt.Errorf("undefined string '%s'", id)
+}
+
+// stringSet returns the strings matching the identifier, which can end in a
+// "*" wildcard.
+func (t *condTranslator) stringSet(id string) ([]*importedString, error) {
+	if !strings.HasSuffix(id, "*") {
+		str, err := t.string(id)
+		if err != nil {
+			return nil, err
+		}
+		return []*importedString{str}, nil
+	}
+
+	var (
+		prefix = strings.TrimSuffix(id, "*")
+		set    []*importedString
+	)
+
+	for _, str := range t.strings {
+		if !strings.HasPrefix(str.def.id, prefix) {
+			continue
+		}
+		if str.err != nil {
+			return nil, fmt.Errorf("string '%s': %s", str.def.id, str.err)
+		}
+
+		set = append(set, str)
+	}
+
+	if len(set) == 0 {
+		return nil, fmt.Errorf("no strings match '%s'", id)
+	}
+
+	return set, nil
+}
+
+// unsupported returns the error for an unexpected token in a condition, which
+// usually means it's a construct binmat doesn't support.
+func unsupported(tok token) error {
+	switch {
+	case tok.kind == tokenIdent && tok.text == "at":
+		return fmt.Errorf("'at' is only supported after a string, as in '$a at 0'")
+	case tok.kind == tokenIdent && tok.text == "in":
+		return fmt.Errorf("string ranges ('in') aren't supported")
+	case tok.kind == tokenIdent:
+		return fmt.Errorf("'%s' isn't supported", tok.text)
+	case tok.kind == tokenPunct:
+		return fmt.Errorf("'%s' operator isn't supported", tok.text)
+	}
+
+	return fmt.Errorf("unexpected '%s'", tok.text)
+}
@@ -0,0 +1,96 @@
+package yara
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateCondition(t *testing.T) {
+	const strs = `$a = "aaa" $b = "bbb" $c = { 01 02 03 } $w = "www" ascii wide`
+
+	for _, tCase := range []struct {
+		cond string
+		want string
+	}{
+		{cond: "$a", want: "a"},
+		{cond: "$a and $b or $c", want: "a AND b OR c"},
+		{cond: "$a and ($b or $c)", want: "a AND (b OR c)"},
+		{cond: "not ($a or $b) and not $c", want: "NOT (a OR b) AND NOT c"},
+		{cond: "$a and $w", want: "a AND (w OR w_w)"},
+		{cond: "any of them", want: "a OR b OR c OR w OR w_w"},
+		{cond: "all of ($a, $b)", want: "a AND b"},
+		{cond: "2 of ($a, $b)", want: "a AND b"},
+		{cond: "1 of ($a, $c)", want: "a OR c"},
+		{cond: "none of ($a, $b)", want: "NOT (a OR b)"},
+		{cond: "$a and other", want: "a AND other"},
+		{cond: "$a at 0x10 and not $c at 0", want: "a at 16 AND NOT c at 0"},
+		{cond: "$w at entrypoint", want: "w at entrypoint OR w_w at entrypoint"},
+		{cond: "uint16(0) == 0x5a4d", want: "uint16(0) == 23117"},
+		{cond: "uint32(uint32(0x3c)) == 0x4550 and $a", want: "uint32(uint32(60)) == 17744 AND a"},
+		{cond: "uint8be(@a[2]) != 0 or @c < 100", want: "uint8be(@a[2]) != 0 OR @c[1] < 100"},
+		{cond: "$a and filesize < 100", want: "a AND filesize < 100"},
+		{cond: "filesize > 10KB and filesize <= 2MB", want: "filesize > 10240 AND filesize <= 2097152"},
+	} {
+		t.Run(
+			fmt.Sprintf("translate '%s' into '%s'", tCase.cond, tCase.want),
+			func(t *testing.T) {
+				importer := NewImporter()
+				importer.rules["other"] = "other"
+				r := mustParseRule(t, fmt.Sprintf("rule r { strings: %s condition: %s }", strs, tCase.cond))
+
+				sig, skips, ok := importer.importRule(r)
+
+				assert.True(t, ok, skips)
+				assert.Equal(t, tCase.want, sig.Condition)
+			})
+	}
+
+	for _, tCase := range []struct {
+		cond   string
+		reason string
+	}{
+		{cond: "$a at filesize", reason: "'at' expressions are only supported with numbers and the entrypoint"},
+		{cond: "($a) at 0", reason: "'at' is only supported after a string, as in '$a at 0'"},
+		{cond: "$a in (0..100)", reason: "string ranges ('in') aren't supported"},
+		{cond: "#a > 2", reason: "string counts ('#a') aren't supported"},
+		{cond: "filesize < uint32(0)", reason: "'filesize' is only supported compared with a number"},
+		{cond: "filesize < 9999999999999999MB", reason: "number '9999999999999999MB' isn't supported"},
+		{cond: "filesize < 0x10KB", reason: "number '0x10KB' isn't supported"},
+		{cond: "2 of them", reason: "'2 of' over 4 strings isn't supported"},
+		{cond: "any of (r1, r2)", reason: "'of' expressions over rules aren't supported"},
+		{cond: "for any of them : ( $ at 0 )", reason: "'for' loops aren't supported"},
+		{cond: "$a and $x", reason: "undefined string '$x'"},
+		{cond: "$a == 1", reason: "'==' operator isn't supported"},
+		{cond: "@w == 0", reason: "offsets of strings with many variants ('@w') aren't supported"},
+		{cond: "uint16(0) + 1 == 2", reason: "'uint16(0)' is only supported compared with a number"},
+		{cond: "uint16(0) == uint16(2)", reason: "'uint16(0)' is only supported compared with a number"},
+		{cond: "uint16(filesize - 2) == 0", reason: "only single numbers, string offsets and integers are supported as the offset of 'uint16'"},
+	} {
+		t.Run(
+			fmt.Sprintf("'%s' can't be translated", tCase.cond),
+			func(t *testing.T) {
+				r := mustParseRule(t, fmt.Sprintf("rule r { strings: %s condition: %s }", strs, tCase.cond))
+
+				_, skips, ok := NewImporter().importRule(r)
+
+				assert.False(t, ok)
+				if assert.Len(t, skips, 1) {
+					assert.Equal(t, tCase.reason, skips[0].Reason)
+				}
+			})
+	}
+}
+
+func mustParseRule(t *testing.T, src string) rule {
+	set, err := parse(src)
+	if err != nil {
+		t.Fatalf("Want no error, got %s", err)
+	}
+	if len(set.rules) != 1 {
+		t.Fatalf("Want one rule, got %d", len(set.rules))
+	}
+
+	return set.rules[0]
+}
@@ -0,0 +1,13 @@
+package yara
+
+import "fmt"
+
+// An ErrSyntax is returned when the YARA source isn't well formed.
+type ErrSyntax struct {
+	Line    int
+	Details string
+}
+
+func (e ErrSyntax) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Details)
+}
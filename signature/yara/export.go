@@ -0,0 +1,322 @@
+package yara
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/angelsolaorbaiceta/binmat/bexpr"
+	"github.com/angelsolaorbaiceta/binmat/signature"
+)
+
+// maxIdentLength is the maximum length of YARA identifiers.
+const maxIdentLength = 128
+
+// keywords are the YARA reserved words, which can't be used as identifiers.
+var keywords = map[string]bool{
+	"all": true, "and": true, "any": true, "ascii": true, "at": true,
+	"base64": true, "base64wide": true, "condition": true, "contains": true,
+	"defined": true, "endswith": true, "entrypoint": true, "false": true,
+	"filesize": true, "for": true, "fullword": true, "global": true,
+	"icontains": true, "iendswith": true, "iequals": true, "import": true,
+	"in": true, "include": true, "int16": true, "int16be": true, "int32": true,
+	"int32be": true, "int8": true, "int8be": true, "istartswith": true,
+	"matches": true, "meta": true, "nocase": true, "none": true, "not": true,
+	"of": true, "or": true, "private": true, "rule": true, "startswith": true,
+	"strings": true, "them": true, "true": true, "uint16": true,
+	"uint16be": true, "uint32": true, "uint32be": true, "uint8": true,
+	"uint8be": true, "wide": true, "xor": true,
+}
+
+// yaraPrinter prints binmat conditions as YARA conditions. Its Var function is
+// set for each rule, as variables can be patterns or other rules.
+var yaraPrinter = bexpr.Printer{And: "and", Or: "or", Not: "not", Cmp: yaraComparison}
+
+// hashFunctions are the functions of YARA's hash module that compute the file
+// values that binmat conditions compare.
+var hashFunctions = map[string]string{
+	"md5":    "hash.md5(0, filesize)",
+	"sha1":   "hash.sha1(0, filesize)",
+	"sha256": "hash.sha256(0, filesize)",
+}
+
+// Export writes the signatures as YARA rules, which the Importer can read back.
+//
+// Patterns are written as hex strings, and conditions are translated from their
+// parsed expression. The signatures have to be in the order returned by
+// signature.Link, as YARA rules can only reference the rules defined before them.
+//
+// Rules are named after the original YARA rule name, if the signature was
+// imported and it's kept in its meta, or the signature name otherwise, changed
+// to be a valid YARA identifier.
+//
+// Comparisons of the file hashes are written with YARA's hash module, which is
+// imported if needed. Patterns checked at an offset, either a number or the
+// entry point, are written as "at" expressions, and integers read from the file
+// as YARA's, except those of 64 bits, which YARA doesn't have.
+func Export(w io.Writer, sigs []signature.Signature) error {
+	var (
+		// ruleNames are the YARA names of the exported signatures, by name.
+		ruleNames = make(map[string]string, len(sigs))
+		usedNames = make(map[string]bool, len(sigs))
+		exprs     = make([]*bexpr.Expression, len(sigs))
+		usesHash  bool
+	)
+
+	for i, sig := range sigs {
+		expr, err := bexpr.Parse(sig.Condition)
+		if err != nil {
+			return fmt.Errorf("signature '%s': %w", sig.Name, err)
+		}
+
+		for name, pattern := range sig.Patterns {
+			if !pattern.Scope().IsZero() {
+				return fmt.Errorf("signature '%s': pattern '%s' has a scope, which can't be exported", sig.Name, name)
+			}
+		}
+
+		// Domain signatures only compare values with literals, whose Ident is empty.
+		for _, cmp := range expr.Comparisons() {
+			if cmp.Op == bexpr.CmpAt {
+				if cmp.Rhs.Kind != bexpr.OperandInt && cmp.Rhs.Ident != "entrypoint" {
+					return fmt.Errorf("signature '%s': comparison '%s' can't be exported", sig.Name, cmp)
+				}
+				continue
+			}
+			if isYaraInteger(cmp.Lhs) && isYaraInteger(cmp.Rhs) {
+				continue
+			}
+			if !isHashComparison(cmp) {
+				return fmt.Errorf("signature '%s': comparison '%s' can't be exported", sig.Name, cmp)
+			}
+			usesHash = true
+		}
+
+		exprs[i] = expr
+	}
+
+	if usesHash {
+		if _, err := io.WriteString(w, "import \"hash\"\n\n"); err != nil {
+			return err
+		}
+	}
+
+	for i, sig := range sigs {
+		expr := exprs[i]
+
+		name := exportedRuleName(sig, usedNames)
+		usedNames[name] = true
+		ruleNames[sig.Name] = name
+
+		printer := yaraPrinter
+		printer.Var = func(varName string) string {
+			if _, isPattern := sig.Patterns[varName]; isPattern {
+				return "$" + varName
+			}
+			return ruleNames[varName]
+		}
+
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err := writeRule(w, name, sig, printer.Print(expr)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeRule(w io.Writer, name string, sig signature.Signature, condition string) error {
+	var rule strings.Builder
+
+	if sig.Private {
+		rule.WriteString("private ")
+	}
+	rule.WriteString("rule " + name)
+
+	if len(sig.Tags) > 0 {
+		rule.WriteString(" :")
+		for _, tag := range sig.Tags {
+			rule.WriteString(" " + identifier(tag))
+		}
+	}
+	rule.WriteString(" {\n")
+
+	var metaKeys []string
+	for key := range sig.Meta {
+		if key != RuleNameMetaKey {
+			metaKeys = append(metaKeys, key)
+		}
+	}
+	sort.Strings(metaKeys)
+
+	if sig.Description != "" || len(metaKeys) > 0 {
+		rule.WriteString("    meta:\n")
+		if sig.Description != "" {
+			fmt.Fprintf(&rule, "        description = %s\n", quote(sig.Description))
+		}
+		for _, key := range metaKeys {
+			fmt.Fprintf(&rule, "        %s = %s\n", identifier(key), quote(sig.Meta[key]))
+		}
+	}
+
+	if len(sig.Patterns) > 0 {
+		names := make([]string, 0, len(sig.Patterns))
+		for patternName := range sig.Patterns {
+			names = append(names, patternName)
+		}
+		sort.Strings(names)
+
+		rule.WriteString("    strings:\n")
+		for _, patternName := range names {
+			fmt.Fprintf(&rule, "        $%s = %s\n", patternName, hexString(sig.Patterns[patternName]))
+		}
+	}
+
+	fmt.Fprintf(&rule, "    condition:\n        %s\n}\n", condition)
+
+	_, err := io.WriteString(w, rule.String())
+	return err
+}
+
+// exportedRuleName returns the YARA rule name for the signature, which isn't
+// in usedNames.
+func exportedRuleName(sig signature.Signature, usedNames map[string]bool) string {
+	name := identifier(sig.Name)
+	if original, ok := sig.Meta[RuleNameMetaKey]; ok {
+		name = identifier(original)
+	}
+
+	if !usedNames[name] {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		candidate := truncateName(name, maxIdentLength-len(suffix)) + suffix
+		if !usedNames[candidate] {
+			return candidate
+		}
+	}
+}
+
+// yaraComparison prints the comparison of a file hash with a literal as a YARA
+// comparison, which uses the hash module, the check of where a pattern matches
+// as an "at" expression, and comparisons of integers, including the file size,
+// as they are.
+func yaraComparison(cmp bexpr.Comparison) string {
+	if cmp.Op == bexpr.CmpAt {
+		return "$" + cmp.Lhs.Ident + " at " + cmp.Rhs.String()
+	}
+	if isYaraInteger(cmp.Lhs) {
+		// Integers, and match offsets, are written the same in YARA.
+		return cmp.String()
+	}
+
+	operand := func(o bexpr.Operand) string {
+		switch o.Kind {
+		case bexpr.OperandIdent:
+			return hashFunctions[o.Ident]
+		case bexpr.OperandString:
+			return quote(strings.ToLower(o.Str))
+		}
+		return o.String()
+	}
+
+	return operand(cmp.Lhs) + " " + cmp.Op + " " + operand(cmp.Rhs)
+}
+
+// isHashComparison returns true if the comparison is of a file hash, on either
+// side, with a string literal on the other.
+func isHashComparison(cmp bexpr.Comparison) bool {
+	hashWithLiteral := func(hash, literal bexpr.Operand) bool {
+		_, isHash := hashFunctions[hash.Ident]
+		return hash.Kind == bexpr.OperandIdent && isHash && literal.Kind == bexpr.OperandString
+	}
+
+	return hashWithLiteral(cmp.Lhs, cmp.Rhs) || hashWithLiteral(cmp.Rhs, cmp.Lhs)
+}
+
+// isYaraInteger returns true if the operand is an integer expression YARA has
+// too: a number, a match offset, the file size, or an integer of up to 32 bits
+// read from the file at such an expression.
+func isYaraInteger(o bexpr.Operand) bool {
+	switch o.Kind {
+	case bexpr.OperandInt, bexpr.OperandOffset:
+		return true
+	case bexpr.OperandIdent:
+		return o.Ident == "filesize"
+	case bexpr.OperandCall:
+		return integerFunctions[o.Ident] && len(o.Args) == 1 && isYaraInteger(o.Args[0])
+	}
+
+	return false
+}
+
+// identifier turns the name into a valid YARA identifier.
+func identifier(name string) string {
+	var ident strings.Builder
+
+	for _, c := range name {
+		if c < 0x80 && isIdentChar(byte(c)) {
+			ident.WriteRune(c)
+		} else {
+			ident.WriteByte('_')
+		}
+	}
+
+	result := ident.String()
+	if result == "" || isDigit(result[0]) || keywords[result] {
+		result = "_" + result
+	}
+
+	return truncateName(result, maxIdentLength)
+}
+
+// hexString returns the pattern as a YARA hex string.
+func hexString(pattern *signature.SignaturePattern) string {
+	var (
+		data   = pattern.Bytes()
+		mask   = pattern.Mask()
+		fields = make([]string, len(data))
+	)
+
+	for i, b := range data {
+		if mask[i] == 0 {
+			fields[i] = "??"
+		} else {
+			fields[i] = fmt.Sprintf("%02X", b)
+		}
+	}
+
+	return "{ " + strings.Join(fields, " ") + " }"
+}
+
+// quote returns the text as a YARA text string.
+func quote(text string) string {
+	var quoted strings.Builder
+
+	quoted.WriteByte('"')
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '"' || c == '\\':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(c)
+		case c == '\n':
+			quoted.WriteString("\\n")
+		case c == '\t':
+			quoted.WriteString("\\t")
+		case c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&quoted, "\\x%02x", c)
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	quoted.WriteByte('"')
+
+	return quoted.String()
+}
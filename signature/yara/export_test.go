@@ -0,0 +1,251 @@
+package yara
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/angelsolaorbaiceta/binmat/bexpr"
+	"github.com/angelsolaorbaiceta/binmat/signature"
+	sigio "github.com/angelsolaorbaiceta/binmat/signature/io"
+	"github.com/stretchr/testify/assert"
+)
+
+const exportedSigs = `
+name: packed dropper
+description: A "dropper" packed with UPX
+tags: [packer, high-risk]
+meta:
+  severity: high
+patterns:
+  a: '{ 74 fc ?? ?? c6 }'
+  b: dropper
+condition: upx AND (a OR NOT b)
+---
+name: upx
+private: true
+patterns:
+  upx: UPX!
+condition: upx
+---
+name: any
+patterns:
+  a: '{ 01 02 03 }'
+meta:
+  yara_rule: Any_Rule
+condition: a
+`
+
+const wantYara = `private rule upx {
+    strings:
+        $upx = { 55 50 58 21 }
+    condition:
+        $upx
+}
+
+rule packed_dropper : packer high_risk {
+    meta:
+        description = "A \"dropper\" packed with UPX"
+        severity = "high"
+    strings:
+        $a = { 74 FC ?? ?? C6 }
+        $b = { 64 72 6F 70 70 65 72 }
+    condition:
+        upx and ($a or not $b)
+}
+
+rule Any_Rule {
+    strings:
+        $a = { 01 02 03 }
+    condition:
+        $a
+}
+`
+
+func loadTestSigs(t *testing.T, yaml string) signature.Signatures {
+	filePath := filepath.Join(t.TempDir(), "sigs.yaml")
+	if err := os.WriteFile(filePath, []byte(yaml), 0o644); err != nil {
+		t.Fatalf("Can't write test file: %s", err)
+	}
+
+	sigs, err := sigio.LoadSignatures(filePath)
+	if err != nil {
+		t.Fatalf("Can't load test signatures: %s", err)
+	}
+
+	return sigs
+}
+
+func TestExport(t *testing.T) {
+	var (
+		sigs = loadTestSigs(t, exportedSigs)
+		yara strings.Builder
+	)
+
+	err := Export(&yara, sigs)
+
+	assert.Nil(t, err)
+	assert.Equal(t, wantYara, yara.String())
+
+	t.Run("round-trips through the importer", func(t *testing.T) {
+		imported, skipped, err := Import(strings.NewReader(yara.String()))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Empty(t, skipped)
+
+		var importedYaml strings.Builder
+		sigio.WriteAllToYaml(&importedYaml, imported)
+		reloaded := loadTestSigs(t, importedYaml.String())
+
+		if !assert.Len(t, reloaded, len(sigs)) {
+			return
+		}
+		for i, sig := range reloaded {
+			original := sigs[i]
+
+			assert.Equal(t, original.Private, sig.Private)
+			assert.Equal(t, printCondition(original.Condition), printCondition(sig.Condition))
+			if assert.Len(t, sig.Patterns, len(original.Patterns)) {
+				for name, pattern := range original.Patterns {
+					assert.True(t, pattern.Equal(sig.Patterns[name]), name)
+				}
+			}
+		}
+	})
+}
+
+func printCondition(condition string) string {
+	expr, _ := bexpr.Parse(condition)
+	return bexpr.DefaultPrinter.Print(expr)
+}
+
+func TestIdentifier(t *testing.T) {
+	assert.Equal(t, "packed_dropper", identifier("packed dropper"))
+	assert.Equal(t, "_2nd", identifier("2nd"))
+	assert.Equal(t, "_rule", identifier("rule"))
+	assert.Equal(t, "_", identifier(""))
+}
+
+func TestExportHashComparisons(t *testing.T) {
+	var (
+		sigs = loadTestSigs(t, `name: known_good
+condition: sha256 == "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855" OR md5 != "d41d8cd98f00b204e9800998ecf8427e"
+`)
+		yara strings.Builder
+	)
+
+	err := Export(&yara, sigs)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `import "hash"
+
+rule known_good {
+    condition:
+        hash.sha256(0, filesize) == "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" or hash.md5(0, filesize) != "d41d8cd98f00b204e9800998ecf8427e"
+}
+`, yara.String())
+}
+
+func TestIsHashComparison(t *testing.T) {
+	for _, tCase := range []struct {
+		cmp  string
+		want bool
+	}{
+		{cmp: `sha256 == "abc"`, want: true},
+		{cmp: `"abc" != md5`, want: true},
+		{cmp: `sha256 == md5`, want: false},
+		{cmp: `"abc" == "abc"`, want: false},
+		{cmp: `size == "abc"`, want: false},
+		{cmp: `sha1 == 1`, want: false},
+	} {
+		t.Run(fmt.Sprintf("'%s' is a hash comparison: %t", tCase.cmp, tCase.want), func(t *testing.T) {
+			expr, err := bexpr.Parse(tCase.cmp)
+			if err != nil {
+				t.Fatalf("Want no error, got %s", err)
+			}
+
+			assert.Equal(t, tCase.want, isHashComparison(expr.Comparisons()[0]))
+		})
+	}
+}
+
+func TestExportScopedPatterns(t *testing.T) {
+	sigs := loadTestSigs(t, `name: overlay_zip
+patterns:
+  pk:
+    pattern: '{ 50 4b 03 04 }'
+    scope: overlay
+condition: pk
+`)
+
+	err := Export(io.Discard, sigs)
+
+	assert.ErrorContains(t, err, "pattern 'pk' has a scope")
+}
+
+func TestExportAtComparisons(t *testing.T) {
+	var (
+		sigs = loadTestSigs(t, `name: stub
+patterns:
+  mz: '{ 4d 5a }'
+  stub: '{ 60 be }'
+condition: mz at 0 AND stub at entrypoint
+`)
+		yara strings.Builder
+	)
+
+	err := Export(&yara, sigs)
+
+	assert.Nil(t, err)
+	assert.Contains(t, yara.String(), "$mz at 0 and $stub at entrypoint")
+
+	t.Run("other offsets can't be exported", func(t *testing.T) {
+		sigs := loadTestSigs(t, `name: stub
+patterns:
+  stub: '{ 60 be }'
+condition: stub at pe.section_size(".text")
+`)
+
+		err := Export(io.Discard, sigs)
+
+		assert.ErrorContains(t, err, "can't be exported")
+	})
+}
+
+func TestExportIntegerComparisons(t *testing.T) {
+	var (
+		sigs = loadTestSigs(t, `name: pe_header
+patterns:
+  upx: UPX!
+condition: uint16(0) == 0x5a4d AND uint32(uint32(0x3c)) == 0x4550 AND uint8(@upx[2]) != 0 AND filesize < 2097152
+`)
+		yara strings.Builder
+	)
+
+	err := Export(&yara, sigs)
+
+	assert.Nil(t, err)
+	assert.Contains(t, yara.String(), "uint16(0) == 0x5a4d and uint32(uint32(0x3c)) == 0x4550 and uint8(@upx[2]) != 0 and filesize < 2097152")
+
+	t.Run("round-trips through the importer", func(t *testing.T) {
+		imported, skipped, err := Import(strings.NewReader(yara.String()))
+
+		assert.Nil(t, err)
+		assert.Empty(t, skipped)
+		if assert.Len(t, imported, 1) {
+			assert.Equal(t, "uint16(0) == 23117 AND uint32(uint32(60)) == 17744 AND uint8(@upx[2]) != 0 AND filesize < 2097152", imported[0].Condition)
+		}
+	})
+
+	t.Run("64 bit integers can't be exported", func(t *testing.T) {
+		sigs := loadTestSigs(t, "name: mz\ncondition: uint64(0) == 0x5a4d\n")
+
+		err := Export(io.Discard, sigs)
+
+		assert.ErrorContains(t, err, "can't be exported")
+	})
+}
@@ -0,0 +1,232 @@
+// Package yara translates YARA rules into binmat signatures, and back.
+//
+// Only the subset of YARA that binmat can express is imported:
+//   - Text strings, with the "ascii", "wide" and "private" modifiers.
+//   - Hex strings, with "??" wildcards and fixed jumps (e.g. "[4]").
+//   - Conditions made of strings, references to other rules, "and", "or",
+//     "not", parentheses and "of" expressions that can be written with those
+//     (e.g. "any of them", "all of ($a*)").
+//
+// Rules using anything else are skipped, and reported as such.
+//
+// All signatures can be exported as YARA rules.
+package yara
+
+import (
+	"fmt"
+	"io"
+
+	sigio "github.com/angelsolaorbaiceta/binmat/signature/io"
+)
+
+// RuleNameMetaKey is the meta key where the original name of an imported rule
+// is kept, when it isn't a valid signature name and had to be changed.
+const RuleNameMetaKey = "yara_rule"
+
+// A Skip is a rule, or a construct in a rule, that couldn't be imported.
+type Skip struct {
+	// Rule is the name of the rule, as written in the YARA source. It's empty for
+	// constructs outside rules (e.g. includes).
+	Rule string
+	// Line is the line where the skipped rule or construct is.
+	Line   int
+	Reason string
+	// RuleSkipped is true if the whole rule was skipped. Otherwise, the rule was
+	// imported without the construct, which its condition doesn't use.
+	RuleSkipped bool
+}
+
+func (s Skip) String() string {
+	switch {
+	case s.Rule == "":
+		return s.Reason
+	case s.RuleSkipped:
+		return fmt.Sprintf("rule '%s' skipped: %s", s.Rule, s.Reason)
+	}
+
+	return fmt.Sprintf("rule '%s': %s", s.Rule, s.Reason)
+}
+
+// An Importer translates YARA rules into signatures.
+//
+// Rules can reference the rules imported before them, also from previous
+// calls to Import, like YARA rules compiled together. Their names are also
+// kept unique across calls.
+type Importer struct {
+	// rules are the names of the imported rules' signatures, by YARA name.
+	rules map[string]string
+	// skippedRules are the YARA names of the skipped rules.
+	skippedRules map[string]bool
+	// usedNames are the names of the imported rules' signatures.
+	usedNames map[string]bool
+}
+
+func NewImporter() *Importer {
+	return &Importer{
+		rules:        make(map[string]string),
+		skippedRules: make(map[string]bool),
+		usedNames:    make(map[string]bool),
+	}
+}
+
+// Import translates the YARA rules read from r into signatures, in the order
+// they're defined, and reports the rules and constructs that were skipped.
+//
+// An ErrSyntax is returned if the source isn't well formed YARA.
+func Import(r io.Reader) ([]sigio.Signature, []Skip, error) {
+	return NewImporter().Import(r)
+}
+
+// Import translates the YARA rules read from r into signatures, in the order
+// they're defined, and reports the rules and constructs that were skipped.
+//
+// An ErrSyntax is returned if the source isn't well formed YARA, in which case
+// no rule is imported.
+func (i *Importer) Import(r io.Reader) ([]sigio.Signature, []Skip, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	set, err := parse(string(src))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		sigs    []sigio.Signature
+		skipped []Skip
+	)
+
+	for _, include := range set.includes {
+		skipped = append(skipped, Skip{
+			Line:   include.line,
+			Reason: fmt.Sprintf("include '%s' isn't supported, import the included file too", include.text),
+		})
+	}
+
+	for _, rule := range set.rules {
+		sig, ruleSkips, ok := i.importRule(rule)
+		skipped = append(skipped, ruleSkips...)
+		if ok {
+			sigs = append(sigs, sig)
+		}
+	}
+
+	return sigs, skipped, nil
+}
+
+// importRule translates the rule into a signature, if possible, and returns
+// the skipped constructs. The returned boolean is false if the whole rule was
+// skipped.
+func (i *Importer) importRule(r rule) (sigio.Signature, []Skip, bool) {
+	skipRule := func(line int, reason string) (sigio.Signature, []Skip, bool) {
+		i.skippedRules[r.name] = true
+		return sigio.Signature{}, []Skip{{Rule: r.name, Line: line, Reason: reason, RuleSkipped: true}}, false
+	}
+
+	if r.global {
+		return skipRule(r.line, "global rules aren't supported")
+	}
+
+	var (
+		name  = uniqueName(sanitizeName(r.name), i.usedNames)
+		used  = make(map[string]bool)
+		strs  = make([]*importedString, len(r.strings))
+		skips []Skip
+	)
+
+	// Pattern names can't clash with the names of the signatures the condition
+	// references, or they'd be taken as patterns.
+	for _, tok := range r.condition {
+		if refName, isRule := i.rules[tok.text]; isRule && tok.kind == tokenIdent {
+			used[refName] = true
+		}
+	}
+
+	for idx, def := range r.strings {
+		str := &importedString{def: def}
+		str.variants, str.err = stringPatterns(def)
+
+		base := sanitizeName(def.id)
+		for _, variant := range str.variants {
+			patternName := base
+			if variant.suffix != "" {
+				patternName = truncateName(base, maxNameLength-len(variant.suffix)) + variant.suffix
+			}
+			patternName = uniqueName(patternName, used)
+			used[patternName] = true
+			str.names = append(str.names, patternName)
+		}
+
+		strs[idx] = str
+	}
+
+	translator := condTranslator{
+		tokens:       r.condition,
+		strings:      strs,
+		rules:        i.rules,
+		skippedRules: i.skippedRules,
+	}
+	condition, err := translator.translate()
+	if err != nil {
+		line := r.line
+		if len(r.condition) > 0 {
+			line = r.condition[0].line
+		}
+		return skipRule(line, err.Error())
+	}
+
+	sig := sigio.Signature{
+		Name:      name,
+		Patterns:  make(map[string]string),
+		Condition: condition.String(),
+		Tags:      r.tags,
+		Private:   r.private,
+	}
+
+	for _, str := range strs {
+		if str.err != nil {
+			skips = append(skips, Skip{
+				Rule:   r.name,
+				Line:   str.def.line,
+				Reason: fmt.Sprintf("unused string '%s' skipped: %s", str.def.id, str.err),
+			})
+			continue
+		}
+
+		for idx, variant := range str.variants {
+			sig.Patterns[str.names[idx]] = variant.value
+		}
+	}
+
+	for _, entry := range r.meta {
+		switch _, exists := sig.Meta[entry.key]; {
+		case entry.key == "description" && sig.Description == "":
+			sig.Description = entry.value
+		case exists || entry.key == "description":
+			skips = append(skips, Skip{
+				Rule:   r.name,
+				Line:   r.line,
+				Reason: fmt.Sprintf("repeated meta '%s' skipped", entry.key),
+			})
+		default:
+			if sig.Meta == nil {
+				sig.Meta = make(map[string]string)
+			}
+			sig.Meta[entry.key] = entry.value
+		}
+	}
+
+	if name != r.name {
+		if sig.Meta == nil {
+			sig.Meta = make(map[string]string)
+		}
+		sig.Meta[RuleNameMetaKey] = r.name
+	}
+
+	i.rules[r.name] = name
+	i.usedNames[name] = true
+
+	return sig, skips, true
+}
@@ -0,0 +1,177 @@
+package yara
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sigio "github.com/angelsolaorbaiceta/binmat/signature/io"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRules = `
+import "pe"
+include "common.yar"
+
+/* Packers */
+rule UPX_Packed : packer upx {
+    meta:
+        description = "UPX packed executable"
+        severity = "low"
+        version = 2
+    strings:
+        $upx0 = "UPX0"
+        $upx1 = "UPX1" ascii wide
+        $stub = { 60 BE ?? ?? [2] 8D BE }
+    condition:
+        $upx0 and ($upx1 or $stub)
+}
+
+private rule elf_magic {
+    strings:
+        $magic = { 7F 45 4C 46 } // ELF
+    condition:
+        $magic
+}
+
+rule packed_elf {
+    condition:
+        elf_magic and UPX_Packed
+}
+
+rule any_shell {
+    strings:
+        $sh_a = "/bin/sh"
+        $sh_b = "/bin/bash"
+        $re = /sh[0-9]/
+    condition:
+        any of ($sh*)
+}
+
+rule nocase_text {
+    strings:
+        $a = "cmd.exe" nocase
+    condition:
+        $a
+}
+
+rule is_dll {
+    condition:
+        pe.is_dll()
+}
+
+rule uses_dll {
+    condition:
+        is_dll or elf_magic
+}
+
+rule at_zero {
+    strings:
+        $mz = { 4D 5A }
+    condition:
+        $mz at 0
+}
+`
+
+func TestImport(t *testing.T) {
+	sigs, skipped, err := Import(strings.NewReader(testRules))
+
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, []sigio.Signature{
+		{
+			Name:        "upx_packed",
+			Description: "UPX packed executable",
+			Patterns: map[string]string{
+				"upx0":   "UPX0",
+				"upx1":   "UPX1",
+				"upx1_w": "{ 55 00 50 00 58 00 31 00 }",
+				"stub":   "{ 60 be ?? ?? ?? ?? 8d be }",
+			},
+			Condition: "upx0 AND (upx1 OR upx1_w OR stub)",
+			Tags:      []string{"packer", "upx"},
+			Meta:      map[string]string{"severity": "low", "version": "2", RuleNameMetaKey: "UPX_Packed"},
+		},
+		{
+			Name:      "elf_magic",
+			Patterns:  map[string]string{"magic": "{ 7f 45 4c 46 }"},
+			Condition: "magic",
+			Private:   true,
+		},
+		{
+			Name:      "packed_elf",
+			Patterns:  map[string]string{},
+			Condition: "elf_magic AND upx_packed",
+		},
+		{
+			Name:      "any_shell",
+			Patterns:  map[string]string{"sh_a": "/bin/sh", "sh_b": "/bin/bash"},
+			Condition: "sh_a OR sh_b",
+		},
+		{
+			Name:      "at_zero",
+			Patterns:  map[string]string{"mz": "{ 4d 5a }"},
+			Condition: "mz at 0",
+		},
+	}, sigs)
+
+	want := []Skip{
+		{Line: 3},
+		{Rule: "any_shell", Line: 35},
+		{Rule: "nocase_text", Line: 44, RuleSkipped: true},
+		{Rule: "is_dll", Line: 49, RuleSkipped: true},
+		{Rule: "uses_dll", Line: 54, RuleSkipped: true},
+	}
+	if assert.Len(t, skipped, len(want)) {
+		for i, skip := range skipped {
+			assert.Equal(t, want[i].Rule, skip.Rule, skip.String())
+			assert.Equal(t, want[i].Line, skip.Line, skip.String())
+			assert.Equal(t, want[i].RuleSkipped, skip.RuleSkipped, skip.String())
+		}
+
+		assert.Contains(t, skipped[1].Reason, "regular expressions")
+		assert.Contains(t, skipped[2].Reason, "'nocase'")
+		assert.Contains(t, skipped[3].Reason, "modules")
+		assert.Contains(t, skipped[4].Reason, "'is_dll', which was skipped")
+	}
+
+	t.Run("imported signatures load", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "imported.yaml")
+		file, err := os.Create(filePath)
+		if err != nil {
+			t.Fatalf("Can't create test file: %s", err)
+		}
+		sigio.WriteAllToYaml(file, sigs)
+		file.Close()
+
+		loaded, err := sigio.LoadSignatures(filePath)
+
+		assert.Nil(t, err)
+		assert.Len(t, loaded, len(sigs))
+	})
+}
+
+func TestImportSyntaxError(t *testing.T) {
+	_, _, err := Import(strings.NewReader("rule broken {\n  strings:\n    $a = \"abc\n  condition:\n    $a\n}\n"))
+
+	if assert.IsType(t, ErrSyntax{}, err) {
+		assert.Equal(t, 3, err.(ErrSyntax).Line)
+	}
+}
+
+func TestImporterKeepsNamesAcrossCalls(t *testing.T) {
+	var (
+		importer     = NewImporter()
+		first, _, _  = importer.Import(strings.NewReader(`rule Dup { strings: $a = "abc" condition: $a }`))
+		second, _, _ = importer.Import(strings.NewReader(`rule dup { condition: Dup }`))
+	)
+
+	if assert.Len(t, first, 1) && assert.Len(t, second, 1) {
+		assert.Equal(t, "dup", first[0].Name)
+		assert.Equal(t, "dup_2", second[0].Name)
+		assert.Equal(t, "dup", second[0].Condition)
+	}
+}
@@ -0,0 +1,335 @@
+package yara
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// tokenIdent are identifiers and keywords (e.g. "rule", "them", "pe").
+	tokenIdent
+	// tokenString are quoted text strings, with their escape sequences decoded.
+	tokenString
+	tokenNumber
+	// tokenStringID are string identifiers (e.g. "$a", "$", "$a*").
+	tokenStringID
+	// tokenStringCount are string counts (e.g. "#a").
+	tokenStringCount
+	// tokenStringOffset are string offsets (e.g. "@a").
+	tokenStringOffset
+	// tokenStringLength are string lengths (e.g. "!a").
+	tokenStringLength
+	// tokenRegex are regular expressions, read with regex, including their
+	// delimiters and modifiers (e.g. "/ab+c/is").
+	tokenRegex
+	// tokenPunct are punctuation and operators (e.g. "{", "==", "..").
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// punctuation are the punctuation and operator tokens, longest first so that
+// they're matched greedily.
+var punctuation = []string{
+	"<<", ">>", "==", "!=", "<=", ">=", "..",
+	"{", "}", "(", ")", "[", "]", ":", "=", ",", ".",
+	"<", ">", "+", "-", "*", "/", "\\", "%", "&", "|", "^", "~",
+}
+
+// A lexer splits YARA source into tokens.
+//
+// Hex strings and regular expressions can't be tokenized like the rest of the
+// source, so the parser reads them raw with hexString and regex, where they're
+// expected.
+type lexer struct {
+	src  string
+	pos  int
+	line int
+	// peeked is the next token, if already read by peek.
+	peeked *token
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1}
+}
+
+// peek returns the next token without consuming it.
+func (l *lexer) peek() (token, error) {
+	if l.peeked == nil {
+		tok, err := l.scan()
+		if err != nil {
+			return tok, err
+		}
+		l.peeked = &tok
+	}
+
+	return *l.peeked, nil
+}
+
+// next consumes and returns the next token.
+func (l *lexer) next() (token, error) {
+	tok, err := l.peek()
+	l.peeked = nil
+	return tok, err
+}
+
+// skipSpace skips whitespace and comments.
+func (l *lexer) skipSpace() error {
+	for l.pos < len(l.src) {
+		switch {
+		case l.src[l.pos] == '\n':
+			l.line++
+			l.pos++
+
+		case l.src[l.pos] == ' ' || l.src[l.pos] == '\t' || l.src[l.pos] == '\r':
+			l.pos++
+
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return ErrSyntax{Line: l.line, Details: "unterminated comment"}
+			}
+			comment := l.src[l.pos : l.pos+2+end+2]
+			l.line += strings.Count(comment, "\n")
+			l.pos += len(comment)
+
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (l *lexer) scan() (token, error) {
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, line: l.line}, nil
+	}
+
+	var (
+		start = l.pos
+		c     = l.src[l.pos]
+	)
+
+	switch {
+	case isIdentStart(c):
+		l.pos = identEnd(l.src, l.pos)
+		return token{kind: tokenIdent, text: l.src[start:l.pos], line: l.line}, nil
+
+	case isDigit(c):
+		for l.pos < len(l.src) && (isIdentChar(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenNumber, text: l.src[start:l.pos], line: l.line}, nil
+
+	case c == '"':
+		return l.quotedString()
+
+	case c == '$' || c == '#' || c == '@' || c == '!' && l.pos+1 < len(l.src) && isIdentStart(l.src[l.pos+1]):
+		l.pos = identEnd(l.src, l.pos+1)
+		if c == '$' && l.pos < len(l.src) && l.src[l.pos] == '*' {
+			l.pos++
+		}
+
+		kind := map[byte]tokenKind{
+			'$': tokenStringID,
+			'#': tokenStringCount,
+			'@': tokenStringOffset,
+			'!': tokenStringLength,
+		}[c]
+		return token{kind: kind, text: l.src[start:l.pos], line: l.line}, nil
+	}
+
+	for _, punct := range punctuation {
+		if strings.HasPrefix(l.src[l.pos:], punct) {
+			l.pos += len(punct)
+			return token{kind: tokenPunct, text: punct, line: l.line}, nil
+		}
+	}
+
+	return token{}, ErrSyntax{Line: l.line, Details: "unexpected character " + strconv.QuoteRune(rune(c))}
+}
+
+// quotedString reads a double quoted string, decoding its escape sequences.
+func (l *lexer) quotedString() (token, error) {
+	var (
+		line  = l.line
+		value strings.Builder
+	)
+
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		switch c := l.src[l.pos]; c {
+		case '"':
+			l.pos++
+			return token{kind: tokenString, text: value.String(), line: line}, nil
+
+		case '\n':
+			return token{}, ErrSyntax{Line: line, Details: "unterminated string"}
+
+		case '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, ErrSyntax{Line: line, Details: "unterminated string"}
+			}
+
+			l.pos++
+			switch escaped := l.src[l.pos]; escaped {
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			case '"', '\\':
+				value.WriteByte(escaped)
+			case 'x':
+				if l.pos+2 >= len(l.src) {
+					return token{}, ErrSyntax{Line: line, Details: "invalid \\x escape sequence"}
+				}
+				b, err := strconv.ParseUint(l.src[l.pos+1:l.pos+3], 16, 8)
+				if err != nil {
+					return token{}, ErrSyntax{Line: line, Details: "invalid \\x escape sequence"}
+				}
+				value.WriteByte(byte(b))
+				l.pos += 2
+			default:
+				return token{}, ErrSyntax{
+					Line:    line,
+					Details: "unknown escape sequence \\" + string(escaped),
+				}
+			}
+
+		default:
+			value.WriteByte(c)
+		}
+	}
+
+	return token{}, ErrSyntax{Line: line, Details: "unterminated string"}
+}
+
+// regex reads a regular expression, including its trailing modifiers (e.g.
+// "/ab+c/is"). The text of the token is the raw regular expression.
+func (l *lexer) regex() (token, error) {
+	if l.peeked != nil {
+		panic("Can't read a regular expression after peeking a token")
+	}
+
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+
+	var (
+		start = l.pos
+		line  = l.line
+	)
+	if l.pos >= len(l.src) || l.src[l.pos] != '/' {
+		return token{}, ErrSyntax{Line: line, Details: "expected regular expression"}
+	}
+
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos++
+		case '\n':
+			return token{}, ErrSyntax{Line: line, Details: "unterminated regular expression"}
+		case '/':
+			l.pos++
+			for l.pos < len(l.src) && (l.src[l.pos] == 'i' || l.src[l.pos] == 's') {
+				l.pos++
+			}
+			return token{kind: tokenRegex, text: l.src[start:l.pos], line: line}, nil
+		}
+	}
+
+	return token{}, ErrSyntax{Line: line, Details: "unterminated regular expression"}
+}
+
+// nextRawByte returns the next byte that isn't whitespace or part of a comment,
+// without consuming it, or zero at the end of the source.
+func (l *lexer) nextRawByte() (byte, error) {
+	if l.peeked != nil {
+		panic("Can't read raw bytes after peeking a token")
+	}
+
+	if err := l.skipSpace(); err != nil || l.pos >= len(l.src) {
+		return 0, err
+	}
+
+	return l.src[l.pos], nil
+}
+
+// hexString reads a hex string, from its opening to its closing brace, and
+// returns what's in between, without comments.
+func (l *lexer) hexString() (token, error) {
+	if l.peeked != nil {
+		panic("Can't read a hex string after peeking a token")
+	}
+
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+
+	line := l.line
+	if l.pos >= len(l.src) || l.src[l.pos] != '{' {
+		return token{}, ErrSyntax{Line: line, Details: "expected hex string"}
+	}
+
+	var value strings.Builder
+	for l.pos++; l.pos < len(l.src); {
+		switch {
+		case l.src[l.pos] == '}':
+			l.pos++
+			return token{kind: tokenPunct, text: value.String(), line: line}, nil
+
+		case l.src[l.pos] == '\n':
+			l.line++
+			l.pos++
+			value.WriteByte(' ')
+
+		case strings.HasPrefix(l.src[l.pos:], "//") || strings.HasPrefix(l.src[l.pos:], "/*"):
+			if err := l.skipSpace(); err != nil {
+				return token{}, err
+			}
+			value.WriteByte(' ')
+
+		default:
+			value.WriteByte(l.src[l.pos])
+			l.pos++
+		}
+	}
+
+	return token{}, ErrSyntax{Line: line, Details: "unterminated hex string"}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func identEnd(src string, pos int) int {
+	for pos < len(src) && isIdentChar(src[pos]) {
+		pos++
+	}
+	return pos
+}
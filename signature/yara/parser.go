@@ -0,0 +1,413 @@
+package yara
+
+import (
+	"fmt"
+)
+
+// A ruleSet is the result of parsing a YARA source file.
+type ruleSet struct {
+	// includes are the include directives in the file.
+	includes []token
+	rules    []rule
+}
+
+// A rule is a parsed YARA rule. Its condition is kept as the list of tokens,
+// which are translated to a binmat condition when importing the rule.
+type rule struct {
+	name      string
+	line      int
+	private   bool
+	global    bool
+	tags      []string
+	meta      []metaEntry
+	strings   []stringDef
+	condition []token
+}
+
+type metaEntry struct {
+	key   string
+	value string
+}
+
+type stringKind int
+
+const (
+	stringText stringKind = iota
+	stringHex
+	stringRegex
+)
+
+// A stringDef is a string defined in the strings section of a rule.
+type stringDef struct {
+	// id is the identifier of the string, including the "$" (e.g. "$a", "$").
+	id   string
+	line int
+	kind stringKind
+	// value is the text of text strings, the contents between the braces of hex
+	// strings, and the raw regular expression.
+	value     string
+	modifiers []string
+}
+
+// stringModifiers are the modifiers that can follow a string definition.
+var stringModifiers = map[string]bool{
+	"ascii":      true,
+	"wide":       true,
+	"nocase":     true,
+	"fullword":   true,
+	"private":    true,
+	"xor":        true,
+	"base64":     true,
+	"base64wide": true,
+}
+
+type parser struct {
+	lex *lexer
+}
+
+// parse parses the YARA source. It fails with an ErrSyntax if the source isn't
+// well formed, but doesn't check whether rules use known identifiers.
+func parse(src string) (ruleSet, error) {
+	var (
+		p   = parser{lex: newLexer(src)}
+		set ruleSet
+	)
+
+	for {
+		tok, err := p.lex.next()
+		if err != nil {
+			return set, err
+		}
+
+		switch {
+		case tok.kind == tokenEOF:
+			return set, nil
+
+		case tok.kind == tokenIdent && tok.text == "import":
+			if _, err := p.expect(tokenString, ""); err != nil {
+				return set, err
+			}
+
+		case tok.kind == tokenIdent && tok.text == "include":
+			path, err := p.expect(tokenString, "")
+			if err != nil {
+				return set, err
+			}
+			set.includes = append(set.includes, path)
+
+		case tok.kind == tokenIdent && (tok.text == "rule" || tok.text == "private" || tok.text == "global"):
+			r, err := p.rule(tok)
+			if err != nil {
+				return set, err
+			}
+			set.rules = append(set.rules, r)
+
+		default:
+			return set, unexpected(tok, "rule")
+		}
+	}
+}
+
+// rule parses a rule, starting from its first token, which has been consumed.
+func (p *parser) rule(tok token) (rule, error) {
+	var (
+		r   = rule{line: tok.line}
+		err error
+	)
+
+	for tok.kind != tokenIdent || tok.text != "rule" {
+		switch {
+		case tok.kind == tokenIdent && tok.text == "private":
+			r.private = true
+		case tok.kind == tokenIdent && tok.text == "global":
+			r.global = true
+		default:
+			return r, unexpected(tok, "rule")
+		}
+
+		if tok, err = p.lex.next(); err != nil {
+			return r, err
+		}
+	}
+
+	name, err := p.expect(tokenIdent, "")
+	if err != nil {
+		return r, err
+	}
+	r.name = name.text
+
+	if next, err := p.lex.peek(); err != nil {
+		return r, err
+	} else if next.kind == tokenPunct && next.text == ":" {
+		p.lex.next()
+		for {
+			next, err := p.lex.peek()
+			if err != nil {
+				return r, err
+			}
+			if next.kind != tokenIdent {
+				break
+			}
+			p.lex.next()
+			r.tags = append(r.tags, next.text)
+		}
+	}
+
+	if _, err := p.expect(tokenPunct, "{"); err != nil {
+		return r, err
+	}
+
+	for {
+		section, err := p.lex.next()
+		if err != nil {
+			return r, err
+		}
+		if section.kind == tokenPunct && section.text == "}" {
+			if r.condition == nil {
+				return r, ErrSyntax{Line: section.line, Details: fmt.Sprintf("rule '%s' has no condition", r.name)}
+			}
+			return r, nil
+		}
+		if r.condition != nil {
+			return r, unexpected(section, "}")
+		}
+		if section.kind != tokenIdent {
+			return r, unexpected(section, "section")
+		}
+		if _, err := p.expect(tokenPunct, ":"); err != nil {
+			return r, err
+		}
+
+		switch section.text {
+		case "meta":
+			err = p.meta(&r)
+		case "strings":
+			err = p.strings(&r)
+		case "condition":
+			err = p.condition(&r)
+		default:
+			err = unexpected(section, "meta, strings or condition")
+		}
+		if err != nil {
+			return r, err
+		}
+	}
+}
+
+// meta parses the entries in the meta section.
+func (p *parser) meta(r *rule) error {
+	for {
+		key, err := p.lex.peek()
+		if err != nil {
+			return err
+		}
+		if key.kind != tokenIdent || isSectionStart(key.text) {
+			return nil
+		}
+		p.lex.next()
+
+		if _, err := p.expect(tokenPunct, "="); err != nil {
+			return err
+		}
+
+		value, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case value.kind == tokenString || value.kind == tokenNumber:
+		case value.kind == tokenIdent && (value.text == "true" || value.text == "false"):
+		case value.kind == tokenPunct && value.text == "-":
+			number, err := p.expect(tokenNumber, "")
+			if err != nil {
+				return err
+			}
+			value.text += number.text
+		default:
+			return unexpected(value, "meta value")
+		}
+
+		r.meta = append(r.meta, metaEntry{key: key.text, value: value.text})
+	}
+}
+
+// strings parses the string definitions in the strings section.
+func (p *parser) strings(r *rule) error {
+	for {
+		id, err := p.lex.peek()
+		if err != nil {
+			return err
+		}
+		if id.kind != tokenStringID {
+			return nil
+		}
+		p.lex.next()
+
+		if _, err := p.expect(tokenPunct, "="); err != nil {
+			return err
+		}
+
+		def := stringDef{id: id.text, line: id.line}
+
+		start, err := p.lex.nextRawByte()
+		if err != nil {
+			return err
+		}
+
+		var value token
+		switch start {
+		case '{':
+			def.kind = stringHex
+			value, err = p.lex.hexString()
+		case '/':
+			def.kind = stringRegex
+			value, err = p.lex.regex()
+		default:
+			def.kind = stringText
+			value, err = p.expect(tokenString, "")
+		}
+		if err != nil {
+			return err
+		}
+		def.value = value.text
+
+		if def.modifiers, err = p.modifiers(); err != nil {
+			return err
+		}
+
+		r.strings = append(r.strings, def)
+	}
+}
+
+// modifiers parses the modifiers after a string definition. Their arguments,
+// if any, are skipped.
+func (p *parser) modifiers() ([]string, error) {
+	var modifiers []string
+
+	for {
+		modifier, err := p.lex.peek()
+		if err != nil {
+			return nil, err
+		}
+		if modifier.kind != tokenIdent || !stringModifiers[modifier.text] {
+			return modifiers, nil
+		}
+		p.lex.next()
+		modifiers = append(modifiers, modifier.text)
+
+		if next, err := p.lex.peek(); err != nil {
+			return nil, err
+		} else if next.kind == tokenPunct && next.text == "(" {
+			if err := p.skipBalanced(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// condition reads the tokens in the condition, up to the closing brace of the
+// rule, which isn't consumed.
+func (p *parser) condition(r *rule) error {
+	r.condition = []token{}
+
+	for depth := 0; ; {
+		tok, err := p.lex.peek()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case tok.kind == tokenEOF:
+			return ErrSyntax{Line: tok.line, Details: fmt.Sprintf("rule '%s' isn't closed", r.name)}
+		case tok.kind == tokenPunct && tok.text == "(":
+			depth++
+		case tok.kind == tokenPunct && tok.text == ")":
+			depth--
+		case tok.kind == tokenPunct && tok.text == "}" && depth <= 0:
+			if len(r.condition) == 0 {
+				return ErrSyntax{Line: tok.line, Details: fmt.Sprintf("rule '%s' has an empty condition", r.name)}
+			}
+			return nil
+		}
+
+		p.lex.next()
+		r.condition = append(r.condition, tok)
+
+		if tok.kind == tokenIdent && tok.text == "matches" {
+			regex, err := p.lex.regex()
+			if err != nil {
+				return err
+			}
+			r.condition = append(r.condition, regex)
+		}
+	}
+}
+
+// skipBalanced consumes the tokens from an opening to its closing parenthesis.
+func (p *parser) skipBalanced() error {
+	for depth := 0; ; {
+		tok, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+		if tok.kind == tokenEOF {
+			return unexpected(tok, ")")
+		}
+
+		if tok.kind == tokenPunct && tok.text == "(" {
+			depth++
+		}
+		if tok.kind == tokenPunct && tok.text == ")" {
+			if depth--; depth == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// expect consumes the next token, and fails unless it's of the given kind and,
+// if not empty, has the given text.
+func (p *parser) expect(kind tokenKind, text string) (token, error) {
+	tok, err := p.lex.next()
+	if err != nil {
+		return tok, err
+	}
+
+	if tok.kind != kind || text != "" && tok.text != text {
+		want := text
+		if want == "" {
+			want = kind.String()
+		}
+		return tok, unexpected(tok, want)
+	}
+
+	return tok, nil
+}
+
+func isSectionStart(ident string) bool {
+	return ident == "meta" || ident == "strings" || ident == "condition"
+}
+
+func unexpected(tok token, want string) ErrSyntax {
+	if tok.kind == tokenEOF {
+		return ErrSyntax{Line: tok.line, Details: fmt.Sprintf("unexpected end of file, expected %s", want)}
+	}
+
+	return ErrSyntax{Line: tok.line, Details: fmt.Sprintf("unexpected '%s', expected %s", tok.text, want)}
+}
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenIdent:
+		return "identifier"
+	case tokenString:
+		return "string"
+	case tokenNumber:
+		return "number"
+	case tokenStringID:
+		return "string identifier"
+	}
+
+	return "token"
+}
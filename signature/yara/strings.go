@@ -0,0 +1,233 @@
+package yara
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxNameLength is the maximum length of binmat signature and pattern names,
+// which are used as condition variables.
+const maxNameLength = 16
+
+// A stringVariant is one of the patterns a YARA string translates to.
+// Strings with both the "ascii" and "wide" modifiers translate to two patterns.
+type stringVariant struct {
+	// suffix is added to the name of the pattern to tell the variants apart.
+	suffix string
+	value  string
+}
+
+// stringPatterns translates the YARA string into binmat patterns, or returns
+// an error explaining why it can't.
+func stringPatterns(def stringDef) ([]stringVariant, error) {
+	switch def.kind {
+	case stringRegex:
+		return nil, fmt.Errorf("regular expressions aren't supported")
+
+	case stringHex:
+		for _, modifier := range def.modifiers {
+			if modifier != "private" {
+				return nil, fmt.Errorf("'%s' modifier isn't supported in hex strings", modifier)
+			}
+		}
+
+		value, err := hexPattern(def.value)
+		if err != nil {
+			return nil, err
+		}
+		return []stringVariant{{value: value}}, nil
+	}
+
+	if def.value == "" {
+		return nil, fmt.Errorf("empty strings aren't supported")
+	}
+
+	var ascii, wide bool
+	for _, modifier := range def.modifiers {
+		switch modifier {
+		case "ascii":
+			ascii = true
+		case "wide":
+			wide = true
+		case "private":
+			// Binmat doesn't report the matched patterns, so all are private.
+		default:
+			return nil, fmt.Errorf("'%s' modifier isn't supported", modifier)
+		}
+	}
+
+	switch {
+	case ascii && wide:
+		return []stringVariant{
+			{value: textPattern([]byte(def.value))},
+			{suffix: "_w", value: textPattern(utf16LE(def.value))},
+		}, nil
+	case wide:
+		return []stringVariant{{value: textPattern(utf16LE(def.value))}}, nil
+	default:
+		return []stringVariant{{value: textPattern([]byte(def.value))}}, nil
+	}
+}
+
+// hexPattern translates the contents of a YARA hex string into a binmat byte
+// pattern. Fixed jumps (e.g. "[4]") are expanded into as many "??" wildcards.
+func hexPattern(hex string) (string, error) {
+	var fields []string
+
+	for pos := 0; pos < len(hex); {
+		switch c := hex[pos]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			pos++
+
+		case c == '[':
+			end := strings.IndexByte(hex[pos:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated jump in hex string")
+			}
+			jump := strings.ReplaceAll(hex[pos+1:pos+end], " ", "")
+			pos += end + 1
+
+			length, err := jumpLength(jump)
+			if err != nil {
+				return "", err
+			}
+			for i := 0; i < length; i++ {
+				fields = append(fields, "??")
+			}
+
+		case c == '(' || c == '|' || c == ')':
+			return "", fmt.Errorf("alternatives in hex strings aren't supported")
+
+		case c == '~':
+			return "", fmt.Errorf("negated bytes in hex strings aren't supported")
+
+		default:
+			if pos+1 >= len(hex) {
+				return "", fmt.Errorf("invalid byte '%s' in hex string", hex[pos:])
+			}
+
+			field := strings.ToLower(hex[pos : pos+2])
+			pos += 2
+
+			switch {
+			case field == "??":
+				fields = append(fields, field)
+			case strings.Contains(field, "?"):
+				return "", fmt.Errorf("nibble wildcards ('%s') aren't supported", field)
+			default:
+				if _, err := strconv.ParseUint(field, 16, 8); err != nil {
+					return "", fmt.Errorf("invalid byte '%s' in hex string", field)
+				}
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty hex strings aren't supported")
+	}
+
+	return "{ " + strings.Join(fields, " ") + " }", nil
+}
+
+// jumpLength returns the length of a fixed jump, as written between brackets
+// (e.g. "4" or "4-4").
+func jumpLength(jump string) (int, error) {
+	from, to, isRange := strings.Cut(jump, "-")
+	if !isRange {
+		to = from
+	}
+
+	fromLen, fromErr := strconv.Atoi(from)
+	toLen, toErr := strconv.Atoi(to)
+	if fromErr != nil || toErr != nil || fromLen != toLen {
+		return 0, fmt.Errorf("variable jumps ('[%s]') aren't supported", jump)
+	}
+
+	return fromLen, nil
+}
+
+// textPattern returns the binmat pattern that matches the given bytes: the
+// text itself if it's printable ASCII that can't be mistaken for a byte
+// pattern, or the byte pattern otherwise.
+func textPattern(data []byte) string {
+	text := string(data)
+	if isPlainText(text) {
+		return text
+	}
+
+	fields := make([]string, len(data))
+	for i, b := range data {
+		fields[i] = fmt.Sprintf("%02x", b)
+	}
+
+	return "{ " + strings.Join(fields, " ") + " }"
+}
+
+func isPlainText(text string) bool {
+	if strings.TrimSpace(text) != text || strings.HasPrefix(text, "{") {
+		return false
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] < 0x20 || text[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// utf16LE encodes the text as UTF-16 little endian, like the "wide" modifier.
+func utf16LE(text string) []byte {
+	var data []byte
+	for _, unit := range utf16.Encode([]rune(text)) {
+		data = append(data, byte(unit), byte(unit>>8))
+	}
+
+	return data
+}
+
+// sanitizeName turns the identifier into a valid binmat name: lowercase letters,
+// numbers and underscores, with at most maxNameLength characters.
+func sanitizeName(name string) string {
+	var sanitized strings.Builder
+
+	for _, c := range strings.ToLower(strings.TrimPrefix(name, "$")) {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' {
+			sanitized.WriteRune(c)
+		} else {
+			sanitized.WriteByte('_')
+		}
+	}
+
+	return truncateName(sanitized.String(), maxNameLength)
+}
+
+// uniqueName returns the name, or the name with a numeric suffix if it's
+// already used, within the maximum name length.
+func uniqueName(name string, used map[string]bool) string {
+	if name == "" {
+		name = "s"
+	}
+	if !used[name] {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		candidate := truncateName(name, maxNameLength-len(suffix)) + suffix
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+func truncateName(name string, length int) string {
+	if len(name) > length {
+		return name[:length]
+	}
+	return name
+}
@@ -0,0 +1,62 @@
+package yara
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHexPattern(t *testing.T) {
+	for _, tCase := range []struct {
+		hex  string
+		want string
+	}{
+		{hex: "4D 5A", want: "{ 4d 5a }"},
+		{hex: "4D5A??90", want: "{ 4d 5a ?? 90 }"},
+		{hex: "01 [3] 02", want: "{ 01 ?? ?? ?? 02 }"},
+		{hex: "01 [2-2] 02", want: "{ 01 ?? ?? 02 }"},
+	} {
+		t.Run(
+			fmt.Sprintf("hex string '%s' is pattern '%s'", tCase.hex, tCase.want),
+			func(t *testing.T) {
+				got, err := hexPattern(tCase.hex)
+
+				assert.Nil(t, err)
+				assert.Equal(t, tCase.want, got)
+			})
+	}
+
+	for _, hex := range []string{
+		"01 [2-4] 02",
+		"01 [2-] 02",
+		"01 ( 02 | 03 )",
+		"01 ?2",
+		"01 ~02",
+		"",
+	} {
+		t.Run(
+			fmt.Sprintf("hex string '%s' isn't supported", hex),
+			func(t *testing.T) {
+				_, err := hexPattern(hex)
+
+				assert.NotNil(t, err)
+			})
+	}
+}
+
+func TestTextPattern(t *testing.T) {
+	assert.Equal(t, "UPX!", textPattern([]byte("UPX!")))
+	assert.Equal(t, "{ 7b 61 7d }", textPattern([]byte("{a}")))
+	assert.Equal(t, "{ 20 61 }", textPattern([]byte(" a")))
+	assert.Equal(t, "{ 61 00 62 00 }", textPattern(utf16LE("ab")))
+}
+
+func TestUniqueName(t *testing.T) {
+	used := map[string]bool{"name": true, "a_very_long_name": true}
+
+	assert.Equal(t, "other", uniqueName(sanitizeName("Other"), used))
+	assert.Equal(t, "name_2", uniqueName(sanitizeName("Name"), used))
+	assert.Equal(t, "a_very_long_na_2", uniqueName(sanitizeName("A-Very-Long-Name-Indeed"), used))
+	assert.Equal(t, "s", uniqueName(sanitizeName("$"), used))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/angelsolaorbaiceta/binmat/signature"
+	sigio "github.com/angelsolaorbaiceta/binmat/signature/io"
+)
+
+// runTest runs the tests of the signatures in the paths passed as arguments, or
+// in the default signatures directory if none is given, and reports the failed
+// ones. Exits with a non zero status if any test fails.
+func runTest(args []string) {
+	var (
+		flags   = flag.NewFlagSet("test", flag.ExitOnError)
+		verbose bool
+		run     string
+	)
+
+	flags.BoolVar(&verbose, "v", false, "also report the tests that pass")
+	flags.StringVar(&run, "run", "", "only run the tests whose 'signature/test' name matches the regular expression")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s test [-v] [-run regexp] [file|directory]...\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	runRe, err := regexp.Compile(run)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -run flag: %s\n", err)
+		os.Exit(1)
+	}
+
+	sigPaths := sigPathsOrDefault(flags.Args())
+	sigs, tests, err := sigio.LoadTests(sigPaths...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the signature tests from '%s': %s\n", strings.Join(sigPaths, ", "), err)
+		os.Exit(1)
+	}
+
+	var selected []signature.Test
+	for _, test := range tests {
+		if runRe.MatchString(testName(test)) {
+			selected = append(selected, test)
+		}
+	}
+
+	var failed int
+	for _, result := range sigs.RunTests(selected) {
+		if result.Passed() {
+			if verbose {
+				fmt.Printf("--- PASS: %s\n", testName(*result.Test))
+			}
+			continue
+		}
+
+		failed++
+		fmt.Printf("--- FAIL: %s\n", testName(*result.Test))
+		for _, failure := range result.Failures {
+			fmt.Printf("    %s: %s\n", result.Test.Source, failure)
+		}
+	}
+
+	switch {
+	case failed > 0:
+		fmt.Printf("FAIL\t%d of %d tests failed\n", failed, len(selected))
+		os.Exit(1)
+	case len(selected) == 0:
+		fmt.Println("ok\tno tests to run")
+	default:
+		fmt.Printf("ok\t%d tests passed\n", len(selected))
+	}
+}
+
+func testName(test signature.Test) string {
+	return test.Signature + "/" + test.Name
+}
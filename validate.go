@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	sigio "github.com/angelsolaorbaiceta/binmat/signature/io"
+)
+
+// runValidate lints the signature files in the paths passed as arguments, or in
+// the default signatures directory if none is given.
+// Exits with a non zero status if any error is found.
+func runValidate(args []string) {
+	sigPaths := sigPathsOrDefault(args)
+
+	issues, err := sigio.Validate(sigPaths...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the signatures from '%s': %s\n", strings.Join(sigPaths, ", "), err)
+		os.Exit(1)
+	}
+
+	var errCount, warnCount int
+	for _, issue := range issues {
+		fmt.Println(issue)
+
+		if issue.Severity == sigio.SeverityError {
+			errCount++
+		} else {
+			warnCount++
+		}
+	}
+
+	fmt.Printf("%d errors, %d warnings.\n", errCount, warnCount)
+	if errCount > 0 {
+		os.Exit(1)
+	}
+}